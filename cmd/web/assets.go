@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golangify.com/snippetbox/pkg/i18n"
+)
+
+// assets отдаёт статические файлы. Для каждого файла при запуске
+// вычисляется хеш содержимого, и шаблоны ссылаются на имя с хешем
+// (css/main.3f2a1b9c04.css). Такой URL меняется вместе с файлом, поэтому
+// браузер может хранить его в кэше сколько угодно и никогда не
+// перепроверять.
+type assets struct {
+	fsys fs.FS
+	// urls: исходное имя -> имя с хешем; files: имя с хешем -> исходное.
+	urls  map[string]string
+	files map[string]string
+	// version - общий хеш всех файлов.
+	version string
+	// dev отключает хеши в адресах и долгое кэширование: в режиме -dev
+	// файлы меняются на диске во время работы.
+	dev bool
+}
+
+// Длина хеша в имени файла (в шестнадцатеричных символах).
+const fingerprintLength = 10
+
+func newAssets(fsys fs.FS, dev bool) (*assets, error) {
+	a := &assets{fsys: fsys, urls: map[string]string{}, files: map[string]string{}, dev: dev}
+	version, err := hashFS(fsys, func(name string, sum []byte) {
+		ext := path.Ext(name)
+		fingerprinted := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum)[:fingerprintLength] + ext
+		a.urls[name] = fingerprinted
+		a.files[fingerprinted] = name
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.version = version
+	return a, nil
+}
+
+// url возвращает адрес файла с хешем в имени. Для неизвестного файла
+// возвращается обычный адрес, чтобы опечатка в шаблоне не ломала страницу.
+func (a *assets) url(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if fingerprinted, ok := a.urls[name]; ok && !a.dev {
+		return "/static/" + fingerprinted
+	}
+	return "/static/" + name
+}
+
+// handler отдаёт файлы по адресам /static/...: с хешем в имени - с
+// бессрочным кэшированием, по исходному имени - с обязательной
+// перепроверкой (ссылки на такие адреса остаются, например, в CSS).
+func (a *assets) handler() http.Handler {
+	fileServer := http.FileServer(http.FS(a.fsys))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/static/")
+		if original, ok := a.files[name]; ok && !a.dev {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/" + original
+			r2.URL.RawPath = ""
+			fileServer.ServeHTTP(w, r2)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		http.StripPrefix("/static", fileServer).ServeHTTP(w, r)
+	})
+}
+
+// hashFS вычисляет SHA-256 каждого файла в fsys (в порядке имён) и общий
+// хеш всех файлов вместе с их именами. Для каждого файла вызывается visit.
+func hashFS(fsys fs.FS, visit func(name string, sum []byte)) (string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+
+	total := sha256.New()
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		if visit != nil {
+			visit(name, sum[:])
+		}
+		total.Write([]byte(name))
+		total.Write(sum[:])
+	}
+	return hex.EncodeToString(total.Sum(nil)), nil
+}
+
+// uiVersion объединяет хеши шаблонов и статических файлов.
+func uiVersion(templates fs.FS, static *assets) (string, error) {
+	version, err := hashFS(templates, nil)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(version + static.version))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// snippetETag вычисляет ETag страницы заметки из всего, что на ней
+// показывается, версии шаблонов и вида страницы (языка и часового пояса).
+func snippetETag(uiVersion string, v view, td *templateData) string {
+	s := td.Snippet
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d\x00%s\x00%s\x00%d\x00%d\x00%d", uiVersion, v.key(), s.ID, s.UserID, s.Title, s.Content, s.Created.Unix(), s.Expires.Unix(), td.Score)
+	for _, a := range td.Attachments {
+		fmt.Fprintf(h, "\x00%d\x00%s", a.ID, a.Filename)
+	}
+	// Комментарии только добавляются, но имя автора может измениться.
+	// Время комментария показывается относительным ("5 минут назад"),
+	// поэтому в хеш входит сам этот текст: ETag меняется ровно тогда, когда
+	// меняется надпись, а страницы со старыми комментариями кэшируются долго.
+	p := i18n.NewPrinter(v.locale)
+	for _, c := range td.Comments {
+		fmt.Fprintf(h, "\x00%d\x00%s\x00%s", c.ID, c.Author, p.Ago(time.Since(c.Created)))
+	}
+	// Гость видит итоги опроса только после закрытия.
+	if p := td.Poll; p != nil {
+		fmt.Fprintf(h, "\x00%t\x00%d\x00%d", td.PollClosed, p.Closes.Unix(), p.Voters)
+		for _, o := range p.Options {
+			fmt.Fprintf(h, "\x00%d\x00%s\x00%d", o.ID, o.Text, o.Votes)
+		}
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// certReloader держит текущий сертификат и подменяет его, когда файлы на
+// диске меняются. tls.Config получает сертификат через GetCertificate при
+// каждом рукопожатии, поэтому продлённый сертификат начинает работать без
+// перезапуска сервера.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// newCertReloader загружает сертификат. Ошибка здесь фатальна: без
+// сертификата сервер запускать бессмысленно.
+func newCertReloader(certFile, keyFile string, logger *slog.Logger) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// reload читает сертификат и ключ заново. Если файлы повреждены (например,
+// их ещё не дописали до конца), остаётся прежний сертификат.
+func (c *certReloader) reload() error {
+	modTime, err := c.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+// lastModified возвращает время последнего изменения сертификата или ключа,
+// смотря что из них новее.
+func (c *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tls: %w", err)
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reloadIfChanged перечитывает сертификат, если файлы изменились с момента
+// прошлой загрузки, и сообщает, был ли он заменён.
+func (c *certReloader) reloadIfChanged() (bool, error) {
+	modTime, err := c.lastModified()
+	if err != nil {
+		return false, err
+	}
+	c.mu.RLock()
+	changed := !modTime.Equal(c.modTime)
+	c.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+	return true, c.reload()
+}
+
+// watch раз в interval проверяет файлы сертификата, пока не закрыт quit.
+// Опрос проще и надёжнее уведомлений файловой системы: certbot и подобные
+// инструменты заменяют файлы через символические ссылки и переименование.
+func (c *certReloader) watch(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+		}
+		changed, err := c.reloadIfChanged()
+		switch {
+		case err != nil:
+			c.logger.Error("certificate reload failed, keeping the previous one", "error", err)
+		case changed:
+			c.logger.Info("certificate reloaded", "file", c.certFile)
+		}
+	}
+}
+
+// newTLSConfig возвращает настройки TLS сервера. TLS 1.3 включён; набор
+// шифров задаётся только для TLS 1.2, в 1.3 Go выбирает их сам и все они
+// безопасны.
+func newTLSConfig(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		},
+	}
+}
+
+// newACMEManager настраивает автоматический выпуск сертификатов по ACME
+// (Let's Encrypt или другой центр сертификации, например тестовый Pebble).
+// Сертификаты и ключ учётной записи хранятся в cfg.ACMECache.
+func newACMEManager(cfg *config) (*autocert.Manager, error) {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cfg.ACMECache),
+		HostPolicy: autocert.HostWhitelist(cfg.ACMEDomains...),
+		Email:      cfg.ACMEEmail,
+	}
+	if cfg.ACMEDirectory == "" && cfg.ACMECARoot == "" {
+		return m, nil
+	}
+	client := &acme.Client{DirectoryURL: cfg.ACMEDirectory}
+	// Тестовые ACME-серверы отдают каталог по HTTPS с собственным корневым
+	// сертификатом, которому нужно доверять явно.
+	if cfg.ACMECARoot != "" {
+		pem, err := os.ReadFile(cfg.ACMECARoot)
+		if err != nil {
+			return nil, fmt.Errorf("acme: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("acme: no certificates in %s", cfg.ACMECARoot)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		client.HTTPClient = &http.Client{Transport: transport, Timeout: time.Minute}
+	}
+	m.Client = client
+	return m, nil
+}
+
+// redirectHTTPS отправляет клиента с HTTP на тот же адрес по HTTPS.
+// Порт берётся из httpsAddr, а стандартный 443 в адресе не указывается.
+func redirectHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a fresh self-signed certificate for commonName into
+// dir and returns the file paths.
+func writeTestCert(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func currentCommonName(t *testing.T, c *certReloader) string {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "old")
+	c, err := newCertReloader(certFile, keyFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := c.reloadIfChanged()
+	if err != nil || changed {
+		t.Fatalf("want no reload for unchanged files; got %v, %v", changed, err)
+	}
+
+	// Renew the certificate. The modification time is moved forward
+	// explicitly because the file system may have a coarse clock.
+	writeTestCert(t, dir, "new")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	changed, err = c.reloadIfChanged()
+	if err != nil || !changed {
+		t.Fatalf("want reload after renewal; got %v, %v", changed, err)
+	}
+	if name := currentCommonName(t, c); name != "new" {
+		t.Errorf("want certificate %q; got %q", "new", name)
+	}
+
+	// A half-written file must not replace a working certificate.
+	os.WriteFile(certFile, []byte("-----BEGIN CERTIFICATE-----"), 0600)
+	later := future.Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	if _, err = c.reloadIfChanged(); err == nil {
+		t.Error("want an error for a broken certificate")
+	}
+	if name := currentCommonName(t, c); name != "new" {
+		t.Errorf("want certificate %q to be kept; got %q", "new", name)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsAddr string
+		host      string
+		want      string
+	}{
+		{"Default port", ":443", "example.com", "https://example.com/snippet/1?x=1"},
+		{"HTTP port stripped", ":443", "example.com:80", "https://example.com/snippet/1?x=1"},
+		{"Custom port", ":4000", "localhost:4080", "https://localhost:4000/snippet/1?x=1"},
+		{"IPv6", ":4000", "[::1]:4080", "https://[::1]:4000/snippet/1?x=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/snippet/1?x=1", nil)
+			r.Host = tt.host
+			redirectHTTPS(tt.httpsAddr).ServeHTTP(rr, r)
+			if rr.Code != http.StatusMovedPermanently {
+				t.Errorf("want %d; got %d", http.StatusMovedPermanently, rr.Code)
+			}
+			if loc := rr.Header().Get("Location"); loc != tt.want {
+				t.Errorf("want Location %q; got %q", tt.want, loc)
+			}
+		})
+	}
+}
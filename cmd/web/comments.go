@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/search"
+)
+
+// Предельная длина комментария в символах.
+const commentMaxLength = 2000
+
+// snippetFromPath возвращает неистёкшую заметку по :id из адреса. Если
+// заметки нет, ответ уже отправлен и возвращается nil.
+func (app *application) snippetFromPath(w http.ResponseWriter, r *http.Request) *models.Snippet {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil
+	}
+	s, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil
+	}
+	return s
+}
+
+// createComment добавляет комментарий к заметке.
+func (app *application) createComment(w http.ResponseWriter, r *http.Request) {
+	s := app.snippetFromPath(w, r)
+	if s == nil {
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("content")
+	form.MaxLength("content", commentMaxLength)
+	if !form.Valid() {
+		td, err := app.snippetPage(r, s)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Form = form
+		app.render(w, r, "show.page.tmpl", td)
+		return
+	}
+
+	userID := app.authenticatedUserID(r)
+	id, err := app.comments.Insert(s.ID, userID, form.Get("content"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Как и для заметок, ошибка индексации только записывается в лог.
+	doc := search.Document{
+		Kind:    search.KindComment,
+		ID:      id,
+		PostID:  s.ID,
+		Content: form.Get("content"),
+		Created: time.Now(),
+	}
+	n := &models.Notification{
+		UserID:       s.UserID,
+		Type:         models.NotificationReply,
+		ActorID:      userID,
+		SnippetID:    s.ID,
+		SnippetTitle: s.Title,
+		CommentID:    id,
+	}
+	if u := app.authenticatedUser(r); u != nil {
+		doc.Author = u.Username
+		n.Actor, n.ActorUsername = u.Name, u.Username
+	}
+	if err = app.search.Add(doc); err != nil {
+		app.logger.ErrorContext(r.Context(), "search index", "error", err, "comment_id", id)
+	}
+	app.notify(r, n)
+	app.publish(r, snippetTopic(s.ID), "comment", newCommentEvent(&models.Comment{
+		ID:             id,
+		Author:         n.Actor,
+		AuthorUsername: n.ActorUsername,
+		Content:        form.Get("content"),
+		Created:        doc.Created,
+	}))
+
+	app.session.Put(r, "flash", "Comment added!")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d#comment-%d", s.ID, id), http.StatusSeeOther)
+}
+
+// voteSnippet записывает голос за заметку: value=1 - за, -1 - против,
+// 0 - отменить голос. За свои заметки голосовать нельзя, иначе карму
+// можно было бы накрутить самому себе.
+func (app *application) voteSnippet(w http.ResponseWriter, r *http.Request) {
+	s := app.snippetFromPath(w, r)
+	if s == nil {
+		return
+	}
+	userID := app.authenticatedUserID(r)
+	if s.UserID == userID {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	value, err := strconv.Atoi(r.PostForm.Get("value"))
+	if err != nil || value < -1 || value > 1 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	err = app.votes.Vote(s.ID, s.UserID, userID, value)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Новый рейтинг сразу показывается всем, у кого открыта заметка.
+	if score, err := app.votes.Score(s.ID); err != nil {
+		app.logger.ErrorContext(r.Context(), "snippet score", "error", err, "snippet_id", s.ID)
+	} else {
+		app.publish(r, snippetTopic(s.ID), "score", map[string]int{"score": score})
+	}
+	// О голосах "против" и отменённых голосах не уведомляем.
+	if value == 1 {
+		app.notifyVote(r, s, userID)
+	}
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d", s.ID), http.StatusSeeOther)
+}
+
+// notifyVote уведомляет автора заметки s о голосе "за" пользователя
+// actorID. О голосе каждого пользователя автор узнаёт один раз: иначе,
+// отменяя и снова ставя голос, можно засыпать его уведомлениями и письмами.
+func (app *application) notifyVote(r *http.Request, s *models.Snippet, actorID int) {
+	first, err := app.notifications.Once(s.UserID, models.NotificationVote, actorID, s.ID)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "vote notification", "error", err, "snippet_id", s.ID)
+		return
+	}
+	if !first {
+		return
+	}
+	n := &models.Notification{
+		UserID:       s.UserID,
+		Type:         models.NotificationVote,
+		ActorID:      actorID,
+		SnippetID:    s.ID,
+		SnippetTitle: s.Title,
+	}
+	if u := app.authenticatedUser(r); u != nil {
+		n.Actor, n.ActorUsername = u.Name, u.Username
+	}
+	app.notify(r, n)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0, gzip;q=0.5", "gzip"},
+		{"BR", "br"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("%q: want %q; got %q", tt.header, tt.want, got)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	page := strings.Repeat("<p>Qogam</p>", 100)
+	handler := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/tagged":
+			w.Header().Set("ETag", `"abc"`)
+		}
+		io.WriteString(w, page)
+	}))
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		wantEnc  string
+		wantETag string
+		decode   func(io.Reader) io.Reader
+	}{
+		{"Brotli", "/", "gzip, br", "br", "", func(r io.Reader) io.Reader { return brotli.NewReader(r) }},
+		{"Gzip", "/", "gzip", "gzip", "", func(r io.Reader) io.Reader {
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return zr
+		}},
+		{"Not accepted", "/", "", "", "", nil},
+		{"Already compressed type", "/image", "br", "", "", nil},
+		{"ETag becomes weak", "/tagged", "gzip", "gzip", `W/"abc"`, func(r io.Reader) io.Reader {
+			zr, _ := gzip.NewReader(r)
+			return zr
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("Accept-Encoding", tt.accept)
+			handler.ServeHTTP(rr, r)
+
+			if enc := rr.Header().Get("Content-Encoding"); enc != tt.wantEnc {
+				t.Errorf("want Content-Encoding %q; got %q", tt.wantEnc, enc)
+			}
+			if rr.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("want Vary: Accept-Encoding; got %q", rr.Header().Get("Vary"))
+			}
+			if tt.wantETag != "" && rr.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("want ETag %q; got %q", tt.wantETag, rr.Header().Get("ETag"))
+			}
+			var body io.Reader = rr.Body
+			if tt.decode != nil {
+				body = tt.decode(body)
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != page {
+				t.Errorf("body does not round-trip: got %d bytes", len(got))
+			}
+		})
+	}
+}
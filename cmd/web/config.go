@@ -0,0 +1,401 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golangify.com/snippetbox/pkg/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// exampleSecret - ключ сеанса из документации. Он известен всем, поэтому
+// в режиме production запуск с ним запрещён.
+const exampleSecret = "s6Ndh+pPbnzHbS*+9Pk8qGWhTzbpa@ge"
+
+// Переменные окружения с этим префиксом переопределяют настройки из файла:
+// QOGAM_ADDR, QOGAM_PURGE_INTERVAL и т.д.
+const envPrefix = "QOGAM_"
+
+const (
+	envDevelopment = "development"
+	envProduction  = "production"
+)
+
+// config - все настройки приложения. Значения собираются в таком порядке
+// (каждый следующий источник побеждает предыдущий):
+//
+//  1. значения по умолчанию;
+//  2. YAML-файл, указанный флагом -config (ключи совпадают с именами флагов);
+//  3. переменные окружения QOGAM_<ИМЯ_ФЛАГА>. Заданная, но пустая переменная
+//     тоже считается значением: QOGAM_SMTP_PASSWORD= очищает пароль из файла;
+//  4. флаги командной строки.
+type config struct {
+	File string
+	Env  string
+	// Dev - режим разработки: шаблоны и статика читаются с диска.
+	Dev  bool
+	Addr string
+	// LogLevel - минимальный уровень записей в логе: debug, info, warn или error.
+	LogLevel slog.Level
+	DSN      string
+	// Секретный ключ сеанса (случайный ключ, который используется для шифрования
+	// и аутентификации сеансовых файлов cookie). Должен быть длиной 32 байта.
+	Secret  string
+	Migrate bool
+	TLSCert string
+	TLSKey  string
+	// Как часто проверять, не обновились ли файлы сертификата.
+	TLSReloadInterval time.Duration
+	// Адрес обычного HTTP, с которого клиентов перенаправляют на HTTPS.
+	HTTPAddr string
+
+	// Автоматический выпуск сертификатов по ACME. Пока список доменов пуст,
+	// используются файлы TLSCert и TLSKey.
+	ACMEDomains   stringList
+	ACMEEmail     string
+	ACMECache     string
+	ACMEDirectory string
+	ACMECARoot    string
+
+	ShutdownTimeout time.Duration
+	ShutdownDelay   time.Duration
+	GracefulRestart bool
+
+	PurgeInterval time.Duration
+	PurgeBatch    int
+	PurgeArchive  bool
+
+	ExpiryMin       int
+	ExpiryMax       int
+	ExpiryPermanent bool
+
+	UploadDir      string
+	UploadMaxSize  int64
+	UploadMaxFiles int
+
+	CSP           string
+	CSPReportOnly bool
+	HSTSMaxAge    time.Duration
+
+	RateLimit      bool
+	TrustedProxies stringList
+
+	// Locale - язык интерфейса, если ни пользователь, ни браузер не
+	// выбрали поддерживаемый язык.
+	Locale string
+	// TimeZone - часовой пояс, в котором показываются даты, пока
+	// пользователь не выбрал свой.
+	TimeZone string
+
+	MetricsAddr     string
+	MetricsUser     string
+	MetricsPassword string
+
+	// Письма с уведомлениями. Пока SMTPAddr пуст, письма не отправляются.
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+	MailFrom     string
+	// BaseURL - адрес сайта для ссылок в письмах.
+	BaseURL string
+
+	// flags - набор флагов, привязанных к полям; по нему print узнаёт
+	// имена и текущие значения настроек.
+	flags *flag.FlagSet
+}
+
+// Настройки, значения которых нельзя показывать в `config print`.
+var secretSettings = map[string]bool{
+	"secret":           true,
+	"metrics-password": true,
+	"smtp-password":    true,
+}
+
+// newFlagSet регистрирует флаги, привязанные к полям cfg. Эти же имена
+// используются как ключи в файле настроек и (в верхнем регистре с
+// префиксом) как имена переменных окружения.
+func newFlagSet(cfg *config) *flag.FlagSet {
+	fs := flag.NewFlagSet("web", flag.ContinueOnError)
+	fs.StringVar(&cfg.File, "config", "", "Путь к YAML-файлу настроек")
+	fs.StringVar(&cfg.Env, "env", envDevelopment, "Режим работы: development или production")
+	fs.BoolVar(&cfg.Dev, "dev", false, "Читать шаблоны и статику из ./ui и перечитывать шаблоны при изменении")
+	fs.StringVar(&cfg.Addr, "addr", ":4000", "Сетевой адрес веб-сервера")
+	fs.TextVar(&cfg.LogLevel, "log-level", slog.LevelInfo, "Уровень логирования: debug, info, warn или error")
+	fs.StringVar(&cfg.DSN, "dsn", "web:pass@/snippetbox?parseTime=true", "Название MySQL источника данных")
+	fs.StringVar(&cfg.Secret, "secret", exampleSecret, "Секретный ключ сеанса (32 байта)")
+	fs.BoolVar(&cfg.Migrate, "migrate", false, "Применить недостающие миграции схемы при запуске")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", "./tls/cert.pem", "Путь к TLS-сертификату")
+	fs.StringVar(&cfg.TLSKey, "tls-key", "./tls/key.pem", "Путь к секретному ключу TLS-сертификата")
+	fs.DurationVar(&cfg.TLSReloadInterval, "tls-reload-interval", time.Minute, "Как часто проверять файлы сертификата на изменения (0 - только по SIGHUP)")
+	fs.StringVar(&cfg.HTTPAddr, "http-addr", "", "Адрес HTTP для перенаправления на HTTPS, например :80 (пусто - отключить)")
+	fs.Var(&cfg.ACMEDomains, "acme-domains", "Домены через запятую, для которых выпускать сертификаты по ACME")
+	fs.StringVar(&cfg.ACMEEmail, "acme-email", "", "Адрес для уведомлений центра сертификации")
+	fs.StringVar(&cfg.ACMECache, "acme-cache", "./acme-cache", "Каталог для сертификатов ACME и ключа учётной записи")
+	fs.StringVar(&cfg.ACMEDirectory, "acme-directory", "", "URL каталога ACME (пусто - Let's Encrypt)")
+	fs.StringVar(&cfg.ACMECARoot, "acme-ca-root", "", "Корневой сертификат ACME-сервера, например тестового Pebble")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 30*time.Second, "Сколько ждать завершения запросов при остановке")
+	fs.DurationVar(&cfg.ShutdownDelay, "shutdown-delay", 0, "Сколько отвечать 503 на /readyz перед остановкой, продолжая обслуживать запросы")
+	fs.BoolVar(&cfg.GracefulRestart, "graceful-restart", false, "Перезапускаться без простоя по сигналу SIGHUP")
+
+	// Настройки фоновой очистки истёкших заметок. Нулевой интервал отключает её.
+	fs.DurationVar(&cfg.PurgeInterval, "purge-interval", time.Hour, "Интервал очистки истёкших заметок (0 - отключить)")
+	fs.IntVar(&cfg.PurgeBatch, "purge-batch", 500, "Сколько заметок удалять за один запрос")
+	fs.BoolVar(&cfg.PurgeArchive, "purge-archive", false, "Переносить истёкшие заметки в snippets_archive вместо удаления")
+
+	// Политика сроков жизни заметок, которую задаёт администратор.
+	fs.IntVar(&cfg.ExpiryMin, "expiry-min", 1, "Минимальный срок жизни заметки в днях")
+	fs.IntVar(&cfg.ExpiryMax, "expiry-max", 365, "Максимальный срок жизни заметки в днях")
+	fs.BoolVar(&cfg.ExpiryPermanent, "expiry-permanent", false, "Разрешить бессрочные заметки")
+
+	// Вложения к заметкам.
+	fs.StringVar(&cfg.UploadDir, "upload-dir", "./uploads", "Каталог для загруженных файлов")
+	fs.Int64Var(&cfg.UploadMaxSize, "upload-max-size", 10, "Максимальный размер одного вложения в мегабайтах")
+	fs.IntVar(&cfg.UploadMaxFiles, "upload-max-files", 5, "Максимальное количество вложений к заметке")
+
+	// Заголовки безопасности.
+	fs.StringVar(&cfg.CSP, "csp", defaultCSP, "Content-Security-Policy; {nonce} заменяется на nonce запроса (пусто - отключить)")
+	fs.BoolVar(&cfg.CSPReportOnly, "csp-report-only", false, "Только сообщать о нарушениях CSP, ничего не блокируя")
+	// HSTS по умолчанию выключен: браузер запоминает его для всего домена,
+	// и заголовок с localhost на год запретил бы разработчику HTTP.
+	fs.DurationVar(&cfg.HSTSMaxAge, "hsts-max-age", 0, "max-age заголовка Strict-Transport-Security, например 8760h (0 - отключить)")
+
+	// Ограничение частоты запросов.
+	fs.BoolVar(&cfg.RateLimit, "rate-limit", true, "Ограничивать частоту запросов от одного клиента")
+	fs.Var(&cfg.TrustedProxies, "trusted-proxies", "Адреса и подсети прокси через запятую, которым можно верить в X-Forwarded-For")
+
+	// Язык и часовой пояс для тех, кто не выбрал свои.
+	fs.StringVar(&cfg.Locale, "locale", string(i18n.Russian), "Язык интерфейса по умолчанию: ru, kk или en")
+	fs.StringVar(&cfg.TimeZone, "timezone", "Asia/Almaty", "Часовой пояс по умолчанию из базы IANA")
+
+	// Метрики Prometheus: отдельный адрес без пароля (для внутренней сети)
+	// и/или /metrics на основном адресе с базовой аутентификацией.
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "Отдельный адрес для /metrics, например localhost:9090 (пусто - отключить)")
+	fs.StringVar(&cfg.MetricsUser, "metrics-user", "prometheus", "Имя пользователя для /metrics на основном адресе")
+	fs.StringVar(&cfg.MetricsPassword, "metrics-password", "", "Пароль для /metrics на основном адресе (пусто - маршрут отключён)")
+
+	// Письма с уведомлениями.
+	fs.StringVar(&cfg.SMTPAddr, "smtp-addr", "", "Адрес SMTP-сервера, например smtp.example.com:587 (пусто - не отправлять письма)")
+	fs.StringVar(&cfg.SMTPUser, "smtp-user", "", "Имя пользователя SMTP (пусто - без аутентификации)")
+	fs.StringVar(&cfg.SMTPPassword, "smtp-password", "", "Пароль SMTP")
+	fs.StringVar(&cfg.MailFrom, "mail-from", "Qogam <noreply@localhost>", "Адрес отправителя писем")
+	fs.StringVar(&cfg.BaseURL, "base-url", "https://localhost:4000", "Адрес сайта для ссылок в письмах")
+	return fs
+}
+
+// loadConfig собирает настройки из всех источников. lookupenv (os.LookupEnv
+// в программе) передаётся параметром, чтобы тесты не зависели от окружения
+// процесса.
+func loadConfig(args []string, lookupenv func(string) (string, bool), output io.Writer) (*config, error) {
+	cfg := &config{}
+	fs := newFlagSet(cfg)
+	fs.SetOutput(output)
+	cfg.flags = fs
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("config: unexpected argument %q", fs.Arg(0))
+	}
+
+	// Запоминаем явно заданные флаги: они должны победить файл и окружение,
+	// поэтому применим их ещё раз в самом конце.
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	if cfg.File != "" {
+		if err := loadConfigFile(fs, cfg.File); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(lookupenv, f.Name)
+		if !ok || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("config: %s%s: %w", envPrefix, envName(f.Name), setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range explicit {
+		fs.Set(name, value)
+	}
+	return cfg, nil
+}
+
+// loadConfigFile читает плоский YAML-файл вида "имя-флага: значение".
+// Неизвестные ключи считаются ошибкой, чтобы опечатка не осталась незамеченной.
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	values := map[string]any{}
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	for key, value := range values {
+		if key == "config" || fs.Lookup(key) == nil {
+			return fmt.Errorf("config: %s: unknown setting %q", path, key)
+		}
+		// Списки (например, acme-domains) можно записать в YAML как массив.
+		if list, ok := value.([]any); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(items, ",")
+		}
+		if err = fs.Set(key, fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("config: %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+func envName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func lookupEnv(lookupenv func(string) (string, bool), flagName string) (string, bool) {
+	if flagName == "config" {
+		return "", false
+	}
+	return lookupenv(envPrefix + envName(flagName))
+}
+
+// validate проверяет настройки при запуске и возвращает все найденные
+// проблемы сразу, а не по одной.
+func (cfg *config) validate() error {
+	var problems []string
+	if cfg.Env != envDevelopment && cfg.Env != envProduction {
+		problems = append(problems, fmt.Sprintf("env must be %q or %q", envDevelopment, envProduction))
+	}
+	if len(cfg.Secret) != 32 {
+		problems = append(problems, fmt.Sprintf("secret must be exactly 32 bytes long (got %d)", len(cfg.Secret)))
+	}
+	if cfg.Env == envProduction && cfg.Secret == exampleSecret {
+		problems = append(problems, "secret must not be the built-in example secret in production")
+	}
+	if cfg.Dev && cfg.HSTSMaxAge > 0 {
+		problems = append(problems, "hsts-max-age must be 0 in dev mode")
+	}
+	if len(cfg.ACMEDomains) == 0 {
+		for _, path := range []string{cfg.TLSCert, cfg.TLSKey} {
+			if _, err := os.Stat(path); err != nil {
+				problems = append(problems, fmt.Sprintf("TLS file %s: %s", path, errors.Unwrap(err)))
+			}
+		}
+	} else if cfg.ACMECache == "" {
+		problems = append(problems, "acme-cache is required when acme-domains is set")
+	}
+	if _, err := parseTrustedProxies(cfg.TrustedProxies); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, ok := i18n.Parse(cfg.Locale); !ok {
+		problems = append(problems, "locale must be one of ru, kk, en")
+	}
+	if _, err := loadZone(cfg.TimeZone); err != nil {
+		problems = append(problems, fmt.Sprintf("timezone %q is not a known IANA time zone", cfg.TimeZone))
+	}
+	if cfg.TLSReloadInterval < 0 {
+		problems = append(problems, "tls-reload-interval must not be negative")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, "shutdown-timeout must be positive")
+	}
+	if cfg.ShutdownDelay < 0 {
+		problems = append(problems, "shutdown-delay must not be negative")
+	}
+	if cfg.PurgeInterval < 0 {
+		problems = append(problems, "purge-interval must not be negative")
+	}
+	if cfg.PurgeBatch < 1 {
+		problems = append(problems, "purge-batch must be positive")
+	}
+	if cfg.ExpiryMin < 1 || cfg.ExpiryMax < cfg.ExpiryMin {
+		problems = append(problems, "expiry-min must be at least 1 and not greater than expiry-max")
+	}
+	if cfg.UploadMaxSize < 1 || cfg.UploadMaxFiles < 0 {
+		problems = append(problems, "upload-max-size must be positive and upload-max-files must not be negative")
+	}
+	if cfg.SMTPAddr != "" {
+		if _, err := netmail.ParseAddress(cfg.MailFrom); err != nil {
+			problems = append(problems, fmt.Sprintf("mail-from %q is not a valid address", cfg.MailFrom))
+		}
+		if u, err := url.Parse(cfg.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("base-url %q must be an absolute http or https URL", cfg.BaseURL))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// print выводит действующие значения всех настроек в формате файла настроек.
+// Секреты и пароль в DSN заменяются на [REDACTED].
+func (cfg *config) print(w io.Writer) {
+	if cfg.File != "" {
+		fmt.Fprintf(w, "# loaded from %s\n", cfg.File)
+	}
+	// VisitAll обходит флаги в лексикографическом порядке.
+	cfg.flags.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		value := f.Value.String()
+		switch {
+		case secretSettings[f.Name]:
+			value = "[REDACTED]"
+		case f.Name == "dsn":
+			value = redactDSN(value)
+		}
+		fmt.Fprintf(w, "%s: %q\n", f.Name, value)
+	})
+}
+
+// stringList - флаг со списком значений через запятую.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// redactDSN скрывает пароль в строке подключения MySQL.
+func redactDSN(dsn string) string {
+	c, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "[REDACTED]"
+	}
+	if c.Passwd != "" {
+		c.Passwd = "REDACTED"
+	}
+	return c.FormatDSN()
+}
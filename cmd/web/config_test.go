@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a YAML config file to a temporary directory and
+// returns its path.
+func writeConfigFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "addr: \":5000\"\npurge-interval: 10m\nexpiry-max: 30\nupload-max-files: 2\nsmtp-password: secret\nacme-domains: [example.com, www.example.com]\n")
+	vars := map[string]string{
+		"QOGAM_PURGE_INTERVAL": "20m",
+		"QOGAM_EXPIRY_MAX":     "60",
+		"QOGAM_SMTP_PASSWORD":  "",
+	}
+	cfg, err := loadConfig([]string{"-config", path, "-expiry-max", "90"}, env(vars), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Default", cfg.PurgeBatch, 500},
+		{"File", cfg.Addr, ":5000"},
+		{"File only", cfg.UploadMaxFiles, 2},
+		{"File list", cfg.ACMEDomains.String(), "example.com,www.example.com"},
+		{"Environment over file", cfg.PurgeInterval, 20 * time.Minute},
+		{"Empty environment over file", cfg.SMTPPassword, ""},
+		{"Flag over environment", cfg.ExpiryMax, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("want %v; got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{"Unknown key", "adress: \":5000\"\n", nil, `unknown setting "adress"`},
+		{"Bad value", "purge-batch: lots\n", nil, "purge-batch"},
+		{"Bad environment", "", map[string]string{"QOGAM_PURGE_INTERVAL": "soon"}, "QOGAM_PURGE_INTERVAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []string{"-config", writeConfigFile(t, tt.file)}
+			_, err := loadConfig(args, env(tt.env), io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("want error containing %q; got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cert := "./../../tls/cert.pem"
+	key := "./../../tls/key.pem"
+	goodSecret := "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Valid", []string{"-secret", goodSecret}, ""},
+		{"Example secret in development", []string{}, ""},
+		{"Example secret in production", []string{"-env", "production"}, "built-in example secret"},
+		{"Short secret", []string{"-secret", "short"}, "exactly 32 bytes"},
+		{"Missing certificate", []string{"-secret", goodSecret, "-tls-cert", "./missing.pem"}, "missing.pem"},
+		{"Unknown environment", []string{"-env", "staging"}, "env must be"},
+		{"HSTS", []string{"-hsts-max-age", "8760h"}, ""},
+		{"HSTS in dev mode", []string{"-dev", "-hsts-max-age", "8760h"}, "hsts-max-age"},
+		{"Inverted expiry bounds", []string{"-expiry-min", "10", "-expiry-max", "5"}, "expiry-min"},
+		{"Mail settings", []string{"-smtp-addr", "smtp.example.com:587", "-mail-from", "Qogam <noreply@example.com>", "-base-url", "https://qogam.kz"}, ""},
+		{"Invalid sender", []string{"-smtp-addr", "smtp.example.com:587", "-mail-from", "noreply"}, "mail-from"},
+		{"Relative base URL", []string{"-smtp-addr", "smtp.example.com:587", "-base-url", "qogam.kz"}, "base-url"},
+		{"Mail settings without SMTP", []string{"-mail-from", "noreply"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-tls-cert", cert, "-tls-key", key}, tt.args...)
+			cfg, err := loadConfig(args, env(nil), io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("want no error; got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("want error containing %q; got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfigPrint(t *testing.T) {
+	args := []string{"-secret", "0123456789abcdef0123456789abcdef", "-dsn", "web:hunter2@/snippetbox?parseTime=true"}
+	cfg, err := loadConfig(args, env(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cfg.print(&buf)
+	out := buf.String()
+
+	for _, secret := range []string{"0123456789abcdef", "hunter2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("want %q to be redacted; got:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{`secret: "[REDACTED]"`, "web:REDACTED@", `addr: ":4000"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want output to contain %q; got:\n%s", want, out)
+		}
+	}
+}
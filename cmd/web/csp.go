@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const contextKeyCSPNonce = contextKey("cspNonce")
+
+// Политика по умолчанию разрешает только ресурсы с нашего сайта. Скрипты
+// дополнительно должны нести nonce текущего запроса, поэтому внедрённый в
+// страницу <script> не выполнится, даже если обойдёт экранирование.
+// Картинки в Markdown могут ссылаться на другие сайты, поэтому для img
+// разрешён https:. Вместо {nonce} подставляется nonce запроса.
+const defaultCSP = "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self'; " +
+	"img-src 'self' data: https:; object-src 'none'; base-uri 'self'; form-action 'self'; " +
+	"frame-ancestors 'none'; report-uri /csp-report; report-to csp"
+
+// securityHeaders - настраиваемая часть заголовков безопасности.
+type securityHeaders struct {
+	// CSP - шаблон политики с заполнителем {nonce}; пустая строка
+	// отключает заголовок.
+	CSP string
+	// CSPReportOnly отправляет политику в заголовке
+	// Content-Security-Policy-Report-Only: браузер только сообщает о
+	// нарушениях, ничего не блокируя. Удобно, чтобы проверить новую политику.
+	CSPReportOnly bool
+	// HSTSMaxAge - сколько браузер должен помнить, что сайт доступен только
+	// по HTTPS; 0 отключает заголовок.
+	HSTSMaxAge time.Duration
+}
+
+// newCSPNonce возвращает случайный nonce для одного ответа. Алфавит base64url
+// без "=", чтобы html/template не экранировал nonce в атрибуте.
+func newCSPNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// cspNonce возвращает nonce текущего запроса, который шаблоны подставляют
+// в атрибут nonce встроенных скриптов.
+func cspNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(contextKeyCSPNonce).(string)
+	return nonce
+}
+
+func (h securityHeaders) hsts() string {
+	if h.HSTSMaxAge <= 0 {
+		return ""
+	}
+	return "max-age=" + strconv.FormatInt(int64(h.HSTSMaxAge.Seconds()), 10)
+}
+
+// Максимальный размер отчёта о нарушении CSP. Обычный отчёт занимает
+// меньше килобайта; всё, что больше, в лог не пишем.
+const maxCSPReportSize = 64 << 10
+
+// cspReport - отчёт браузера о нарушении политики. Старый формат
+// (report-uri) вкладывает поля в "csp-report", Reporting API (report-to)
+// присылает массив отчётов с полями в "body".
+type cspReport struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	Disposition        string `json:"disposition"`
+}
+
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// reportCSP принимает отчёты о нарушениях CSP и пишет их в лог.
+// Браузер отправляет их без CSRF-токена и cookie, поэтому маршрут
+// обходится без сеанса и nosurf.
+func (app *application) reportCSP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+	if err != nil {
+		app.clientError(w, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var reports []cspReport
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/reports+json") {
+		var batch []reportingAPIReport
+		if err = json.Unmarshal(body, &batch); err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		for _, rep := range batch {
+			if rep.Type != "csp-violation" {
+				continue
+			}
+			reports = append(reports, cspReport{
+				DocumentURI:        rep.Body.DocumentURL,
+				BlockedURI:         rep.Body.BlockedURL,
+				EffectiveDirective: rep.Body.EffectiveDirective,
+				SourceFile:         rep.Body.SourceFile,
+				LineNumber:         rep.Body.LineNumber,
+				Disposition:        rep.Body.Disposition,
+			})
+		}
+	} else {
+		var legacy struct {
+			Report cspReport `json:"csp-report"`
+		}
+		if err = json.Unmarshal(body, &legacy); err != nil {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+		reports = append(reports, legacy.Report)
+	}
+
+	for _, rep := range reports {
+		directive := rep.EffectiveDirective
+		if directive == "" {
+			directive = rep.ViolatedDirective
+		}
+		app.logger.WarnContext(r.Context(), "csp violation",
+			"document_uri", rep.DocumentURI,
+			"blocked_uri", rep.BlockedURI,
+			"directive", directive,
+			"source_file", rep.SourceFile,
+			"line", rep.LineNumber,
+			"disposition", rep.Disposition,
+			"user_agent", r.UserAgent(),
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
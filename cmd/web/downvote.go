@@ -1,7 +0,0 @@
-package main
-
-type Downvote struct {
-	ID     int
-	UserID int
-	PostID int
-}
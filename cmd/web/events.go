@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/pubsub"
+)
+
+// Как часто поток событий шлёт пустой комментарий, чтобы прокси и
+// балансировщики не закрыли простаивающее соединение.
+const eventsHeartbeat = 30 * time.Second
+
+// Темы событий: страница заметки и уведомления пользователя.
+func snippetTopic(id int) string { return fmt.Sprintf("snippet:%d", id) }
+func userTopic(id int) string    { return fmt.Sprintf("user:%d", id) }
+
+// commentEvent - новый комментарий для страницы заметки. Текст
+// передаётся как есть: браузер вставляет его через textContent.
+type commentEvent struct {
+	ID         int       `json:"id"`
+	Author     string    `json:"author"`
+	ProfileURL string    `json:"profileURL"`
+	Content    string    `json:"content"`
+	Created    time.Time `json:"created"`
+}
+
+// publish отправляет событие name с данными v в тему topic. Данные уже
+// сохранены, поэтому ошибка только записывается в лог.
+func (app *application) publish(r *http.Request, topic, name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "publish event", "error", err, "topic", topic, "event", name)
+		return
+	}
+	app.events.Publish(topic, pubsub.Event{Name: name, Data: string(data)})
+}
+
+// publishUnread сообщает открытым страницам пользователя новое число
+// непрочитанных уведомлений.
+func (app *application) publishUnread(r *http.Request, userID int) {
+	unread, err := app.notifications.Unread(userID)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "unread notifications", "error", err, "user_id", userID)
+		return
+	}
+	app.publish(r, userTopic(userID), "unread", map[string]int{"count": unread})
+}
+
+// snippetEvents - поток событий страницы заметки: новые комментарии
+// ("comment") и изменения рейтинга ("score").
+func (app *application) snippetEvents(w http.ResponseWriter, r *http.Request) {
+	s := app.snippetFromPath(w, r)
+	if s == nil {
+		return
+	}
+	app.stream(w, r, snippetTopic(s.ID))
+}
+
+// notificationEvents - поток событий пользователя: "unread" с числом
+// непрочитанных уведомлений и "messages" с числом переписок, где есть
+// новые сообщения. Первыми сразу приходят текущие числа: за время
+// переподключения они могли измениться.
+func (app *application) notificationEvents(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	unread, err := app.notifications.Unread(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	messages, err := app.dms.Unread(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.stream(w, r, userTopic(userID),
+		pubsub.Event{Name: "unread", Data: fmt.Sprintf(`{"count":%d}`, unread)},
+		pubsub.Event{Name: "messages", Data: fmt.Sprintf(`{"count":%d}`, messages)})
+}
+
+// stream отправляет клиенту события темы topic в формате Server-Sent
+// Events, начиная с initial, пока клиент не отключится или сервер не
+// остановится (тогда брокер закрывает канал подписки).
+func (app *application) stream(w http.ResponseWriter, r *http.Request, topic string, initial ...pubsub.Event) {
+	events, unsubscribe := app.events.Subscribe(topic)
+	defer unsubscribe()
+
+	// Тайм-аут записи сервера рассчитан на обычные страницы и оборвал бы
+	// поток через несколько секунд.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-store")
+	// nginx иначе копит ответ в буфере.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	for _, e := range initial {
+		writeEvent(w, e)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent записывает событие в формате text/event-stream. Каждая
+// строка данных идёт в своём поле data, иначе перевод строки в данных
+// завершил бы событие раньше времени.
+func writeEvent(w http.ResponseWriter, e pubsub.Event) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", e.Name)
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	w.Write([]byte(b.String()))
+}
+
+// streamSession нужен потокам, которым требуется пользователь из сеанса.
+// session.Enable буферизует весь ответ до завершения обработчика, поэтому
+// поток пишет не в буфер сеанса, а прямо в исходный ResponseWriter; из
+// цепочки сеанса берётся только запрос с пользователем в контексте.
+// Менять cookie сеанса такой обработчик не может.
+func (app *application) streamSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+		app.session.Enable(app.authenticate(inner)).ServeHTTP(w, r)
+	})
+}
+
+// newCommentEvent готовит событие о комментарии c к отправке.
+func newCommentEvent(c *models.Comment) commentEvent {
+	return commentEvent{
+		ID:         c.ID,
+		Author:     c.Author,
+		ProfileURL: profileURL(c.AuthorUsername),
+		Content:    c.Content,
+		Created:    c.Created,
+	}
+}
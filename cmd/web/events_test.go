@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golangify.com/snippetbox/pkg/pubsub"
+)
+
+// openStream starts a GET request for an event stream. The caller closes
+// the body; the test server can't shut down while a stream is open.
+func (ts *testServer) openStream(t *testing.T, urlPath string) *http.Response {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+// nextEvent reads the stream up to the next event and returns its name and
+// data, skipping heartbeat comments.
+func nextEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	var name, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data += strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestSnippetEvents(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, _ := ts.get(t, "/snippet/2/events")
+	if code != http.StatusNotFound {
+		t.Errorf("want %d for a missing snippet; got %d", http.StatusNotFound, code)
+	}
+
+	csrfToken := ts.login(t)
+	rs := ts.openStream(t, "/snippet/3/events")
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, rs.StatusCode)
+	}
+	if ct := rs.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("want Content-Type text/event-stream; got %q", ct)
+	}
+	// Compressing the stream would hold events back in the compressor.
+	if ce := rs.Header.Get("Content-Encoding"); ce != "" {
+		t.Errorf("want an uncompressed stream; got Content-Encoding %q", ce)
+	}
+	events := bufio.NewReader(rs.Body)
+
+	form := url.Values{"content": {"<b>Live</b> comment"}, "csrf_token": {csrfToken}}
+	if code, _, _ := ts.postForm(t, "/snippet/3/comment", form); code != http.StatusSeeOther {
+		t.Fatalf("comment: want %d; got %d", http.StatusSeeOther, code)
+	}
+	name, data := nextEvent(t, events)
+	if name != "comment" {
+		t.Errorf("want a comment event; got %q", name)
+	}
+	for _, want := range []string{`"id":2`, `"author":"Alice"`, `"profileURL":"/u/alice"`, `"content":"\u003cb\u003eLive\u003c/b\u003e comment"`} {
+		if !strings.Contains(data, want) {
+			t.Errorf("want event data to contain %s; got %s", want, data)
+		}
+	}
+
+	form = url.Values{"value": {"1"}, "csrf_token": {csrfToken}}
+	if code, _, _ := ts.postForm(t, "/snippet/3/vote", form); code != http.StatusSeeOther {
+		t.Fatalf("vote: want %d; got %d", http.StatusSeeOther, code)
+	}
+	if name, data := nextEvent(t, events); name != "score" || data != `{"score":0}` {
+		t.Errorf("want score event; got %q %s", name, data)
+	}
+}
+
+func TestNotificationEvents(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, _ := ts.get(t, "/user/notifications/events")
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/login" {
+		t.Fatalf("want redirect to /user/login; got %d %q", code, header.Get("Location"))
+	}
+
+	csrfToken := ts.login(t)
+	rs := ts.openStream(t, "/user/notifications/events")
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, rs.StatusCode)
+	}
+	events := bufio.NewReader(rs.Body)
+	// The current counts arrive first, so a reconnecting page catches up.
+	if name, data := nextEvent(t, events); name != "unread" || data != `{"count":1}` {
+		t.Errorf("want initial unread event; got %q %s", name, data)
+	}
+	if name, data := nextEvent(t, events); name != "messages" || data != `{"count":1}` {
+		t.Errorf("want initial messages event; got %q %s", name, data)
+	}
+
+	form := url.Values{"csrf_token": {csrfToken}}
+	if code, _, _ := ts.postForm(t, "/user/notifications/read", form); code != http.StatusSeeOther {
+		t.Fatalf("want %d; got %d", http.StatusSeeOther, code)
+	}
+	if name, _ := nextEvent(t, events); name != "unread" {
+		t.Errorf("want an unread event after marking read; got %q", name)
+	}
+
+	// Stopping the broker, as the server does on shutdown, ends the stream.
+	app.events.(*pubsub.Memory).Close()
+	if _, err := io.ReadAll(events); err != nil {
+		t.Errorf("want the stream to end cleanly; got %v", err)
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeEvent(rr, pubsub.Event{Name: "comment", Data: "line one\nline two"})
+	want := "event: comment\ndata: line one\ndata: line two\n\n"
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"golangify.com/snippetbox/pkg/forms"
+)
+
+// expiryPolicy описывает, какие сроки жизни заметок разрешил администратор.
+// Срок задаётся в днях; бессрочные заметки допускаются, только если
+// AllowPermanent равен true.
+type expiryPolicy struct {
+	MinDays        int
+	MaxDays        int
+	AllowPermanent bool
+}
+
+// Форма передаёт срок жизни в поле "expires", а флажок "permanent"
+// со значением "true" просит сделать заметку бессрочной.
+const permanentValue = "true"
+
+// validate проверяет поля "expires" и "permanent" формы и добавляет
+// сообщения об ошибках, если срок выходит за рамки политики.
+func (p expiryPolicy) validate(form *forms.Form) {
+	if form.Get("permanent") == permanentValue {
+		if !p.AllowPermanent {
+			form.AddError("expires", "Permanent posts are not allowed")
+		}
+		return
+	}
+	form.Required("expires")
+	form.IntRange("expires", p.MinDays, p.MaxDays)
+}
+
+// days возвращает срок жизни из уже проверенной формы в днях,
+// 0 означает бессрочную заметку.
+func (p expiryPolicy) days(form *forms.Form) int {
+	if form.Get("permanent") == permanentValue {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(form.Get("expires")))
+	return n
+}
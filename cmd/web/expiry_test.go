@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/forms"
+)
+
+func TestExpiryPolicy(t *testing.T) {
+	strict := expiryPolicy{MinDays: 1, MaxDays: 30}
+	lenient := expiryPolicy{MinDays: 1, MaxDays: 30, AllowPermanent: true}
+
+	tests := []struct {
+		name      string
+		policy    expiryPolicy
+		data      url.Values
+		wantValid bool
+		wantDays  int
+	}{
+		{"Within bounds", strict, url.Values{"expires": {"14"}}, true, 14},
+		{"Lower bound", strict, url.Values{"expires": {"1"}}, true, 1},
+		{"Upper bound", strict, url.Values{"expires": {"30"}}, true, 30},
+		{"Too long", strict, url.Values{"expires": {"31"}}, false, 0},
+		{"Zero", strict, url.Values{"expires": {"0"}}, false, 0},
+		{"Not a number", strict, url.Values{"expires": {"week"}}, false, 0},
+		{"Blank", strict, url.Values{}, false, 0},
+		{"Permanent not allowed", strict, url.Values{"permanent": {"true"}}, false, 0},
+		{"Permanent allowed", lenient, url.Values{"permanent": {"true"}}, true, 0},
+		{"Permanent ignores days", lenient, url.Values{"expires": {"999"}, "permanent": {"true"}}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := forms.New(tt.data)
+			tt.policy.validate(form)
+			if form.Valid() != tt.wantValid {
+				t.Fatalf("want valid %t; got errors %v", tt.wantValid, form.Errors)
+			}
+			if !tt.wantValid {
+				return
+			}
+			if days := tt.policy.days(form); days != tt.wantDays {
+				t.Errorf("want %d days; got %d", tt.wantDays, days)
+			}
+		})
+	}
+}
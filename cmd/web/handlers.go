@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/markdown"
 	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/search"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
@@ -15,7 +18,7 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 
 	s, err := app.snippets.Latest()
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 	app.render(w, r, "home.page.tmpl", &templateData{
@@ -34,49 +37,159 @@ func (app *application) showSnippet(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, models.ErrNoRecord) {
 			app.notFound(w)
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
-	app.render(w, r, "show.page.tmpl", &templateData{
-		Snippet: s,
-	})
+	td, err := app.snippetPage(r, s)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Гостям страница показывается одинаково, поэтому браузер и прокси могут
+	// перепроверять её по ETag, не скачивая заново.
+	// В режиме -dev шаблоны меняются на ходу, и ETag устарел бы.
+	if app.templates == nil && !app.isAuthenticated(r) && !app.session.Exists(r, "flash") {
+		if app.notModified(w, r, snippetETag(app.uiVersion, app.viewOf(r), td)) {
+			return
+		}
+	}
+	app.render(w, r, "show.page.tmpl", td)
+}
+
+// snippetPage собирает всё, что показывается на странице заметки: вложения,
+// комментарии, сумму голосов и опрос, если заметка - опрос. Опрос
+// показывается таким, каким его видит пользователь запроса r.
+func (app *application) snippetPage(r *http.Request, s *models.Snippet) (*templateData, error) {
+	attachments, err := app.attachments.ForSnippet(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := app.comments.ForSnippet(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	score, err := app.votes.Score(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	poll, err := app.polls.Get(s.ID, app.authenticatedUserID(r))
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		return nil, err
+	}
+	td := &templateData{
+		Attachments: attachments,
+		Comments:    comments,
+		Poll:        poll,
+		PollClosed:  poll != nil && poll.Closed(time.Now()),
+		Score:       score,
+		Snippet:     s,
+	}
+	// Кнопки сохранения видны только пользователям, поэтому страница для
+	// гостей от закладок не зависит.
+	if userID := app.authenticatedUserID(r); userID != 0 {
+		if td.Saved, err = app.saved.Saved(userID, s.ID); err != nil {
+			return nil, err
+		}
+		if td.Collections, err = app.saved.Collections(userID); err != nil {
+			return nil, err
+		}
+	}
+	return td, nil
 }
 
 // Add a new createSnippetForm handler, which for now returns a placeholder response.
 func (app *application) createSnippetForm(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, "create.page.tmpl", &templateData{
 		// Pass a new empty forms.Form object to the template.
-		Form: forms.New(nil),
+		Form:        forms.New(nil),
+		PollOptions: pollOptionFields(nil),
 	})
 }
 
 func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseForm()
-	if err != nil {
+	// Форма с вложениями приходит как multipart/form-data, без них - как
+	// обычная urlencoded-форма.
+	err := r.ParseMultipartForm(multipartMemory)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 	// Create a new forms.Form struct containing the POSTed data from the
 	// form, then use the validation methods to check the content.
-	form := forms.New(r.PostForm)
-	form.Required("title", "content", "expires")
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("title", "content")
 	form.MaxLength("title", 100)
-	form.PermittedValues("expires", "30", "7", "1")
+	app.expiry.validate(form)
+	poll := app.readPoll(r, form)
+	uploads, err := app.readUploads(r, form)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
 	// If the form isn't valid, redisplay the template passing in the
 	// form.Form object as the data.
 	if !form.Valid() {
-		app.render(w, r, "create.page.tmpl", &templateData{Form: form})
+		app.render(w, r, "create.page.tmpl", &templateData{Form: form, PollOptions: pollOptionFields(form)})
 		return
 	}
 	// Because the form data (with type url.Values) has been anonymously embedded
 	// in the form.Form struct, we can use the Get() method to retrieve
 	// the validated value for a particular form field.
-	id, err := app.snippets.Insert(form.Get("title"), form.Get("content"), form.Get("expires"))
+	userID := app.authenticatedUserID(r)
+	id, err := app.snippets.Insert(userID, form.Get("title"), form.Get("content"), app.expiry.days(form))
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
+	// Заметка уже сохранена, поэтому если вложения или опрос записать не
+	// удалось, её нужно удалить: иначе она останется опубликованной без них.
+	keys, err := app.storeUploads(id, userID, uploads)
+	if err != nil {
+		app.discardSnippet(r, id, keys)
+		app.serverError(w, r, err)
+		return
+	}
+	if poll != nil {
+		err = app.polls.Insert(id, poll.Multiple, poll.Closes, poll.Options)
+		if err != nil {
+			app.discardSnippet(r, id, keys)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	// Заметка уже сохранена, поэтому ошибка индексации не должна
+	// превращаться в ответ 500 - достаточно записать её в лог.
+	doc := search.Document{
+		Kind:    search.KindPost,
+		ID:      id,
+		PostID:  id,
+		Title:   form.Get("title"),
+		Content: form.Get("content"),
+		Created: time.Now(),
+	}
+	u := app.authenticatedUser(r)
+	if u != nil {
+		doc.Author = u.Username
+	}
+	if err = app.search.Add(doc); err != nil {
+		app.logger.ErrorContext(r.Context(), "search index", "error", err, "snippet_id", id)
+	}
+	mentions, tags := markdown.Extract(form.Get("content"))
+	if err = app.tags.Set(id, tags); err != nil {
+		app.logger.ErrorContext(r.Context(), "snippet tags", "error", err, "snippet_id", id)
+	}
+	n := models.Notification{
+		Type:         models.NotificationMention,
+		ActorID:      userID,
+		SnippetID:    id,
+		SnippetTitle: form.Get("title"),
+	}
+	if u != nil {
+		n.Actor, n.ActorUsername = u.Name, u.Username
+	}
+	app.notifyMentions(r, mentions, n)
 
 	// Используйте метод Put() для добавления строкового значения ("Ваш фрагмент был сохранен
 	// успешно!") и соответствующий ключ ("flash") к сеансу данные.
@@ -88,6 +201,98 @@ func (app *application) createSnippet(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("/snippet/%d", id), http.StatusSeeOther)
 }
 
+// previewSnippet возвращает HTML-фрагмент с отрисованным Markdown для
+// предпросмотра на форме создания заметки.
+func (app *application) previewSnippet(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(markdown.Render(r.PostForm.Get("content"))))
+}
+
+// Количество результатов на одной странице поиска и последняя страница,
+// которую можно открыть. Без ограничения page=1000000000 заставил бы базу
+// пропустить миллиарды строк, а (page-1)*searchPerPage мог бы переполниться.
+const (
+	searchPerPage = 20
+	searchMaxPage = 50
+)
+
+// searchSnippets показывает страницу поиска. Синтаксис запроса описан
+// в пакете search: фразы в кавычках, -исключения, author: и community:.
+func (app *application) searchSnippets(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query().Get("q")
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	page = min(page, searchMaxPage)
+
+	td := &templateData{SearchQuery: qs}
+	if q := search.Parse(qs); !q.Empty() {
+		td.Search, err = app.search.Search(q, page, searchPerPage)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Search.MaxPage = searchMaxPage
+	}
+	app.render(w, r, "search.page.tmpl", td)
+}
+
+// updateSnippetExpiry позволяет автору продлить или сократить срок жизни
+// своей заметки. Новый срок отсчитывается от текущего момента.
+func (app *application) updateSnippetExpiry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	s, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	// Чужие заметки (и старые заметки без автора) менять нельзя.
+	if s.UserID == 0 || s.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	app.expiry.validate(form)
+	if !form.Valid() {
+		td, err := app.snippetPage(r, s)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Form = form
+		app.render(w, r, "show.page.tmpl", td)
+		return
+	}
+
+	err = app.snippets.UpdateExpiry(id, s.UserID, app.expiry.days(form))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "Snippet expiry updated!")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d", id), http.StatusSeeOther)
+}
+
 func (app *application) deleteSnippet(w http.ResponseWriter, r http.Request) {
 	if r.Method != http.MethodDelete {
 		w.Header().Set("Allow", http.MethodDelete)
@@ -119,9 +324,10 @@ func (app *application) signupUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Проверьте содержимое формы с помощью помощника формы, который мы создали ранее.
-	form := forms.New(r.PostForm)
-	form.Required("name", "email", "password")
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("name", "username", "email", "password")
 	form.MaxLength("name", 255)
+	form.Username("username")
 	form.MaxLength("email", 255)
 	form.MatchesPattern("email", forms.EmailRX)
 	form.MinLength("password", 10)
@@ -134,13 +340,16 @@ func (app *application) signupUser(w http.ResponseWriter, r *http.Request) {
 	// Попробует создать новую запись пользователя в базе данных.
 	// Если электронное письмо уже существует,
 	// добавит сообщение об ошибке в форму и повторно отобразит его.
-	err = app.users.Insert(form.Get("name"), form.Get("email"), form.Get("password"))
+	err = app.users.Insert(form.Get("name"), form.Get("username"), form.Get("email"), form.Get("password"))
 	if err != nil {
 		if errors.Is(err, models.ErrDuplicateEmail) {
-			form.Errors.Add("email", "Address is already in use")
+			form.AddError("email", "Address is already in use")
+			app.render(w, r, "signup.page.tmpl", &templateData{Form: form})
+		} else if errors.Is(err, models.ErrDuplicateUsername) {
+			form.AddError("username", "Username is already taken")
 			app.render(w, r, "signup.page.tmpl", &templateData{Form: form})
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
@@ -167,17 +376,19 @@ func (app *application) loginUser(w http.ResponseWriter, r *http.Request) {
 	// Проверит, действительны ли учетные данные.
 	// Если это не так, добавит общее сообщение об ошибке
 	// на карту сбоев формы и повторно отобразит страницу входа.
-	form := forms.New(r.PostForm)
+	form := forms.New(r.PostForm).Localize(app.printer(r))
 	id, err := app.users.Authenticate(form.Get("email"), form.Get("password"))
 	if err != nil {
 		if errors.Is(err, models.ErrInvalidCredentials) {
-			form.Errors.Add("generic", "Email or Password is incorrect")
+			app.metrics.logins.WithLabelValues("failure").Inc()
+			form.AddError("generic", "Email or Password is incorrect")
 			app.render(w, r, "login.page.tmpl", &templateData{Form: form})
 		} else {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 		}
 		return
 	}
+	app.metrics.logins.WithLabelValues("success").Inc()
 	// Добавит идентификатор текущего пользователя в сеанс, чтобы теперь он "logged in".
 	app.session.Put(r, "authenticatedUserID", id)
 	// Redirect the user to the create snippet page.
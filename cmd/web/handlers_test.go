@@ -1,8 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/models/mock"
+	"golangify.com/snippetbox/pkg/search"
+	"golangify.com/snippetbox/pkg/storage"
 )
 
 func TestPing(t *testing.T) {
@@ -27,3 +45,401 @@ func TestPing(t *testing.T) {
 	}
 
 }
+
+func TestSearchSnippets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		wantBody []byte
+	}{
+		{"Empty query", "", []byte("<form action='/search'")},
+		{"Matching word", "silent", []byte("<mark>silent</mark>")},
+		{"Phrase", `"old silent"`, []byte("<mark>old</mark> <mark>silent</mark>")},
+		{"Excluded word", "pond -silent", []byte("Ничего не найдено")},
+		{"Author filter", "pond author:alice", []byte("Найдено: 1")},
+		{"Author as a mention", "pond author:@Alice", []byte("<a href='/u/alice'>@alice</a>")},
+		{"Other author", "pond author:bob", []byte("Ничего не найдено")},
+		{"Escaped input", "<script>", []byte("&lt;script&gt;")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, "/search?q="+url.QueryEscape(tt.query))
+			if code != http.StatusOK {
+				t.Errorf("want %d; got %d", http.StatusOK, code)
+			}
+			if !bytes.Contains(body, tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSearchComments(t *testing.T) {
+	app := newTestApplication(t)
+	app.search.Add(search.Document{
+		Kind:    search.KindComment,
+		ID:      1,
+		PostID:  1,
+		Content: "A frog jumps into the pond, splash! Silence again.",
+		Author:  "alice",
+		Created: time.Now(),
+	})
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/search?q=frog")
+	want := "<a href='/snippet/1#comment-1'>Комментарий</a>"
+	if !bytes.Contains(body, []byte(want)) {
+		t.Errorf("want body to contain %q", want)
+	}
+}
+
+// pageSpy records the page the handler asked the index for.
+type pageSpy struct {
+	search.Index
+	page int
+}
+
+func (s *pageSpy) Search(q search.Query, page, perPage int) (*search.Results, error) {
+	s.page = page
+	return s.Index.Search(q, page, perPage)
+}
+
+func TestSearchSnippetsPageLimit(t *testing.T) {
+	app := newTestApplication(t)
+	spy := &pageSpy{Index: app.search}
+	app.search = spy
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	for page, want := range map[string]int{"0": 1, "2": 2, "1000000000": searchMaxPage} {
+		if code, _, _ := ts.get(t, "/search?q=pond&page="+page); code != http.StatusOK {
+			t.Fatalf("page %s: want %d; got %d", page, http.StatusOK, code)
+		}
+		if spy.page != want {
+			t.Errorf("page %s: want index asked for page %d; got %d", page, want, spy.page)
+		}
+	}
+}
+
+func TestShowAttachment(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	t.Run("Anonymous", func(t *testing.T) {
+		code, header, _ := ts.get(t, "/attachment/1")
+		if code != http.StatusSeeOther || header.Get("Location") != "/user/login" {
+			t.Errorf("want redirect to login; got %d %q", code, header.Get("Location"))
+		}
+	})
+
+	ts.login(t)
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody []byte
+	}{
+		{"Original", "/attachment/1", http.StatusOK, []byte("pond")},
+		{"Thumbnail", "/attachment/1/thumb", http.StatusOK, []byte("thumb")},
+		{"Non-existent ID", "/attachment/2", http.StatusNotFound, nil},
+		{"String ID", "/attachment/foo", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if !bytes.Equal(body, tt.wantBody) && tt.wantBody != nil {
+				t.Errorf("want body %q; got %q", tt.wantBody, body)
+			}
+			if code == http.StatusOK && header.Get("X-Content-Type-Options") != "nosniff" {
+				t.Errorf("want nosniff header")
+			}
+		})
+	}
+}
+
+// failingStorage fails every Save after the first ok ones.
+type failingStorage struct {
+	*storage.Local
+	ok int
+}
+
+func (s *failingStorage) Save(key string, r io.Reader) error {
+	if s.ok == 0 {
+		return errors.New("disk full")
+	}
+	s.ok--
+	return s.Local.Save(key, r)
+}
+
+func TestCreateSnippetUploadFailure(t *testing.T) {
+	app := newTestApplication(t)
+	snippets := &mock.SnippetModel{}
+	app.snippets = snippets
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first image and its thumbnail are stored, the second image is not.
+	app.storage = &failingStorage{Local: files, ok: 2}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("csrf_token", csrfToken)
+	mw.WriteField("title", "Pond")
+	mw.WriteField("content", "An old silent pond")
+	mw.WriteField("expires", "7")
+	for _, name := range []string{"one.png", "two.png"} {
+		fw, err := mw.CreateFormFile("attachments", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(pngData.Bytes())
+	}
+	mw.Close()
+
+	rs, err := ts.Client().Post(ts.URL+"/snippet/create", mw.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+	if rs.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("want %d; got %d", http.StatusInternalServerError, rs.StatusCode)
+	}
+	if len(snippets.Deleted) != 1 || snippets.Deleted[0] != 2 {
+		t.Errorf("want snippet 2 deleted; got %v", snippets.Deleted)
+	}
+	entries, err := os.ReadDir(files.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("want stored files removed; got %d left", len(entries))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	app := newTestApplication(t)
+	app.metricsUser = "prometheus"
+	app.metricsPassword = "s3cret"
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.get(t, "/snippet/1")
+	ts.get(t, "/snippet/2")
+	ts.get(t, "/no/such/page")
+
+	code, _, _ := ts.get(t, "/metrics")
+	if code != http.StatusUnauthorized {
+		t.Fatalf("want %d without credentials; got %d", http.StatusUnauthorized, code)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("prometheus", "s3cret")
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.StatusCode != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, rs.StatusCode)
+	}
+
+	// Both snippet pages are counted under the route pattern, not the raw
+	// path, and unknown paths share a single label.
+	for _, want := range []string{
+		`snippetbox_http_requests_total{method="GET",route="/snippet/:id",status="200"} 1`,
+		`snippetbox_http_requests_total{method="GET",route="/snippet/:id",status="404"} 1`,
+		`snippetbox_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`snippetbox_template_render_duration_seconds_count{template="show.page.tmpl"} 1`,
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want metrics to contain %q", want)
+		}
+	}
+	if bytes.Contains(body, []byte("/snippet/2")) {
+		t.Error("raw paths must not be used as labels")
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(app *application)
+		wantCode   int
+		wantFailed map[string]bool
+	}{
+		{"Ready", func(app *application) {}, http.StatusOK, nil},
+		{"Database down", func(app *application) {
+			app.health = &mock.HealthModel{Down: true}
+		}, http.StatusServiceUnavailable, map[string]bool{"database": true, "migrations": true}},
+		{"Schema behind", func(app *application) {
+			app.latestMigration++
+		}, http.StatusServiceUnavailable, map[string]bool{"migrations": true}},
+		{"Shutting down", func(app *application) {
+			app.shuttingDown.Store(true)
+		}, http.StatusServiceUnavailable, map[string]bool{"shutdown": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			tt.setup(app)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			code, _, body := ts.get(t, "/readyz")
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			var report healthReport
+			if err := json.Unmarshal(body, &report); err != nil {
+				t.Fatal(err)
+			}
+			for name, c := range report.Checks {
+				failed := c.Status == statusFail
+				if failed != tt.wantFailed[name] {
+					t.Errorf("check %s: unexpected status %q (%s)", name, c.Status, c.Error)
+				}
+			}
+			// Driver errors reveal the database address and stay in the log.
+			if bytes.Contains(body, []byte("127.0.0.1")) {
+				t.Errorf("want no database details in %s", body)
+			}
+			if report.Checks["janitor"].Status != statusDisabled {
+				t.Errorf("want janitor %q; got %q", statusDisabled, report.Checks["janitor"].Status)
+			}
+
+			// Liveness never depends on the database.
+			code, _, _ = ts.get(t, "/healthz")
+			if code != http.StatusOK {
+				t.Errorf("want /healthz %d; got %d", http.StatusOK, code)
+			}
+		})
+	}
+}
+
+func TestStaticAssets(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// The layout links to the fingerprinted stylesheet.
+	_, _, body := ts.get(t, "/")
+	cssURL := app.assets.url("css/main.css")
+	if !regexp.MustCompile(`^/static/css/main\.[0-9a-f]{10}\.css$`).MatchString(cssURL) {
+		t.Fatalf("unexpected fingerprinted URL %q", cssURL)
+	}
+	if !bytes.Contains(body, []byte(cssURL)) {
+		t.Errorf("want page to link to %q", cssURL)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCache string
+	}{
+		{"Fingerprinted", cssURL, "public, max-age=31536000, immutable"},
+		{"Original name", "/static/css/main.css", "no-cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.path)
+			if code != http.StatusOK {
+				t.Fatalf("want %d; got %d", http.StatusOK, code)
+			}
+			if got := header.Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("want Cache-Control %q; got %q", tt.wantCache, got)
+			}
+			if !bytes.Contains(body, []byte("font-family")) {
+				t.Error("want the stylesheet contents")
+			}
+		})
+	}
+}
+
+func TestShowSnippetETag(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, _ := ts.get(t, "/snippet/1")
+	etag := strings.TrimPrefix(header.Get("ETag"), "W/")
+	if code != http.StatusOK || etag == "" {
+		t.Fatalf("want 200 with an ETag; got %d, %q", code, etag)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"Matching", etag, http.StatusNotModified},
+		{"Matching weak", "W/" + etag, http.StatusNotModified},
+		{"Stale", `"stale"`, http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/snippet/1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rs, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.Body.Close()
+			if rs.StatusCode != tt.want {
+				t.Errorf("want %d; got %d", tt.want, rs.StatusCode)
+			}
+		})
+	}
+
+	// Signed-in users see owner controls, so their pages are not cached.
+	ts.login(t)
+	_, header, _ = ts.get(t, "/snippet/1")
+	if header.Get("ETag") != "" {
+		t.Error("want no ETag for authenticated users")
+	}
+}
+
+func TestSnippetETagCommentAge(t *testing.T) {
+	v := view{i18n.Russian, time.UTC}
+	etag := func(age time.Duration) string {
+		td := &templateData{
+			Snippet:  &models.Snippet{ID: 1},
+			Comments: []*models.Comment{{ID: 1, Author: "alice", Created: time.Now().Add(-age)}},
+		}
+		return snippetETag("v1", v, td)
+	}
+
+	// "5 minutes ago" must not be served from cache once it reads "6 minutes ago".
+	if etag(5*time.Minute+time.Second) == etag(6*time.Minute+time.Second) {
+		t.Error("want ETag to change with the minutes shown")
+	}
+	// Older comments only change their label once a day.
+	if etag(40*24*time.Hour) != etag(40*24*time.Hour+time.Minute) {
+		t.Error("want ETag to stay the same while the label does")
+	}
+}
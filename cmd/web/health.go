@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Сколько ждать ответа базы в проверке готовности. Оркестратор опрашивает
+// /readyz часто, и зависший запрос не должен копиться.
+const readinessDBTimeout = 2 * time.Second
+
+// check - результат одной проверки в ответе /readyz.
+type check struct {
+	Status    string   `json:"status"`
+	Error     string   `json:"error,omitempty"`
+	LatencyMS *float64 `json:"latency_ms,omitempty"`
+	Version   *int     `json:"version,omitempty"`
+	Latest    *int     `json:"latest,omitempty"`
+	Count     *int     `json:"count,omitempty"`
+	LastRun   string   `json:"last_run,omitempty"`
+}
+
+type healthReport struct {
+	Status string           `json:"status"`
+	Checks map[string]check `json:"checks,omitempty"`
+}
+
+const (
+	statusOK       = "ok"
+	statusFail     = "fail"
+	statusDisabled = "disabled"
+	statusPending  = "pending"
+)
+
+// errUnavailable заменяет в ответе /readyz настоящие ошибки базы: они
+// раскрывают адрес сервера и подробности драйвера, а /readyz открыт без
+// авторизации. Сама ошибка записывается в лог вместе с request_id.
+const errUnavailable = "unavailable"
+
+// healthz - проверка живости: процесс запущен и обрабатывает запросы.
+// Зависимости здесь не проверяются намеренно: если упадёт база, перезапуск
+// приложения не поможет, а оркестратор начал бы перезапускать все копии разом.
+func (app *application) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthReport{Status: statusOK})
+}
+
+// readyz - проверка готовности: можно ли направлять на эту копию трафик.
+// Возвращает 503, если база недоступна, её схема отстаёт от кода, шаблоны
+// не загружены или сервер уже завершает работу.
+func (app *application) readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]check{
+		"database":   app.checkDatabase(r.Context()),
+		"templates":  app.checkTemplates(),
+		"janitor":    app.checkJanitor(r.Context()),
+		"shutdown":   app.checkShutdown(),
+		"migrations": app.checkMigrations(r.Context()),
+	}
+
+	report := healthReport{Status: statusOK, Checks: checks}
+	status := http.StatusOK
+	for _, c := range checks {
+		if c.Status == statusFail {
+			report.Status = statusFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeHealth(w, status, report)
+}
+
+func (app *application) checkDatabase(ctx context.Context) check {
+	ctx, cancel := context.WithTimeout(ctx, readinessDBTimeout)
+	defer cancel()
+	start := time.Now()
+	err := app.health.Ping(ctx)
+	latency := float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		app.logger.ErrorContext(ctx, "readiness: database", "error", err)
+		return check{Status: statusFail, Error: errUnavailable, LatencyMS: &latency}
+	}
+	return check{Status: statusOK, LatencyMS: &latency}
+}
+
+func (app *application) checkMigrations(ctx context.Context) check {
+	ctx, cancel := context.WithTimeout(ctx, readinessDBTimeout)
+	defer cancel()
+	latest := app.latestMigration
+	version, err := app.health.SchemaVersion(ctx)
+	if err != nil {
+		app.logger.ErrorContext(ctx, "readiness: schema version", "error", err)
+		return check{Status: statusFail, Error: errUnavailable, Latest: &latest}
+	}
+	c := check{Status: statusOK, Version: &version, Latest: &latest}
+	if version < latest {
+		c.Status = statusFail
+		c.Error = "schema is behind the code; run with -migrate"
+	}
+	return c
+}
+
+func (app *application) checkTemplates() check {
+	n := len(app.templateCache)
+	if n == 0 {
+		return check{Status: statusFail, Error: "no templates loaded", Count: &n}
+	}
+	return check{Status: statusOK, Count: &n}
+}
+
+// checkJanitor только сообщает о состоянии очистки: её сбой не мешает
+// обслуживать запросы, поэтому копия из-за него не выводится из работы.
+func (app *application) checkJanitor(ctx context.Context) check {
+	if app.janitor == nil {
+		return check{Status: statusDisabled}
+	}
+	lastRun, err := app.janitor.status()
+	if lastRun.IsZero() {
+		return check{Status: statusPending}
+	}
+	c := check{Status: statusOK, LastRun: lastRun.UTC().Format(time.RFC3339)}
+	if err != nil {
+		app.logger.ErrorContext(ctx, "readiness: janitor", "error", err)
+		c.Error = errUnavailable
+	}
+	return c
+}
+
+func (app *application) checkShutdown() check {
+	if app.shuttingDown.Load() {
+		return check{Status: statusFail, Error: "server is shutting down"}
+	}
+	return check{Status: statusOK}
+}
+
+func writeHealth(w http.ResponseWriter, status int, report healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -4,16 +4,19 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/justinas/nosurf"
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/pkg/models"
 	"net/http"
 	"runtime/debug"
+	"strings"
 	"time"
 )
 
-// Помощник serverError записывает сообщение об ошибке в errorLog и
-// затем отправляет пользователю ответ 500 "Внутренняя ошибка сервера".
-func (app *application) serverError(w http.ResponseWriter, err error) {
-	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
-	app.errorLog.Output(2, trace)
+// Помощник serverError записывает сообщение об ошибке вместе со стеком вызовов
+// в лог и затем отправляет пользователю ответ 500 "Внутренняя ошибка сервера".
+// Запрос нужен, чтобы запись в логе получила его идентификатор.
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.ErrorContext(r.Context(), err.Error(), "trace", string(debug.Stack()))
 
 	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
@@ -35,9 +38,22 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, name stri
 	// Извлекаем соответствующий набор шаблонов из кэша в зависимости от названия страницы
 	// (например, 'home.page.tmpl'). Если в кэше нет записи запрашиваемого шаблона, то
 	// вызывается вспомогательный метод serverError(), который мы создали ранее.
-	ts, ok := app.templateCache[name]
+	cache := app.templateCache
+	if app.templates != nil {
+		var err error
+		if cache, err = app.templates.load(); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	page, ok := cache[name]
 	if !ok {
-		app.serverError(w, fmt.Errorf("Шаблон %s не существует!", name))
+		app.serverError(w, r, fmt.Errorf("Шаблон %s не существует!", name))
+		return
+	}
+	ts, err := page.forView(app.viewOf(r))
+	if err != nil {
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -46,9 +62,11 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, name stri
 	// Запишем шаблон в буфер, а не прямо в
 	// http.ResponseWriter. Если произошла ошибка, вызовите наш помощник serverError, а затем
 	// return.
-	err := ts.Execute(buf, app.addDefaultData(td, r))
+	start := time.Now()
+	err = ts.Execute(buf, app.addDefaultData(td, r))
+	app.metrics.renderDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
 	if err != nil {
-		app.serverError(w, err)
+		app.serverError(w, r, err)
 		return
 	}
 	// Запишет содержимое буфера в http.ResponseWriter. Опять же, это
@@ -57,6 +75,24 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, name stri
 	buf.WriteTo(w)
 }
 
+// notModified выставляет заголовки кэширования для страницы с данным ETag
+// и отвечает 304 Not Modified, если у клиента уже есть эта версия.
+// Страница зависит от сеанса, поэтому кэш должен учитывать cookie.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Add("Vary", "Cookie")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		// Сравнение слабое: сжатый ответ уходит со слабым ETag.
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
 // Создает помощник по добавлению данных по умолчанию. Это принимает указатель на TemplateData
 // struct, добавляет текущий год в поле currentYear, а затем возвращает
 // указатель. Опять же, мы не используем *http. Запрашивает параметр в
@@ -67,9 +103,36 @@ func (app *application) addDefaultData(td *templateData, r *http.Request) *templ
 	}
 
 	td.CSRFToken = nosurf.Token(r)
+	td.CSPNonce = cspNonce(r.Context())
 	td.CurrentYear = time.Now().Year()
 	td.Flash = app.session.PopString(r, "flash")
 	td.IsAuthenticated = app.isAuthenticated(r)
+	td.AuthenticatedUserID = app.authenticatedUserID(r)
+	if user := app.authenticatedUser(r); user != nil {
+		td.AuthenticatedUsername = user.Username
+		// Без счётчика страница всё равно полезна, поэтому ошибку
+		// достаточно записать в лог.
+		unread, err := app.notifications.Unread(user.ID)
+		if err != nil {
+			app.logger.ErrorContext(r.Context(), "unread notifications", "error", err, "user_id", user.ID)
+		}
+		td.UnreadNotifications = unread
+		messages, err := app.dms.Unread(user.ID)
+		if err != nil {
+			app.logger.ErrorContext(r.Context(), "unread messages", "error", err, "user_id", user.ID)
+		}
+		td.UnreadMessages = messages
+	}
+	td.ExpiryPolicy = app.expiry
+	td.Locale = app.localeOf(r)
+	td.TimeZone = app.zoneOf(r).String()
+	td.Locales = i18n.Supported
+	// После смены языка возвращаемся на текущую страницу. Страницу,
+	// показанную в ответ на POST (форму с ошибками), заново по GET не
+	// открыть, поэтому с неё переключатель ведёт на главную.
+	if r.Method == http.MethodGet {
+		td.CurrentPath = r.URL.RequestURI()
+	}
 	return td
 }
 
@@ -82,3 +145,19 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// Возвращает аутентифицированного пользователя или nil, если запрос
+// анонимный. Пользователя в контекст кладёт authenticate.
+func (app *application) authenticatedUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(contextKeyUser).(*models.User)
+	return user
+}
+
+// Возвращает идентификатор аутентифицированного пользователя или 0,
+// если запрос анонимный.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+	return app.session.GetInt(r, "authenticatedUserID")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"golangify.com/snippetbox/pkg/storage"
+)
+
+// janitor - фоновый процесс, который периодически удаляет (или архивирует)
+// истёкшие заметки. Get() и Latest() и так их не показывают, но без очистки
+// они навсегда остаются в таблице snippets. Файлы вложений удалённых
+// заметок стираются из storage.
+type janitor struct {
+	snippets interface {
+		PurgeExpired(int, bool) (int, []string, error)
+	}
+	storage   storage.Storage
+	interval  time.Duration
+	batchSize int
+	archive   bool
+	logger    *slog.Logger
+
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// Итог последнего прохода для проверки готовности.
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+// status возвращает время последнего прохода и его ошибку, если она была.
+func (j *janitor) status() (time.Time, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.lastRun, j.lastErr
+}
+
+// start запускает горутину очистки. Первый проход выполняется сразу,
+// последующие - через каждые interval.
+func (j *janitor) start() {
+	j.quit = make(chan struct{})
+	j.done = make(chan struct{})
+	go j.run()
+}
+
+// stop просит горутину завершиться и ждёт, пока она дочистит текущую пачку.
+// Повторные вызовы безопасны.
+func (j *janitor) stop() {
+	j.stopOnce.Do(func() {
+		close(j.quit)
+		<-j.done
+	})
+}
+
+func (j *janitor) run() {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge()
+		select {
+		case <-j.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge удаляет истёкшие заметки пачками по batchSize, пока не встретит
+// неполную пачку, ошибку или сигнал остановки. Большие DELETE одним запросом
+// надолго блокировали бы таблицу, поэтому работаем небольшими порциями.
+func (j *janitor) purge() int {
+	// При пачке меньше одной заметки условие n < batchSize никогда не
+	// выполнится и цикл не закончится, поэтому такую настройку не исполняем.
+	if j.batchSize < 1 {
+		j.logger.Error("janitor: invalid batch size", "batch", j.batchSize)
+		return 0
+	}
+	total := 0
+	var lastErr error
+	defer func() {
+		j.mu.Lock()
+		j.lastRun, j.lastErr = time.Now(), lastErr
+		j.mu.Unlock()
+	}()
+	for {
+		n, keys, err := j.snippets.PurgeExpired(j.batchSize, j.archive)
+		if err != nil {
+			j.logger.Error("janitor: purge failed", "error", err)
+			lastErr = err
+			break
+		}
+		// Записи о вложениях уже удалены, поэтому файл, который не удалось
+		// стереть, останется лишним, но ни на что не повлияет.
+		for _, key := range keys {
+			if err = j.storage.Delete(key); err != nil {
+				j.logger.Error("janitor: delete attachment", "error", err, "key", key)
+			}
+		}
+		total += n
+		if n < j.batchSize {
+			break
+		}
+		select {
+		case <-j.quit:
+			j.report(total)
+			return total
+		default:
+		}
+	}
+	j.report(total)
+	return total
+}
+
+func (j *janitor) report(n int) {
+	if n == 0 {
+		return
+	}
+	j.logger.Info("janitor: expired snippets purged", "count", n, "archived", j.archive)
+}
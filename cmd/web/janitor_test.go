@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golangify.com/snippetbox/pkg/storage"
+)
+
+// fakePurger imitates a table holding a fixed number of expired snippets.
+// Snippet n has a single attachment stored as "n.png".
+type fakePurger struct {
+	mu      sync.Mutex
+	expired int
+	calls   int
+	err     error
+}
+
+func (p *fakePurger) PurgeExpired(limit int, archive bool) (int, []string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.err != nil {
+		return 0, nil, p.err
+	}
+	n := limit
+	if p.expired < n {
+		n = p.expired
+	}
+	var keys []string
+	for i := 0; i < n; i++ {
+		keys = append(keys, fmt.Sprintf("%d.png", p.expired-i))
+	}
+	p.expired -= n
+	return n, keys, nil
+}
+
+func newTestStorage(t *testing.T) *storage.Local {
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestJanitorPurge(t *testing.T) {
+	tests := []struct {
+		name      string
+		expired   int
+		err       error
+		wantTotal int
+		wantCalls int
+		wantLog   string
+	}{
+		{"Nothing expired", 0, nil, 0, 1, ""},
+		{"Single batch", 3, nil, 3, 1, `"count":3`},
+		{"Several batches", 25, nil, 25, 3, `"count":25`},
+		{"Exact batches", 20, nil, 20, 3, `"count":20`},
+		{"Database error", 5, errors.New("boom"), 0, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &fakePurger{expired: tt.expired, err: tt.err}
+			var logBuf bytes.Buffer
+			j := &janitor{
+				snippets:  p,
+				storage:   newTestStorage(t),
+				batchSize: 10,
+				logger:    newLogger(&logBuf, slog.LevelInfo),
+				quit:      make(chan struct{}),
+			}
+			total := j.purge()
+			if total != tt.wantTotal {
+				t.Errorf("want total %d; got %d", tt.wantTotal, total)
+			}
+			if p.calls != tt.wantCalls {
+				t.Errorf("want %d calls; got %d", tt.wantCalls, p.calls)
+			}
+			if !strings.Contains(logBuf.String(), tt.wantLog) {
+				t.Errorf("want log to contain %q; got %q", tt.wantLog, logBuf.String())
+			}
+		})
+	}
+}
+
+func TestJanitorPurgeInvalidBatch(t *testing.T) {
+	for _, batchSize := range []int{0, -1} {
+		p := &fakePurger{expired: 5}
+		j := &janitor{
+			snippets:  p,
+			batchSize: batchSize,
+			logger:    newLogger(io.Discard, slog.LevelInfo),
+			quit:      make(chan struct{}),
+		}
+		if total := j.purge(); total != 0 || p.calls != 0 {
+			t.Errorf("batch %d: want no purge; got total %d after %d calls", batchSize, total, p.calls)
+		}
+	}
+}
+
+func TestJanitorDeletesAttachments(t *testing.T) {
+	files := newTestStorage(t)
+	for _, key := range []string{"1.png", "2.png", "3.png", "other.png"} {
+		files.Save(key, strings.NewReader(key))
+	}
+	j := &janitor{
+		snippets:  &fakePurger{expired: 3},
+		storage:   files,
+		batchSize: 2,
+		logger:    newLogger(io.Discard, slog.LevelInfo),
+		quit:      make(chan struct{}),
+	}
+	j.purge()
+
+	for _, key := range []string{"1.png", "2.png", "3.png"} {
+		if _, err := files.Open(key); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("want %s deleted; got %v", key, err)
+		}
+	}
+	f, err := files.Open("other.png")
+	if err != nil {
+		t.Fatalf("want other.png kept; got %v", err)
+	}
+	f.Close()
+}
+
+func TestJanitorStop(t *testing.T) {
+	p := &fakePurger{expired: 1}
+	j := &janitor{
+		snippets:  p,
+		storage:   newTestStorage(t),
+		interval:  time.Hour,
+		batchSize: 10,
+		logger:    newLogger(io.Discard, slog.LevelInfo),
+	}
+	j.start()
+
+	stopped := make(chan struct{})
+	go func() {
+		j.stop()
+		j.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not stop")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.expired != 0 {
+		t.Errorf("want first pass to run on start; %d snippets left", p.expired)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"golangify.com/snippetbox/pkg/i18n"
+)
+
+const contextKeyLocale = contextKey("locale")
+
+// detectLocale выбирает язык интерфейса для запроса. Побеждает первый
+// найденный вариант:
+//
+//  1. язык, сохранённый в профиле пользователя;
+//  2. язык, выбранный переключателем в этом сеансе;
+//  3. заголовок Accept-Language браузера;
+//  4. язык по умолчанию из настроек.
+//
+// Профиль читает authenticate, поэтому detectLocale идёт в цепочке после него.
+func (app *application) detectLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := app.locale
+		if l, ok := i18n.Match(r.Header.Get("Accept-Language")); ok {
+			locale = l
+		}
+		if l, ok := i18n.Parse(app.session.GetString(r, "locale")); ok {
+			locale = l
+		}
+		if user := app.authenticatedUser(r); user != nil {
+			if l, ok := i18n.Parse(user.Locale); ok {
+				locale = l
+			}
+		}
+		// Страница зависит от Accept-Language, и кэш должен это учитывать.
+		w.Header().Add("Vary", "Accept-Language")
+		ctx := context.WithValue(r.Context(), contextKeyLocale, locale)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// localeOf возвращает язык запроса. Для маршрутов без detectLocale это
+// язык по умолчанию.
+func (app *application) localeOf(r *http.Request) i18n.Locale {
+	if l, ok := r.Context().Value(contextKeyLocale).(i18n.Locale); ok {
+		return l
+	}
+	return app.locale
+}
+
+// printer переводит сообщения на язык запроса. Им локализуются ошибки
+// форм: forms.New(r.PostForm).Localize(app.printer(r)).
+func (app *application) printer(r *http.Request) *i18n.Printer {
+	return i18n.NewPrinter(app.localeOf(r))
+}
+
+// changeLocale обрабатывает переключатель языка: запоминает выбор в сеансе,
+// а у вошедшего пользователя ещё и в профиле, чтобы язык сохранился при
+// входе с другого устройства.
+func (app *application) changeLocale(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	locale, ok := i18n.Parse(r.PostForm.Get("locale"))
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	app.session.Put(r, "locale", string(locale))
+	if id := app.authenticatedUserID(r); id != 0 {
+		if err = app.users.SetLocale(id, string(locale)); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	http.Redirect(w, r, localRedirect(r.PostForm.Get("next")), http.StatusSeeOther)
+}
+
+// localRedirect возвращает path, если это адрес на нашем сайте, и "/" в
+// противном случае: иначе переключатель можно было бы использовать для
+// перенаправления на чужой сайт ("//evil.example", "/\evil.example").
+func localRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/ui"
+)
+
+// getWithLanguage makes a GET request with the given Accept-Language header.
+func (ts *testServer) getWithLanguage(t *testing.T, urlPath, acceptLanguage string) (int, http.Header, []byte) {
+	req, err := http.NewRequest(http.MethodGet, ts.URL+urlPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Language", acceptLanguage)
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.StatusCode, rs.Header, body
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{"Kazakh", "kk-KZ,kk;q=0.9", "Соңғы жазбалар"},
+		{"English", "en-US,en;q=0.9,ru;q=0.5", "Latest snippets"},
+		{"Unsupported falls back to default", "de-DE", "Последние заметки"},
+		{"No header", "", "Последние заметки"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.getWithLanguage(t, "/", tt.acceptLanguage)
+			if code != http.StatusOK {
+				t.Fatalf("want %d; got %d", http.StatusOK, code)
+			}
+			if !bytes.Contains(body, []byte(tt.want)) {
+				t.Errorf("want body to contain %q", tt.want)
+			}
+			if !regexp.MustCompile(`\bAccept-Language\b`).MatchString(strings.Join(header.Values("Vary"), ",")) {
+				t.Errorf("want Vary to include Accept-Language; got %q", header.Values("Vary"))
+			}
+		})
+	}
+}
+
+func TestChangeLocale(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		locale       string
+		next         string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Valid", "en", "/search?q=pond", http.StatusSeeOther, "/search?q=pond"},
+		{"Region is dropped", "kk-KZ", "/", http.StatusSeeOther, "/"},
+		{"Unsupported", "de", "/", http.StatusBadRequest, ""},
+		{"Open redirect", "en", "//evil.example", http.StatusSeeOther, "/"},
+		{"Absolute URL", "en", "https://evil.example/", http.StatusSeeOther, "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("locale", tt.locale)
+			form.Add("next", tt.next)
+			form.Add("csrf_token", csrfToken)
+			code, header, _ := ts.postForm(t, "/locale", form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want Location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+
+	// The choice made with the switcher beats the browser's language.
+	form := url.Values{}
+	form.Add("locale", "en")
+	form.Add("csrf_token", csrfToken)
+	ts.postForm(t, "/locale", form)
+	_, _, body = ts.getWithLanguage(t, "/", "kk")
+	if !bytes.Contains(body, []byte("Latest snippets")) || !bytes.Contains(body, []byte("<html lang='en'>")) {
+		t.Error("want the page in the chosen language")
+	}
+}
+
+func TestLocalizedFormErrors(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"en", "This field cannot be blank"},
+		{"ru", "Это поле не может быть пустым"},
+		{"kk", "Бұл өріс бос болмауы керек"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			app := newTestApplication(t)
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			_, _, body := ts.getWithLanguage(t, "/user/signup", tt.locale)
+			form := url.Values{}
+			form.Add("csrf_token", extractCSRFToken(t, body))
+			form.Add("locale", tt.locale)
+			ts.postForm(t, "/locale", form)
+
+			form.Del("locale")
+			code, _, body := ts.postForm(t, "/user/signup", form)
+			if code != http.StatusOK {
+				t.Fatalf("want %d; got %d", http.StatusOK, code)
+			}
+			if !bytes.Contains(body, []byte(tt.want)) {
+				t.Errorf("want body to contain %q", tt.want)
+			}
+		})
+	}
+}
+
+// tRX matches the messages passed to the t template function, both in
+// double quotes and in backquotes.
+var tRX = regexp.MustCompile("\\{\\{t (\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)")
+
+// TestTemplatesTranslated checks that every message used in the templates
+// has a translation in every catalog.
+func TestTemplatesTranslated(t *testing.T) {
+	htmlFiles, err := fs.Sub(ui.Files, "html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := fs.Glob(htmlFiles, "*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		data, err := fs.ReadFile(htmlFiles, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range tRX.FindAllSubmatch(data, -1) {
+			key, err := strconv.Unquote(string(m[1]))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			for _, l := range i18n.Supported {
+				if !i18n.NewPrinter(l).Has(key) {
+					t.Errorf("%s: %q has no %s translation", name, key, l)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+
+	"golangify.com/snippetbox/pkg/mail"
+)
+
+// Сколько писем может ждать отправки. Если очередь полна, новые письма
+// отбрасываются: уведомление в приложении всё равно останется.
+const mailQueueSize = 100
+
+// mailer отправляет письма в фоновой горутине, чтобы медленный
+// SMTP-сервер не задерживал ответы на запросы.
+type mailer struct {
+	sender mail.Sender
+	logger *slog.Logger
+
+	queue    chan mail.Message
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newMailer создаёт mailer и запускает горутину отправки.
+func newMailer(sender mail.Sender, logger *slog.Logger) *mailer {
+	m := &mailer{
+		sender: sender,
+		logger: logger,
+		queue:  make(chan mail.Message, mailQueueSize),
+		done:   make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// send ставит письмо в очередь и сразу возвращается.
+func (m *mailer) send(msg mail.Message) {
+	select {
+	case m.queue <- msg:
+	default:
+		m.logger.Error("mailer: queue is full, message dropped", "subject", msg.Subject)
+	}
+}
+
+// stop дожидается отправки писем из очереди. После stop вызывать send
+// нельзя; повторные вызовы stop безопасны.
+func (m *mailer) stop() {
+	m.stopOnce.Do(func() {
+		close(m.queue)
+		<-m.done
+	})
+}
+
+func (m *mailer) run() {
+	defer close(m.done)
+	for msg := range m.queue {
+		if err := m.sender.Send(msg); err != nil {
+			m.logger.Error("mailer: send failed", "error", err, "subject", msg.Subject)
+		}
+	}
+}
@@ -1,69 +1,276 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql" // Новый импорт
+	"errors"
 	"flag"
 	"github.com/golangcollege/sessions"
+	"golang.org/x/crypto/acme"
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/pkg/mail"
 	"golangify.com/snippetbox/pkg/models"
 	"golangify.com/snippetbox/pkg/models/mysql"
-	"html/template"
-	"log"
+	"golangify.com/snippetbox/pkg/pubsub"
+	"golangify.com/snippetbox/pkg/ratelimit"
+	"golangify.com/snippetbox/pkg/search"
+	"golangify.com/snippetbox/pkg/storage"
+	"golangify.com/snippetbox/ui"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
+	// База часовых поясов встраивается в бинарник: в минимальных
+	// контейнерах нет /usr/share/zoneinfo.
+	_ "time/tzdata"
 
 	_ "github.com/go-sql-driver/mysql" // Новый импорт
 )
 
 type contextKey string
 
-const contextKeyIsAuthenticated = contextKey("isAuthenticated")
+const (
+	contextKeyIsAuthenticated = contextKey("isAuthenticated")
+	contextKeyUser            = contextKey("user")
+)
 
 type application struct {
-	errorLog *log.Logger
-	infoLog  *log.Logger
+	// assets - статические файлы; uiVersion меняется вместе с любым
+	// шаблоном или статическим файлом и входит в ETag страниц.
+	assets      *assets
+	attachments interface {
+		Insert(*models.Attachment) (int, error)
+		Get(int) (*models.Attachment, error)
+		ForSnippet(int) ([]*models.Attachment, error)
+	}
+	comments interface {
+		Insert(int, int, string) (int, error)
+		ForSnippet(int) ([]*models.Comment, error)
+		ByUser(int, int) ([]*models.Comment, error)
+	}
+	// dms - личные переписки и блокировки.
+	dms interface {
+		Find([]int) (int, error)
+		Start(int, []int, string) (int, error)
+		Send(int, int, string) (int, error)
+		Get(int, int) (*models.Conversation, error)
+		Conversations(int, int) ([]*models.Conversation, error)
+		Messages(int, int) ([]*models.Message, error)
+		MarkRead(int, int) error
+		Unread(int) (int, error)
+		Block(int, int) error
+		Unblock(int, int) error
+		Blocks(int) ([]models.Participant, error)
+		BlockedBy(int, []int) ([]int, error)
+	}
+	// events рассылает изменения открытым страницам (Server-Sent Events).
+	events pubsub.Broker
+	// certs перечитывает сертификат из файлов; nil, если сертификаты
+	// выпускаются по ACME.
+	certs *certReloader
+	// health и latestMigration нужны проверке готовности /readyz.
+	health interface {
+		Ping(context.Context) error
+		SchemaVersion(context.Context) (int, error)
+	}
+	janitor *janitor
+	// mailer отправляет письма с уведомлениями; nil, если SMTP не настроен.
+	// baseURL - адрес сайта для ссылок в письмах.
+	mailer  *mailer
+	baseURL string
+	// limiter ограничивает частоту запросов; nil отключает ограничения.
+	limiter        ratelimit.Limiter
+	trustedProxies []netip.Prefix
+	// locale и timeZone - язык и часовой пояс для тех, кто не выбрал свои.
+	locale          i18n.Locale
+	timeZone        *time.Location
+	latestMigration int
+	logger          *slog.Logger
+	metrics         *metrics
+	// Логин и пароль для /metrics на основном адресе; пустой пароль
+	// скрывает этот маршрут.
+	metricsUser     string
+	metricsPassword string
+	notifications   interface {
+		Insert(*models.Notification) (int, error)
+		Once(int, string, int, int) (bool, error)
+		Latest(int, int) ([]*models.Notification, error)
+		Unread(int) (int, error)
+		MarkRead(int, int) error
+		MarkAllRead(int) error
+		Preferences(int) (map[string]models.NotificationPreference, error)
+		SetPreferences(int, []models.NotificationPreference) error
+	}
+	polls interface {
+		Insert(int, bool, time.Time, []string) error
+		Get(int, int) (*models.Poll, error)
+		Vote(int, int, []int) error
+	}
+	profiles interface {
+		Get(int) (*models.Profile, error)
+		ByUsername(string) (*models.Profile, error)
+		Update(int, string, string) error
+	}
+	saved interface {
+		Save(int, int, int, int) error
+		Unsave(int, int, int) error
+		Saved(int, int) (map[int]bool, error)
+		Items(int, int, int, int) ([]*models.SavedItem, error)
+		Collections(int) ([]*models.Collection, error)
+		CreateCollection(int, string) (int, error)
+		DeleteCollection(int, int) error
+	}
 	session  *sessions.Session
+	expiry   expiryPolicy
+	headers  securityHeaders
 	snippets interface {
-		Insert(string, string, string) (int, error)
+		Insert(int, string, string, int) (int, error)
 		Get(int) (*models.Snippet, error)
 		Latest() ([]*models.Snippet, error)
+		ByUser(int, int) ([]*models.Snippet, error)
+		UpdateExpiry(int, int, int) error
+		Delete(int) error
 	}
-	templateCache map[string]*template.Template
-	users         interface {
-		Insert(string, string, string) error
+	search search.Index
+	tags   interface {
+		Set(int, []string) error
+		Snippets(string, int, int) ([]*models.Snippet, error)
+	}
+	// shuttingDown выставляется при остановке, чтобы /readyz сразу начал
+	// отвечать 503 и балансировщик перестал присылать новые запросы.
+	shuttingDown  atomic.Bool
+	storage       storage.Storage
+	templateCache map[string]*pageTemplate
+	// templates перечитывает шаблоны с диска в режиме -dev; nil в обычном
+	// режиме, когда используется templateCache.
+	templates *templateReloader
+	uiVersion string
+	uploads   uploadPolicy
+	users     interface {
+		Insert(string, string, string, string) error
 		Authenticate(string, string) (int, error)
 		Get(int) (*models.User, error)
+		SetLocale(int, string) error
+		SetTimeZone(int, string) error
+		SetUsername(int, string) error
+	}
+	votes interface {
+		Vote(int, int, int, int) error
+		Score(int) (int, error)
 	}
 }
 
 func main() {
-	addr := flag.String("addr", ":4000", "Сетевой адрес веб-сервера")
-	// Определение нового флага из командной строки для настройки MySQL подключения.
-	dsn := flag.String("dsn", "web:pass@/snippetbox?parseTime=true", "Название MySQL источника данных")
-	flag.Parse()
+	// До загрузки настроек уровень логирования ещё неизвестен.
+	logger := newLogger(os.Stderr, slog.LevelInfo)
 
-	// Определяем новый флаг командной строки для секрета сеанса (случайный ключ, который
-	// будет использоваться для шифрования и аутентификации сеансовых файлов cookie).
-	// Это должно быть 32 байт длиной.
-	secret := flag.String("secret", "s6Ndh+pPbnzHbS*+9Pk8qGWhTzbpa@ge", "Secret key")
-	flag.Parse()
+	// Команда `web config print [флаги]` показывает действующие настройки
+	// (с учётом файла, окружения и флагов) и завершает работу.
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		cfg, err := loadConfig(os.Args[3:], os.LookupEnv, os.Stderr)
+		if err != nil {
+			fatal(logger, err)
+		}
+		cfg.print(os.Stdout)
+		if err = cfg.validate(); err != nil {
+			fatal(logger, err)
+		}
+		return
+	}
 
-	infoLog := log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime)
-	errorLog := log.New(os.Stderr, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
+	cfg, err := loadConfig(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal(logger, err)
+	}
+	// Все записи, включая журнал запросов, пишутся в stdout одной JSON-строкой
+	// на запись.
+	logger = newLogger(os.Stdout, cfg.LogLevel)
+	// Проверяем настройки до подключения к базе, чтобы не запускаться
+	// с заведомо неправильной конфигурацией.
+	if err = cfg.validate(); err != nil {
+		fatal(logger, err)
+	}
 
-	db, err := openDB(*dsn)
+	db, err := openDB(cfg.DSN)
 	if err != nil {
-		errorLog.Fatal(err)
+		fatal(logger, err)
 	}
 
 	defer db.Close()
 
-	templateCache, err := newTemplateCache("./ui/html/")
+	if cfg.Migrate {
+		n, err := mysql.Migrate(db)
+		if err != nil {
+			fatal(logger, err)
+		}
+		logger.Info("migrations applied", "count", n)
+	}
+
+	latestMigration, err := mysql.LatestVersion()
+	if err != nil {
+		fatal(logger, err)
+	}
+
+	// Шаблоны и статические файлы встроены в бинарник. В режиме -dev они
+	// читаются с диска (программу нужно запускать из корня репозитория),
+	// а шаблоны перечитываются при изменении.
+	var uiFiles fs.FS = ui.Files
+	if cfg.Dev {
+		uiFiles = os.DirFS("./ui")
+	}
+	htmlFiles, err := fs.Sub(uiFiles, "html")
+	if err != nil {
+		fatal(logger, err)
+	}
+	staticFiles, err := fs.Sub(uiFiles, "static")
+	if err != nil {
+		fatal(logger, err)
+	}
+	static, err := newAssets(staticFiles, cfg.Dev)
+	if err != nil {
+		fatal(logger, err)
+	}
+	templateCache, err := newTemplateCache(htmlFiles, static)
+	if err != nil {
+		fatal(logger, err)
+	}
+	var templates *templateReloader
+	if cfg.Dev {
+		if templates, err = newTemplateReloader(htmlFiles, static); err != nil {
+			fatal(logger, err)
+		}
+	}
+	version, err := uiVersion(htmlFiles, static)
 	if err != nil {
-		errorLog.Fatal(err)
+		fatal(logger, err)
 	}
 
+	// Язык и часовой пояс уже проверены в validate.
+	locale, _ := i18n.Parse(cfg.Locale)
+	timeZone, err := loadZone(cfg.TimeZone)
+	if err != nil {
+		fatal(logger, err)
+	}
+
+	files, err := storage.NewLocal(cfg.UploadDir)
+	if err != nil {
+		fatal(logger, err)
+	}
+
+	// Потоки событий живут, пока открыта страница; при остановке сервера
+	// брокер закрывает их (см. RegisterOnShutdown ниже).
+	events := pubsub.NewMemory()
+
 	// Используем sessions.New() функция для инициализации нового диспетчера сеансов,
 	// передавая секретный ключ в качестве параметра.
 	//Затем мы настраиваем его так, чтобы сеансы всегда истекали через 12 часов.
@@ -71,38 +278,97 @@ func main() {
 	//configuration settings for the session. In the code above we’ve set the Lifetime field
 	//of this struct so that sessions expire after 12 hours, but there’s a range of other fields
 	//that you can and should configure depending on your application’s needs.
-	session := sessions.New([]byte(*secret))
+	session := sessions.New([]byte(cfg.Secret))
 	session.Lifetime = 12 * time.Hour
 
 	// Инициализируем экземпляр модели mysql и добавляем его в зависимости приложения.
 	app := &application{
-		errorLog:      errorLog,
-		infoLog:       infoLog,
-		session:       session,
-		snippets:      &mysql.SnippetModel{DB: db},
-		templateCache: templateCache,
-		users:         &mysql.UserModel{DB: db},
-	}
-
-	// Инициализируем структуру tls.Config для хранения настроек TLS,
-	// отличных от стандартных, которые мы хотим использовать на сервере.
-	tlsConfig := &tls.Config{
-		MinVersion:               tls.VersionTLS12,
-		MaxVersion:               tls.VersionTLS12,
-		PreferServerCipherSuites: true,
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		assets:      static,
+		attachments: &mysql.AttachmentModel{DB: db},
+		baseURL:     strings.TrimSuffix(cfg.BaseURL, "/"),
+		comments:    &mysql.CommentModel{DB: db},
+		dms:         &mysql.DMModel{DB: db},
+		events:      events,
+		health:      &mysql.HealthModel{DB: db},
+		expiry: expiryPolicy{
+			MinDays:        cfg.ExpiryMin,
+			MaxDays:        cfg.ExpiryMax,
+			AllowPermanent: cfg.ExpiryPermanent,
+		},
+		headers: securityHeaders{
+			CSP:           cfg.CSP,
+			CSPReportOnly: cfg.CSPReportOnly,
+			HSTSMaxAge:    cfg.HSTSMaxAge,
 		},
+		latestMigration: latestMigration,
+		locale:          locale,
+		timeZone:        timeZone,
+		logger:          logger,
+		metrics:         newMetrics(db),
+		metricsUser:     cfg.MetricsUser,
+		metricsPassword: cfg.MetricsPassword,
+		notifications:   &mysql.NotificationModel{DB: db},
+		polls:           &mysql.PollModel{DB: db},
+		profiles:        &mysql.ProfileModel{DB: db},
+		saved:           &mysql.SavedModel{DB: db},
+		session:         session,
+		search:          &mysql.SearchIndex{DB: db},
+		snippets:        &mysql.SnippetModel{DB: db},
+		storage:         files,
+		tags:            &mysql.TagModel{DB: db},
+		templateCache:   templateCache,
+		templates:       templates,
+		uiVersion:       version,
+		uploads: uploadPolicy{
+			MaxFileSize: cfg.UploadMaxSize << 20,
+			MaxFiles:    cfg.UploadMaxFiles,
+		},
+		users: &mysql.UserModel{DB: db},
+		votes: &mysql.VoteModel{DB: db},
+	}
+
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		fatal(logger, err)
+	}
+	app.trustedProxies = trustedProxies
+	if cfg.RateLimit {
+		// Раз в минуту забываем клиентов, чьи вёдра уже снова полные.
+		limiter := ratelimit.NewMemory(time.Minute)
+		defer limiter.Close()
+		app.limiter = limiter
+	}
+
+	// Сертификат берётся либо из файлов (и перечитывается при их изменении
+	// или по SIGHUP), либо выпускается автоматически по ACME.
+	var tlsConfig *tls.Config
+	// HTTP-адрес перенаправляет на HTTPS; при ACME он же отвечает на
+	// проверки http-01.
+	httpHandler := redirectHTTPS(cfg.Addr)
+	stopWatching := make(chan struct{})
+	if len(cfg.ACMEDomains) > 0 {
+		m, err := newACMEManager(cfg)
+		if err != nil {
+			fatal(logger, err)
+		}
+		tlsConfig = newTLSConfig(m.GetCertificate)
+		// Протокол проверки tls-alpn-01.
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, acme.ALPNProto)
+		httpHandler = m.HTTPHandler(httpHandler)
+	} else {
+		app.certs, err = newCertReloader(cfg.TLSCert, cfg.TLSKey, logger)
+		if err != nil {
+			fatal(logger, err)
+		}
+		tlsConfig = newTLSConfig(app.certs.GetCertificate)
+		if cfg.TLSReloadInterval > 0 {
+			go app.certs.watch(cfg.TLSReloadInterval, stopWatching)
+		}
 	}
 
 	srv := &http.Server{
-		Addr:      *addr,
-		ErrorLog:  errorLog,
+		Addr:      cfg.Addr,
+		ErrorLog:  slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		Handler:   app.routes(),
 		TLSConfig: tlsConfig,
 		// Добавим тайм-ауты ожидания, чтения и записи на сервер.
@@ -111,12 +377,111 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	infoLog.Printf("Запуск сервера на https://localhost%s/", *addr)
-	// Используем метод ListenAndServeTLS() для запуска HTTPS-сервера. Мы
-	// передаем пути к tls-сертификату и соответствующему секретному ключу в качестве
-	// двух параметров.
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	errorLog.Fatal(err)
+	// Shutdown ждёт завершения всех запросов, а потоки событий сами не
+	// завершаются: закрываем их, как только началась остановка.
+	srv.RegisterOnShutdown(events.Close)
+
+	// Запускаем фоновую очистку истёкших заметок.
+	var j *janitor
+	if cfg.PurgeInterval > 0 {
+		j = &janitor{
+			snippets:  &mysql.SnippetModel{DB: db},
+			storage:   files,
+			interval:  cfg.PurgeInterval,
+			batchSize: cfg.PurgeBatch,
+			archive:   cfg.PurgeArchive,
+			logger:    logger,
+		}
+		j.start()
+		app.janitor = j
+	}
+
+	// Письма отправляются в фоне, только если указан SMTP-сервер.
+	if cfg.SMTPAddr != "" {
+		app.mailer = newMailer(&mail.SMTP{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}, logger)
+	}
+
+	// Отдельный адрес для метрик слушает обычный HTTP: он предназначен для
+	// внутренней сети, куда не попадают пользователи.
+	var metricsSrv *http.Server
+	if cfg.MetricsAddr != "" {
+		metricsSrv = &http.Server{
+			Addr:        cfg.MetricsAddr,
+			ErrorLog:    slog.NewLogLogger(logger.Handler(), slog.LevelError),
+			Handler:     app.metrics.handler(),
+			ReadTimeout: 5 * time.Second,
+		}
+		go func() {
+			logger.Info("starting metrics server", "addr", cfg.MetricsAddr)
+			if err := metricsSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("metrics server", "error", err)
+			}
+		}()
+	}
+
+	var httpSrv *http.Server
+	if cfg.HTTPAddr != "" {
+		httpSrv = &http.Server{
+			Addr:         cfg.HTTPAddr,
+			ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
+			Handler:      httpHandler,
+			IdleTimeout:  time.Minute,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+		go func() {
+			logger.Info("starting HTTP redirect server", "addr", cfg.HTTPAddr)
+			if err := httpSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("HTTP redirect server", "error", err)
+			}
+		}()
+	}
+
+	ln, inherited, err := listen(cfg.Addr)
+	if err != nil {
+		fatal(logger, err)
+	}
+	// Подписываемся на сигналы до того, как сообщить родителю о готовности,
+	// чтобы не пропустить SIGTERM, который придёт при следующем перезапуске.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	if inherited {
+		logger.Info("listener inherited from previous process", "addr", ln.Addr().String())
+		if err = notifyParent(); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	logger.Info("starting server", "addr", cfg.Addr)
+	// app.serve обслуживает HTTPS-запросы на ln (сертификат берётся из
+	// TLSConfig) и по сигналам плавно останавливает или перезапускает сервер.
+	err = app.serve(srv, ln, cfg, signals)
+	// Запросы больше не обрабатываются: останавливаем фоновые процессы.
+	// Пул соединений с базой закроет отложенный db.Close().
+	if j != nil {
+		j.stop()
+	}
+	if app.mailer != nil {
+		app.mailer.stop()
+	}
+	close(stopWatching)
+	if metricsSrv != nil {
+		metricsSrv.Close()
+	}
+	if httpSrv != nil {
+		httpSrv.Close()
+	}
+	if err != nil {
+		logger.Error(err.Error())
+		db.Close()
+		os.Exit(1)
+	}
+	logger.Info("server stopped")
 }
 
 // Функция openDB() обертывает sql.Open() и возвращает пул соединений sql.DB
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/username"
+)
+
+// Предельная длина личного сообщения в символах и число получателей, с
+// которыми можно начать одну переписку.
+const (
+	messageMaxLength = 5000
+	maxRecipients    = 10
+)
+
+// Сколько переписок показывается во входящих и сколько последних
+// сообщений - на странице переписки.
+const (
+	inboxSize        = 50
+	conversationSize = 100
+)
+
+// conversationURL возвращает адрес страницы переписки.
+func conversationURL(id int) string {
+	return fmt.Sprintf("/messages/%d", id)
+}
+
+// publishMessages сообщает открытым страницам пользователя новое число
+// переписок с непрочитанными сообщениями.
+func (app *application) publishMessages(r *http.Request, userID int) {
+	unread, err := app.dms.Unread(userID)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "unread messages", "error", err, "user_id", userID)
+		return
+	}
+	app.publish(r, userTopic(userID), "messages", map[string]int{"count": unread})
+}
+
+// inbox показывает переписки пользователя и тех, кого он заблокировал.
+func (app *application) inbox(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	conversations, err := app.dms.Conversations(userID, inboxSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	blocked, err := app.dms.Blocks(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.render(w, r, "inbox.page.tmpl", &templateData{
+		Conversations: conversations,
+		BlockedUsers:  blocked,
+	})
+}
+
+// newMessageForm показывает форму новой переписки. Получателя можно
+// подставить заранее: /messages/new?to=bob.
+func (app *application) newMessageForm(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "newmessage.page.tmpl", &templateData{
+		Form: forms.New(url.Values{"to": {r.URL.Query().Get("to")}}),
+	})
+}
+
+// splitRecipients разбирает поле "Кому": имена пользователей через
+// запятую или пробел, с @ или без. Повторы убираются.
+func splitRecipients(to string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, f := range strings.FieldsFunc(to, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		name := username.Normalize(f)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// startConversation отправляет первое сообщение. Если переписка с теми же
+// участниками уже есть, сообщение добавляется в неё, а не создаёт новую.
+func (app *application) startConversation(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("to", "content")
+	form.MaxLength("content", messageMaxLength)
+
+	userID := app.authenticatedUserID(r)
+	names := splitRecipients(form.Get("to"))
+	if len(names) > maxRecipients {
+		form.AddError("to", "You can message at most %d users at once", maxRecipients)
+	}
+	var recipients []int
+	byID := map[int]string{}
+	if form.Errors.Get("to") == "" {
+		for _, name := range names {
+			p, err := app.profiles.ByUsername(name)
+			if errors.Is(err, models.ErrNoRecord) {
+				form.AddError("to", "Unknown user: %s", name)
+				continue
+			}
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			// Себя в получатели добавлять незачем: отправитель и так
+			// участник переписки.
+			if p.ID == userID || byID[p.ID] != "" {
+				continue
+			}
+			byID[p.ID] = p.Username
+			recipients = append(recipients, p.ID)
+		}
+		if len(names) > 0 && len(recipients) == 0 && form.Errors.Get("to") == "" {
+			form.AddError("to", "Add at least one other user")
+		}
+	}
+	if form.Valid() {
+		blockedBy, err := app.dms.BlockedBy(userID, recipients)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		for _, id := range blockedBy {
+			form.AddError("to", "%s doesn't accept messages from you", byID[id])
+		}
+	}
+	if !form.Valid() {
+		app.render(w, r, "newmessage.page.tmpl", &templateData{Form: form})
+		return
+	}
+
+	id, err := app.dms.Find(append([]int{userID}, recipients...))
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		id, err = app.dms.Start(userID, recipients, form.Get("content"))
+	case err == nil:
+		_, err = app.dms.Send(id, userID, form.Get("content"))
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for _, recipient := range recipients {
+		app.publishMessages(r, recipient)
+	}
+	http.Redirect(w, r, conversationURL(id), http.StatusSeeOther)
+}
+
+// conversationFromPath возвращает переписку по :id из адреса, если
+// пользователь в ней участвует. Иначе ответ уже отправлен и возвращается
+// nil.
+func (app *application) conversationFromPath(w http.ResponseWriter, r *http.Request) *models.Conversation {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return nil
+	}
+	c, err := app.dms.Get(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil
+	}
+	return c
+}
+
+// conversationPage собирает данные страницы переписки.
+func (app *application) conversationPage(c *models.Conversation) (*templateData, error) {
+	messages, err := app.dms.Messages(c.ID, conversationSize)
+	if err != nil {
+		return nil, err
+	}
+	return &templateData{Conversation: c, Messages: messages}, nil
+}
+
+// showConversation показывает переписку и отмечает её прочитанной.
+func (app *application) showConversation(w http.ResponseWriter, r *http.Request) {
+	c := app.conversationFromPath(w, r)
+	if c == nil {
+		return
+	}
+	td, err := app.conversationPage(c)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if c.Unread > 0 {
+		userID := app.authenticatedUserID(r)
+		if err = app.dms.MarkRead(c.ID, userID); err != nil {
+			app.logger.ErrorContext(r.Context(), "mark conversation read", "error", err, "conversation_id", c.ID)
+		} else {
+			app.publishMessages(r, userID)
+		}
+	}
+	app.render(w, r, "conversation.page.tmpl", td)
+}
+
+// sendMessage отвечает в переписке. Если кто-то из участников
+// заблокировал отправителя, ответить нельзя.
+func (app *application) sendMessage(w http.ResponseWriter, r *http.Request) {
+	c := app.conversationFromPath(w, r)
+	if c == nil {
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("content")
+	form.MaxLength("content", messageMaxLength)
+
+	userID := app.authenticatedUserID(r)
+	members := make([]int, 0, len(c.Participants))
+	for _, p := range c.Participants {
+		members = append(members, p.ID)
+	}
+	blockedBy, err := app.dms.BlockedBy(userID, members)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(blockedBy) > 0 {
+		form.AddError("content", "Someone in this conversation doesn't accept messages from you")
+	}
+	if !form.Valid() {
+		td, err := app.conversationPage(c)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Form = form
+		app.render(w, r, "conversation.page.tmpl", td)
+		return
+	}
+
+	if _, err = app.dms.Send(c.ID, userID, form.Get("content")); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for _, id := range members {
+		app.publishMessages(r, id)
+	}
+	http.Redirect(w, r, conversationURL(c.ID), http.StatusSeeOther)
+}
+
+// blockUser запрещает владельцу профиля писать текущему пользователю.
+// Уже начатые переписки остаются, но ответить в них он не сможет.
+func (app *application) blockUser(w http.ResponseWriter, r *http.Request) {
+	p := app.profileFromPath(w, r)
+	if p == nil {
+		return
+	}
+	userID := app.authenticatedUserID(r)
+	if p.ID == userID {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	if err := app.dms.Block(userID, p.ID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "User blocked.")
+	http.Redirect(w, r, profileURL(p.Username), http.StatusSeeOther)
+}
+
+// unblockUser снимает блокировку.
+func (app *application) unblockUser(w http.ResponseWriter, r *http.Request) {
+	p := app.profileFromPath(w, r)
+	if p == nil {
+		return
+	}
+	if err := app.dms.Unblock(app.authenticatedUserID(r), p.ID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "User unblocked.")
+	http.Redirect(w, r, profileURL(p.Username), http.StatusSeeOther)
+}
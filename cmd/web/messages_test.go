@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/models/mock"
+)
+
+func TestInbox(t *testing.T) {
+	app := newTestApplication(t)
+	app.dms = &mock.DMModel{Blocked: map[int][]int{1: {2}}}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, _ := ts.get(t, "/messages")
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/login" {
+		t.Fatalf("want redirect to /user/login; got %d %q", code, header.Get("Location"))
+	}
+
+	ts.login(t)
+	code, _, body := ts.get(t, "/messages")
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	for _, want := range []string{
+		"<li class='unread'>",
+		"href='/messages/1'",
+		"Nice haiku!",
+		"<span class='badge' data-count='messages'>1</span>",
+		"action='/u/bob/unblock'",
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+}
+
+func TestSplitRecipients(t *testing.T) {
+	got := splitRecipients(" @Bob, alice  bob,,carol ")
+	want := []string{"bob", "alice", "carol"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestStartConversation(t *testing.T) {
+	tests := []struct {
+		name         string
+		to           string
+		content      string
+		blocked      map[int][]int
+		wantCode     int
+		wantLocation string
+		wantStarted  int
+		wantSent     int
+		wantError    string
+	}{
+		{"Existing conversation", "@Bob", "Hello again", nil, http.StatusSeeOther, "/messages/1", 0, 1, ""},
+		{"Empty content", "bob", "", nil, http.StatusOK, "", 0, 0, "Это поле не может быть пустым"},
+		{"Unknown user", "bob, carol", "Hi", nil, http.StatusOK, "", 0, 0, "Нет такого пользователя: carol"},
+		{"Only self", "alice", "Hi", nil, http.StatusOK, "", 0, 0, "Добавьте хотя бы одного другого пользователя"},
+		{"Too many", strings.Repeat("bob ", 5) + "a1 a2 a3 a4 a5 a6 a7 a8 a9 a10", "Hi", nil, http.StatusOK, "", 0, 0, "не более чем 10"},
+		{"Blocked", "bob", "Hi", map[int][]int{2: {1}}, http.StatusOK, "", 0, 0, "bob не принимает от вас сообщения"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			dms := &mock.DMModel{Blocked: tt.blocked}
+			app.dms = dms
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+
+			csrfToken := ts.login(t)
+			form := url.Values{"csrf_token": {csrfToken}, "to": {tt.to}, "content": {tt.content}}
+			code, header, body := ts.postForm(t, "/messages/new", form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want Location %q; got %q", tt.wantLocation, loc)
+			}
+			if len(dms.Started) != tt.wantStarted || len(dms.Sent) != tt.wantSent {
+				t.Errorf("want %d started and %d sent; got %d and %d", tt.wantStarted, tt.wantSent, len(dms.Started), len(dms.Sent))
+			}
+			if tt.wantError != "" && !bytes.Contains(body, []byte(tt.wantError)) {
+				t.Errorf("want body to contain %q", tt.wantError)
+			}
+		})
+	}
+}
+
+func TestShowConversation(t *testing.T) {
+	app := newTestApplication(t)
+	dms := &mock.DMModel{}
+	app.dms = dms
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	ts.login(t)
+	code, _, body := ts.get(t, "/messages/1")
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	for _, want := range []string{"Hi Bob", "Nice haiku!", "class='message own'", "action='/messages/1'"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+	if !reflect.DeepEqual(dms.Read, []int{1}) {
+		t.Errorf("want conversation 1 marked read; got %v", dms.Read)
+	}
+
+	// Other people's conversations look the same as missing ones.
+	for _, urlPath := range []string{"/messages/2", "/messages/0", "/messages/foo"} {
+		if code, _, _ := ts.get(t, urlPath); code != http.StatusNotFound {
+			t.Errorf("%s: want %d; got %d", urlPath, http.StatusNotFound, code)
+		}
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	app := newTestApplication(t)
+	dms := &mock.DMModel{}
+	app.dms = dms
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+	form := url.Values{"csrf_token": {csrfToken}, "content": {"See you"}}
+	code, header, _ := ts.postForm(t, "/messages/1", form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/messages/1" {
+		t.Fatalf("want redirect to /messages/1; got %d %q", code, header.Get("Location"))
+	}
+	if len(dms.Sent) != 1 || dms.Sent[0].Content != "See you" || dms.Sent[0].SenderID != 1 {
+		t.Errorf("want the message sent; got %+v", dms.Sent)
+	}
+
+	// Once Bob blocks Alice she can no longer reply.
+	dms.Block(2, 1)
+	code, _, body := ts.postForm(t, "/messages/1", form)
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	if !bytes.Contains(body, []byte("не принимает от вас сообщения")) {
+		t.Error("want a blocked error")
+	}
+	if len(dms.Sent) != 1 {
+		t.Errorf("want no new message; got %d", len(dms.Sent))
+	}
+
+	if code, _, _ := ts.postForm(t, "/messages/2", form); code != http.StatusNotFound {
+		t.Errorf("want %d; got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestBlockUser(t *testing.T) {
+	app := newTestApplication(t)
+	dms := &mock.DMModel{}
+	app.dms = dms
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	csrfToken := ts.login(t)
+	_, _, body := ts.get(t, "/u/bob")
+	for _, want := range []string{"href='/messages/new?to=bob'", "action='/u/bob/block'"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want profile to contain %q", want)
+		}
+	}
+
+	form := url.Values{"csrf_token": {csrfToken}}
+	code, header, _ := ts.postForm(t, "/u/bob/block", form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/u/bob" {
+		t.Fatalf("want redirect to /u/bob; got %d %q", code, header.Get("Location"))
+	}
+	if !reflect.DeepEqual(dms.Blocked[1], []int{2}) {
+		t.Errorf("want bob blocked; got %v", dms.Blocked)
+	}
+	_, _, body = ts.get(t, "/u/bob")
+	if !bytes.Contains(body, []byte("action='/u/bob/unblock'")) || bytes.Contains(body, []byte("/messages/new?to=bob")) {
+		t.Error("want an unblock button and no message link")
+	}
+
+	if code, _, _ := ts.postForm(t, "/u/bob/unblock", form); code != http.StatusSeeOther {
+		t.Fatalf("want %d; got %d", http.StatusSeeOther, code)
+	}
+	if len(dms.Blocked[1]) != 0 {
+		t.Errorf("want bob unblocked; got %v", dms.Blocked)
+	}
+
+	// Blocking yourself makes no sense.
+	if code, _, _ := ts.postForm(t, "/u/alice/block", form); code != http.StatusBadRequest {
+		t.Errorf("want %d; got %d", http.StatusBadRequest, code)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bmizerany/pat"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const contextKeyRoute = contextKey("route")
+
+// Метка маршрута для запросов, которые не совпали ни с одним шаблоном.
+// Сырой путь в метку не пишем: каждый новый URL создавал бы новый временной
+// ряд, и сканер, перебирающий адреса, раздул бы хранилище метрик.
+const unmatchedRoute = "unmatched"
+
+// metrics - метрики приложения в формате Prometheus. У приложения свой
+// реестр, а не глобальный из client_golang, чтобы в тестах каждое
+// приложение начинало со счётчиков с нуля.
+type metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	renderDuration  *prometheus.HistogramVec
+	logins          *prometheus.CounterVec
+	panics          prometheus.Counter
+}
+
+// newMetrics создаёт и регистрирует метрики. Если передан db, в реестр
+// добавляется статистика пула соединений (открытые, занятые, ожидания).
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snippetbox_http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "snippetbox_http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		renderDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "snippetbox_template_render_duration_seconds",
+			Help:    "Time spent executing page templates.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1},
+		}, []string{"template"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snippetbox_logins_total",
+			Help: "Login attempts by result (success or failure).",
+		}, []string{"result"}),
+		panics: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snippetbox_panics_recovered_total",
+			Help: "Panics in handlers recovered by the recoverPanic middleware.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.renderDuration,
+		m.logins,
+		m.panics,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "snippetbox"))
+	}
+	return m
+}
+
+// handler отдаёт метрики в текстовом формате Prometheus.
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// measure считает запросы и время их обработки. Шаблон маршрута становится
+// известен только после того, как pat выберет обработчик, поэтому measure
+// кладёт в контекст пустую метку, а заполняет её обёртка из route.
+func (m *metrics) measure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := unmatchedRoute
+		ctx := context.WithValue(r.Context(), contextKeyRoute, &route)
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		m.requestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// route помечает запрос шаблоном маршрута, под который он попал.
+func (m *metrics) route(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(contextKeyRoute).(*string); ok {
+			*route = pattern
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeMux - pat.PatternServeMux, который помечает каждый маршрут для
+// метрик. Так в routes() не нужно повторять шаблон дважды.
+type routeMux struct {
+	*pat.PatternServeMux
+	metrics *metrics
+}
+
+func (mux routeMux) Get(pattern string, h http.Handler) {
+	mux.PatternServeMux.Get(pattern, mux.metrics.route(pattern, h))
+}
+
+func (mux routeMux) Post(pattern string, h http.Handler) {
+	mux.PatternServeMux.Post(pattern, mux.metrics.route(pattern, h))
+}
+
+// basicAuth закрывает обработчик паролем. Сравниваем хеши, чтобы время
+// сравнения не зависело ни от длины, ни от содержимого строк.
+func basicAuth(user, password string, next http.Handler) http.Handler {
+	wantUser := sha256.Sum256([]byte(user))
+	wantPassword := sha256.Sum256([]byte(password))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		gotUser := sha256.Sum256([]byte(u))
+		gotPassword := sha256.Sum256([]byte(p))
+		userOK := subtle.ConstantTimeCompare(gotUser[:], wantUser[:]) == 1
+		passwordOK := subtle.ConstantTimeCompare(gotPassword[:], wantPassword[:]) == 1
+		if !ok || !userOK || !passwordOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
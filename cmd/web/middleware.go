@@ -7,20 +7,81 @@ import (
 	"github.com/justinas/nosurf"
 	"golangify.com/snippetbox/pkg/models"
 	"net/http"
+	"strings"
+	"time"
 )
 
-func secureHeaders(next http.Handler) http.Handler {
+// secureHeaders добавляет заголовки безопасности и создаёт nonce для
+// Content-Security-Policy. X-XSS-Protection выключен: встроенный фильтр
+// старых браузеров сам открывал уязвимости, а от XSS теперь защищает CSP.
+// X-Frame-Options оставлен для браузеров без поддержки frame-ancestors.
+func (app *application) secureHeaders(next http.Handler) http.Handler {
+	hsts := app.headers.hsts()
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		w.Header().Set("X-Frame-Options", "deny")
+		h := w.Header()
+		h.Set("X-XSS-Protection", "0")
+		h.Set("X-Frame-Options", "deny")
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		h.Set("Permissions-Policy", "camera=(), microphone=(), geolocation=(), payment=(), usb=()")
+		h.Set("Cross-Origin-Opener-Policy", "same-origin")
+		if hsts != "" {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+
+		if app.headers.CSP != "" {
+			nonce, err := newCSPNonce()
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			name := "Content-Security-Policy"
+			if app.headers.CSPReportOnly {
+				name = "Content-Security-Policy-Report-Only"
+			}
+			h.Set(name, strings.ReplaceAll(app.headers.CSP, "{nonce}", nonce))
+			h.Set("Reporting-Endpoints", `csp="/csp-report"`)
+			r = r.WithContext(context.WithValue(r.Context(), contextKeyCSPNonce, nonce))
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// requestID присваивает запросу идентификатор: берёт его из заголовка
+// X-Request-ID (если запрос пришёл через прокси, который его уже выдал) или
+// генерирует новый. Идентификатор возвращается клиенту в том же заголовке и
+// попадает в каждую запись лога, сделанную при обработке запроса.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDRX.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), contextKeyRequestID, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// logRequest записывает в лог запрос вместе с кодом ответа, его размером
+// и временем обработки.
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
-		next.ServeHTTP(w, r)
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		app.logger.InfoContext(r.Context(), "request",
+			"remote_addr", r.RemoteAddr,
+			"proto", r.Proto,
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+			"status", rec.status,
+			"size", rec.size,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+		)
 	})
 }
 
@@ -34,9 +95,10 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 			if err := recover(); err != nil {
 				// Устанавливаем заголовок "Подключение: закрыть" в ответе.
 				w.Header().Set("Connection", "close")
+				app.metrics.panics.Inc()
 				// Вызываем вспомогательный метод app.serverError, чтобы вернуть 500ь 500
 				// Внутренний ответ сервера.
-				app.serverError(w, fmt.Errorf("%s", err))
+				app.serverError(w, r, fmt.Errorf("%s", err))
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -61,6 +123,15 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	})
 }
 
+// limitUploadSize ограничивает размер тела запроса с вложениями. Он должен
+// стоять до noSurf, который первым разбирает форму в поисках CSRF-токена.
+func (app *application) limitUploadSize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, app.uploads.maxRequestSize())
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Create a NoSurf middleware function which uses a customized CSRF cookie with
 // the Secure, Path and HttpOnly flags set.
 func noSurf(next http.Handler) http.Handler {
@@ -92,14 +163,17 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		} else if err != nil {
-			app.serverError(w, err)
+			app.serverError(w, r, err)
 			return
 		}
 		// Otherwise, we know that the request is coming from a active, authenticated,
 		// user. We create a new copy of the request, with a true boolean value
 		// added to the request context to indicate this, and call the next handler
 		// in the chain *using this new copy of the request*.
+		// Сам пользователь тоже кладётся в контекст: из профиля берутся его
+		// настройки, например язык интерфейса.
 		ctx := context.WithValue(r.Context(), contextKeyIsAuthenticated, true)
+		ctx = context.WithValue(ctx, contextKeyUser, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
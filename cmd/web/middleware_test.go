@@ -1,13 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 )
 
 func TestSecureHeaders(t *testing.T) {
+	app := newTestApplication(t)
 	// Initialize a new httptest.ResponseRecorder and dummy http.Request.
 	rr := httptest.NewRecorder()
 	r, err := http.NewRequest(http.MethodGet, "/", nil)
@@ -16,14 +23,17 @@ func TestSecureHeaders(t *testing.T) {
 	}
 	// Create a mock HTTP handler that we can pass to our secureHeaders
 	// middleware, which writes a 200 status code and "OK" response body.
+	// It also remembers the nonce that templates would receive.
+	var nonce string
 	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = cspNonce(r.Context())
 		w.Write([]byte("OK"))
 	})
 	// Pass the mock HTTP handler to our secureHeaders middleware. Because
 	// secureHeaders *returns* a http.Handler we can call its ServeHTTP()
 	// method, passing in the http.ResponseRecorder and dummy http.Request to
 	// execute it.
-	secureHeaders(next).ServeHTTP(rr, r)
+	app.secureHeaders(next).ServeHTTP(rr, r)
 	// Call the Result() method on the http.ResponseRecorder to get the results
 	// of the test.
 	rs := rr.Result()
@@ -33,11 +43,29 @@ func TestSecureHeaders(t *testing.T) {
 	if frameOptions != "deny" {
 		t.Errorf("want %q; got %q", "deny", frameOptions)
 	}
-	// Check that the middleware has correctly set the X-XSS-Protection header
-	// on the response.
+	// The legacy XSS filter is explicitly disabled; CSP replaces it.
 	xssProtection := rs.Header.Get("X-XSS-Protection")
-	if xssProtection != "1; mode=block" {
-		t.Errorf("want %q; got %q", "1; mode=block", xssProtection)
+	if xssProtection != "0" {
+		t.Errorf("want %q; got %q", "0", xssProtection)
+	}
+	for name, want := range map[string]string{
+		"Strict-Transport-Security":  "max-age=3600",
+		"Referrer-Policy":            "strict-origin-when-cross-origin",
+		"Cross-Origin-Opener-Policy": "same-origin",
+		"X-Content-Type-Options":     "nosniff",
+	} {
+		if got := rs.Header.Get(name); got != want {
+			t.Errorf("want %s %q; got %q", name, want, got)
+		}
+	}
+	if rs.Header.Get("Permissions-Policy") == "" {
+		t.Error("want Permissions-Policy header")
+	}
+	// The policy carries the same per-request nonce that is handed to the
+	// templates.
+	csp := rs.Header.Get("Content-Security-Policy")
+	if nonce == "" || !strings.Contains(csp, "'nonce-"+nonce+"'") {
+		t.Errorf("want CSP with nonce %q; got %q", nonce, csp)
 	}
 	// Check that the middleware has correctly called the next handler in line
 	// and the response status code and body are as expected.
@@ -53,3 +81,127 @@ func TestSecureHeaders(t *testing.T) {
 		t.Errorf("want body to equal %q", "OK")
 	}
 }
+
+func TestRequestLogging(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.logger = newLogger(&buf, slog.LevelInfo)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.serverError(w, r, errors.New("boom"))
+	})
+	handler := app.requestID(app.logRequest(next))
+
+	tests := []struct {
+		name   string
+		header string
+		wantID string
+	}{
+		{"Valid ID is propagated", "abc-123", "abc-123"},
+		{"Missing ID is generated", "", ""},
+		{"Unsafe ID is replaced", "bad\nid", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/snippet/1", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			handler.ServeHTTP(rr, r)
+
+			id := rr.Header().Get("X-Request-ID")
+			if tt.wantID != "" && id != tt.wantID {
+				t.Errorf("want request ID %q; got %q", tt.wantID, id)
+			}
+			if !requestIDRX.MatchString(id) {
+				t.Errorf("want a safe request ID; got %q", id)
+			}
+
+			// The error trace and the access log line must both carry the ID.
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("want 2 log lines; got %d: %q", len(lines), buf.String())
+			}
+			for _, line := range lines {
+				var entry map[string]any
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				if entry["request_id"] != id {
+					t.Errorf("want request_id %q in %s", id, line)
+				}
+			}
+			var access map[string]any
+			json.Unmarshal([]byte(lines[1]), &access)
+			if access["status"] != float64(http.StatusInternalServerError) {
+				t.Errorf("want status 500 in access log; got %v", access["status"])
+			}
+			if _, ok := access["latency_ms"]; !ok {
+				t.Error("want latency_ms in access log")
+			}
+		})
+	}
+}
+
+func TestCSPNonceIsUnique(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, header1, body1 := ts.get(t, "/")
+	_, header2, _ := ts.get(t, "/")
+	csp1, csp2 := header1.Get("Content-Security-Policy"), header2.Get("Content-Security-Policy")
+	if csp1 == csp2 {
+		t.Errorf("want a fresh nonce per response; got %q twice", csp1)
+	}
+	nonce := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(csp1)
+	if nonce == nil {
+		t.Fatalf("want a nonce in %q", csp1)
+	}
+	if !bytes.Contains(body1, []byte(`nonce='`+nonce[1]+`'`)) {
+		t.Errorf("want the page script to carry nonce %q", nonce[1])
+	}
+}
+
+func TestReportCSP(t *testing.T) {
+	app := newTestApplication(t)
+	var buf bytes.Buffer
+	app.logger = newLogger(&buf, slog.LevelInfo)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantLog     string
+	}{
+		{"report-uri", "application/csp-report",
+			`{"csp-report":{"document-uri":"https://qogam.kz/","blocked-uri":"inline","violated-directive":"script-src-elem"}}`,
+			http.StatusNoContent, `"directive":"script-src-elem"`},
+		{"Reporting API", "application/reports+json",
+			`[{"type":"csp-violation","body":{"documentURL":"https://qogam.kz/","blockedURL":"https://evil.example/x.js","effectiveDirective":"script-src-elem"}}]`,
+			http.StatusNoContent, `"blocked_uri":"https://evil.example/x.js"`},
+		{"Malformed", "application/csp-report", `{`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			// No session cookie or CSRF token: browsers send reports without them.
+			rs, err := ts.Client().Post(ts.URL+"/csp-report", tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.Body.Close()
+			if rs.StatusCode != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, rs.StatusCode)
+			}
+			if !strings.Contains(buf.String(), tt.wantLog) {
+				t.Errorf("want log to contain %q; got %q", tt.wantLog, buf.String())
+			}
+		})
+	}
+}
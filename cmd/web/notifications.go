@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/pkg/mail"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// Сколько последних уведомлений показывается на странице уведомлений.
+const notificationListSize = 50
+
+// notify доставляет уведомление n так, как выбрал получатель: в
+// приложении и/или письмом. Действие, о котором сообщается, к этому
+// моменту уже выполнено, поэтому ошибки доставки только записываются в
+// лог. Уведомлять о собственных действиях незачем.
+func (app *application) notify(r *http.Request, n *models.Notification) {
+	if n.UserID == 0 || n.UserID == n.ActorID {
+		return
+	}
+	prefs, err := app.notifications.Preferences(n.UserID)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "notification preferences", "error", err, "user_id", n.UserID)
+		return
+	}
+	pref := prefs[n.Type]
+	if pref.InApp {
+		if _, err = app.notifications.Insert(n); err != nil {
+			app.logger.ErrorContext(r.Context(), "insert notification", "error", err, "user_id", n.UserID, "type", n.Type)
+		} else {
+			app.publishUnread(r, n.UserID)
+		}
+	}
+	if pref.Email && app.mailer != nil {
+		app.mailNotification(r, n)
+	}
+}
+
+// mailNotification ставит в очередь письмо с уведомлением на языке
+// получателя (а если он его не выбирал - на языке сайта).
+func (app *application) mailNotification(r *http.Request, n *models.Notification) {
+	user, err := app.users.Get(n.UserID)
+	if err != nil {
+		app.logger.ErrorContext(r.Context(), "notification recipient", "error", err, "user_id", n.UserID)
+		return
+	}
+	locale, ok := i18n.Parse(user.Locale)
+	if !ok {
+		locale = app.locale
+	}
+	p := i18n.NewPrinter(locale)
+	text := notificationText(p, n)
+	app.mailer.send(mail.Message{
+		To:      user.Email,
+		Subject: "Qogam: " + text,
+		Body: text + "\n\n" + app.baseURL + notificationPath(n) + "\n\n" +
+			p.Sprintf("You can choose which notifications to receive by email at %s", app.baseURL+"/user/notifications"),
+	})
+}
+
+// notificationText описывает уведомление одной фразой на языке p.
+func notificationText(p *i18n.Printer, n *models.Notification) string {
+	actor := n.Actor
+	if actor == "" {
+		actor = p.Sprintf("Someone")
+	}
+	title := n.SnippetTitle
+	if title == "" {
+		title = p.Sprintf("(deleted)")
+	}
+	switch n.Type {
+	case models.NotificationReply:
+		return p.Sprintf("%s commented on your snippet: %s", actor, title)
+	case models.NotificationMention:
+		return p.Sprintf("%s mentioned you: %s", actor, title)
+	default:
+		return p.Sprintf("%s upvoted your snippet: %s", actor, title)
+	}
+}
+
+// notificationPath возвращает адрес, который открывает уведомление:
+// заметку или комментарий к ней.
+func notificationPath(n *models.Notification) string {
+	switch {
+	case n.SnippetID == 0:
+		return "/user/notifications"
+	case n.CommentID != 0:
+		return fmt.Sprintf("/snippet/%d#comment-%d", n.SnippetID, n.CommentID)
+	default:
+		return fmt.Sprintf("/snippet/%d", n.SnippetID)
+	}
+}
+
+// showNotifications показывает последние уведомления и настройки их
+// доставки.
+func (app *application) showNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	notifications, err := app.notifications.Latest(userID, notificationListSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	prefs, err := app.notifications.Preferences(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Настройки показываются в постоянном порядке NotificationTypes.
+	ordered := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		ordered = append(ordered, prefs[t])
+	}
+	app.render(w, r, "notifications.page.tmpl", &templateData{
+		Notifications:           notifications,
+		NotificationPreferences: ordered,
+		EmailNotifications:      app.mailer != nil,
+	})
+}
+
+// readNotification отмечает прочитанным одно уведомление.
+func (app *application) readNotification(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	// Чужие уведомления MarkRead не меняет, так что проверять владельца
+	// отдельно не нужно.
+	userID := app.authenticatedUserID(r)
+	err = app.notifications.MarkRead(userID, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Счётчик обновится и в других открытых вкладках.
+	app.publishUnread(r, userID)
+	http.Redirect(w, r, "/user/notifications", http.StatusSeeOther)
+}
+
+// readAllNotifications отмечает прочитанными все уведомления.
+func (app *application) readAllNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := app.authenticatedUserID(r)
+	err := app.notifications.MarkAllRead(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.publishUnread(r, userID)
+	http.Redirect(w, r, "/user/notifications", http.StatusSeeOther)
+}
+
+// updateNotificationPreferences сохраняет настройки доставки. Флажки
+// называются inapp_<тип> и email_<тип>; неотмеченный флажок браузер не
+// присылает, поэтому его отсутствие означает "выключено".
+func (app *application) updateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	prefs := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		prefs = append(prefs, models.NotificationPreference{
+			Type:  t,
+			InApp: r.PostForm.Get("inapp_"+t) != "",
+			Email: r.PostForm.Get("email_"+t) != "",
+		})
+	}
+	err = app.notifications.SetPreferences(app.authenticatedUserID(r), prefs)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "Notification settings saved!")
+	http.Redirect(w, r, "/user/notifications", http.StatusSeeOther)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/mail"
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/models/mock"
+)
+
+// fakeSender records messages instead of sending them.
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []mail.Message
+}
+
+func (s *fakeSender) Send(m mail.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func TestShowNotifications(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, _ := ts.get(t, "/user/notifications")
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/login" {
+		t.Fatalf("want redirect to /user/login; got %d %q", code, header.Get("Location"))
+	}
+
+	ts.login(t)
+	code, _, body := ts.get(t, "/user/notifications")
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	for _, want := range []string{
+		"Bob прокомментировал(а) вашу заметку: An old silent pond",
+		"href='/snippet/1#comment-1'",
+		"action='/user/notifications/1/read'",
+		"<span class='badge' data-count='unread'>1</span>",
+		"name='inapp_reply' value='true' checked",
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+	// Without an SMTP server there is no email column.
+	if bytes.Contains(body, []byte("name='email_reply'")) {
+		t.Error("want no email preferences when email is disabled")
+	}
+}
+
+func TestNotificationActions(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		form     url.Values
+		wantCode int
+	}{
+		{"Mark one read", "/user/notifications/1/read", url.Values{}, http.StatusSeeOther},
+		{"Mark invalid id read", "/user/notifications/x/read", url.Values{}, http.StatusNotFound},
+		{"Mark all read", "/user/notifications/read", url.Values{}, http.StatusSeeOther},
+		{"Save preferences", "/user/notifications/preferences", url.Values{"inapp_reply": {"true"}}, http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.form.Set("csrf_token", csrfToken)
+			code, _, _ := ts.postForm(t, tt.urlPath, tt.form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+		})
+	}
+
+	_, _, body := ts.get(t, "/user/notifications")
+	if !bytes.Contains(body, []byte("Настройки уведомлений сохранены!")) {
+		t.Error("want the flash message after saving preferences")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	app := newTestApplication(t)
+	notifications := &mock.NotificationModel{}
+	app.notifications = notifications
+	sender := &fakeSender{}
+	app.mailer = newMailer(sender, newLogger(io.Discard, slog.LevelInfo))
+	app.baseURL = "https://qogam.example"
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	post := func(urlPath string, form url.Values) {
+		t.Helper()
+		form.Set("csrf_token", csrfToken)
+		if code, _, _ := ts.postForm(t, urlPath, form); code != http.StatusSeeOther {
+			t.Fatalf("%s: want %d; got %d", urlPath, http.StatusSeeOther, code)
+		}
+	}
+	// Alice's own snippet: no notification.
+	post("/snippet/1/comment", url.Values{"content": {"Note to self"}})
+	// Bob's snippet: a reply and an upvote; a downvote is not announced.
+	post("/snippet/3/comment", url.Values{"content": {"Nice one"}})
+	post("/snippet/3/vote", url.Values{"value": {"1"}})
+	post("/snippet/3/vote", url.Values{"value": {"-1"}})
+	// Voting again announces nothing new.
+	post("/snippet/3/vote", url.Values{"value": {"0"}})
+	post("/snippet/3/vote", url.Values{"value": {"1"}})
+
+	if len(notifications.Inserted) != 2 {
+		t.Fatalf("want 2 notifications; got %d", len(notifications.Inserted))
+	}
+	for i, wantType := range []string{models.NotificationReply, models.NotificationVote} {
+		n := notifications.Inserted[i]
+		if n.UserID != 2 || n.ActorID != 1 || n.Type != wantType || n.ActorUsername != "alice" {
+			t.Errorf("unexpected notification %+v", n)
+		}
+	}
+
+	// Bob receives replies by email, in English, but not upvotes.
+	app.mailer.stop()
+	if len(sender.sent) != 1 {
+		t.Fatalf("want 1 email; got %d", len(sender.sent))
+	}
+	m := sender.sent[0]
+	if m.To != "bob@example.com" {
+		t.Errorf("want email to bob@example.com; got %q", m.To)
+	}
+	if !strings.Contains(m.Subject, "Alice commented on your snippet") {
+		t.Errorf("unexpected subject %q", m.Subject)
+	}
+	if !strings.Contains(m.Body, "https://qogam.example/snippet/3#comment-2") {
+		t.Errorf("want a link to the comment in the body; got %q", m.Body)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// Ограничения опроса: число вариантов и длина каждого из них (столько
+// вмещает poll_options.text).
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 10
+	pollOptionMaxLength = 200
+)
+
+// Формат поля "closes" - так его присылает <input type='datetime-local'>.
+const pollClosesLayout = "2006-01-02T15:04"
+
+// pendingPoll - проверенный опрос из формы создания заметки, который ещё
+// не сохранён: у заметки пока нет идентификатора.
+type pendingPoll struct {
+	Multiple bool
+	Closes   time.Time
+	Options  []string
+}
+
+// readPoll проверяет поля опроса на форме создания заметки. Флажок "poll"
+// делает заметку опросом, варианты приходят в повторяющемся поле "option",
+// флажок "multiple" разрешает выбрать несколько вариантов, а "closes" -
+// необязательное время закрытия в часовом поясе пользователя. Если
+// заметка - не опрос или поля не прошли проверку, возвращается nil.
+func (app *application) readPoll(r *http.Request, form *forms.Form) *pendingPoll {
+	if form.Get("poll") != "true" {
+		return nil
+	}
+	form.ListLength("option", minPollOptions, maxPollOptions, pollOptionMaxLength)
+	form.DateTime("closes", pollClosesLayout)
+	if !form.Valid() {
+		return nil
+	}
+	p := &pendingPoll{Multiple: form.Get("multiple") == "true", Options: form.List("option")}
+	if value := strings.TrimSpace(form.Get("closes")); value != "" {
+		p.Closes, _ = time.ParseInLocation(pollClosesLayout, value, app.zoneOf(r))
+		if !p.Closes.After(time.Now()) {
+			form.AddError("closes", "The poll must close in the future")
+			return nil
+		}
+	}
+	return p
+}
+
+// pollOptionFields возвращает значения полей вариантов для формы создания
+// заметки: введённые ранее и пустые, всего maxPollOptions.
+func pollOptionFields(form *forms.Form) []string {
+	fields := make([]string, maxPollOptions)
+	if form != nil {
+		copy(fields, form.List("option"))
+	}
+	return fields
+}
+
+// votePoll принимает бюллетень. Варианты приходят в повторяющемся поле
+// "option"; в опросе с одним ответом вариант должен быть ровно один.
+// Повторно проголосовать нельзя - это проверяет база данных.
+func (app *application) votePoll(w http.ResponseWriter, r *http.Request) {
+	s := app.snippetFromPath(w, r)
+	if s == nil {
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	userID := app.authenticatedUserID(r)
+	poll, err := app.polls.Get(s.ID, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	choices, ok := pollChoices(poll, form.List("option"))
+	switch {
+	case poll.Closed(time.Now()):
+		form.AddError("option", "This poll is closed")
+	case poll.Voted():
+		form.AddError("option", "You have already voted")
+	case !ok:
+		form.AddError("option", "This field is invalid")
+	case len(choices) == 0:
+		form.AddError("option", "Choose an option")
+	case len(choices) > 1 && !poll.Multiple:
+		form.AddError("option", "Choose only one option")
+	}
+	if form.Valid() {
+		err = app.polls.Vote(s.ID, userID, choices)
+		if errors.Is(err, models.ErrAlreadyVoted) {
+			form.AddError("option", "You have already voted")
+		} else if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if !form.Valid() {
+		td, err := app.snippetPage(r, s)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Form = form
+		app.render(w, r, "show.page.tmpl", td)
+		return
+	}
+	app.session.Put(r, "flash", "Your vote has been counted!")
+	http.Redirect(w, r, fmt.Sprintf("/snippet/%d#poll", s.ID), http.StatusSeeOther)
+}
+
+// pollChoices переводит значения поля "option" в идентификаторы вариантов
+// опроса p без повторов. ok равен false, если среди значений есть чужой
+// или некорректный вариант.
+func pollChoices(p *models.Poll, values []string) (choices []int, ok bool) {
+	valid := map[int]bool{}
+	for _, o := range p.Options {
+		valid[o.ID] = true
+	}
+	seen := map[int]bool{}
+	for _, value := range values {
+		id, err := strconv.Atoi(value)
+		if err != nil || !valid[id] {
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			choices = append(choices, id)
+		}
+	}
+	return choices, true
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/models/mock"
+)
+
+func TestCreatePoll(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []string
+		multiple     string
+		closes       string
+		wantCode     int
+		wantOptions  []string
+		wantMultiple bool
+		wantError    string
+	}{
+		{"Poll", []string{" Monday ", "", "Friday"}, "", "", http.StatusSeeOther, []string{"Monday", "Friday"}, false, ""},
+		{"Multiple choice", []string{"A", "B", "C"}, "true", "2999-01-01T10:00", http.StatusSeeOther, []string{"A", "B", "C"}, true, ""},
+		{"One option", []string{"Monday", ""}, "", "", http.StatusOK, nil, false, "Заполните от 2 до 10 полей"},
+		{"Too many options", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}, "", "", http.StatusOK, nil, false, "Заполните от 2 до 10 полей"},
+		{"Repeated option", []string{"Monday", "monday"}, "", "", http.StatusOK, nil, false, "Значения не должны повторяться"},
+		{"Bad date", []string{"A", "B"}, "", "tomorrow", http.StatusOK, nil, false, "Укажите корректные дату и время"},
+		{"Closes in the past", []string{"A", "B"}, "", "2000-01-01T10:00", http.StatusOK, nil, false, "Время закрытия опроса должно быть в будущем"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			polls := &mock.PollModel{}
+			app.polls = polls
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			csrfToken := ts.login(t)
+
+			form := url.Values{
+				"csrf_token": {csrfToken},
+				"title":      {"Exam date"},
+				"content":    {"When should we take the exam?"},
+				"expires":    {"7"},
+				"poll":       {"true"},
+				"option":     tt.options,
+				"multiple":   {tt.multiple},
+				"closes":     {tt.closes},
+			}
+			code, _, body := ts.postForm(t, "/snippet/create", form)
+			if code != tt.wantCode {
+				t.Fatalf("want %d; got %d", tt.wantCode, code)
+			}
+			if tt.wantError != "" {
+				if !bytes.Contains(body, []byte(tt.wantError)) {
+					t.Errorf("want body to contain %q", tt.wantError)
+				}
+				if len(polls.Inserted) != 0 {
+					t.Errorf("want no poll; got %d", len(polls.Inserted))
+				}
+				return
+			}
+			if len(polls.Inserted) != 1 {
+				t.Fatalf("want 1 poll; got %d", len(polls.Inserted))
+			}
+			p := polls.Inserted[0]
+			var options []string
+			for _, o := range p.Options {
+				options = append(options, o.Text)
+			}
+			if p.SnippetID != 2 || p.Multiple != tt.wantMultiple || !reflect.DeepEqual(options, tt.wantOptions) {
+				t.Errorf("unexpected poll %+v with options %q", p, options)
+			}
+			if (tt.closes == "") != p.Closes.IsZero() {
+				t.Errorf("want closes %q; got %v", tt.closes, p.Closes)
+			}
+		})
+	}
+}
+
+func TestCreateSnippetWithoutPoll(t *testing.T) {
+	app := newTestApplication(t)
+	polls := &mock.PollModel{}
+	app.polls = polls
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	// Options are ignored unless the poll box is checked.
+	form := url.Values{
+		"csrf_token": {csrfToken},
+		"title":      {"Haiku"},
+		"content":    {"An old silent pond"},
+		"expires":    {"7"},
+		"option":     {"A"},
+	}
+	if code, _, _ := ts.postForm(t, "/snippet/create", form); code != http.StatusSeeOther {
+		t.Fatalf("want %d; got %d", http.StatusSeeOther, code)
+	}
+	if len(polls.Inserted) != 0 {
+		t.Errorf("want no poll; got %d", len(polls.Inserted))
+	}
+}
+
+func TestCreatePollFailure(t *testing.T) {
+	app := newTestApplication(t)
+	snippets := &mock.SnippetModel{}
+	app.snippets = snippets
+	app.polls = &mock.PollModel{InsertErr: errors.New("boom")}
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	form := url.Values{
+		"csrf_token": {csrfToken},
+		"title":      {"Exam date"},
+		"content":    {"When should we take the exam?"},
+		"expires":    {"7"},
+		"poll":       {"true"},
+		"option":     {"Monday", "Friday"},
+	}
+	if code, _, _ := ts.postForm(t, "/snippet/create", form); code != http.StatusInternalServerError {
+		t.Fatalf("want %d; got %d", http.StatusInternalServerError, code)
+	}
+	// A snippet without its poll must not stay published.
+	if len(snippets.Deleted) != 1 || snippets.Deleted[0] != 2 {
+		t.Errorf("want snippet 2 deleted; got %v", snippets.Deleted)
+	}
+}
+
+func TestShowPoll(t *testing.T) {
+	app := newTestApplication(t)
+	polls := &mock.PollModel{}
+	app.polls = polls
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// Guests see the options but not the results.
+	_, _, body := ts.get(t, "/snippet/3")
+	if !bytes.Contains(body, []byte("<li>Monday</li>")) || !bytes.Contains(body, []byte("Войдите, чтобы проголосовать.")) {
+		t.Error("want poll options and a login link for guests")
+	}
+	if bytes.Contains(body, []byte("<meter")) {
+		t.Error("want no results for guests before the poll closes")
+	}
+
+	ts.login(t)
+	_, _, body = ts.get(t, "/snippet/3")
+	if !bytes.Contains(body, []byte("action='/snippet/3/poll'")) || !bytes.Contains(body, []byte("type='radio' name='option' value='1'")) {
+		t.Error("want a voting form before voting")
+	}
+
+	polls.Closed = true
+	_, _, body = ts.get(t, "/snippet/3")
+	if !bytes.Contains(body, []byte("<meter min='0' max='100' value='100'>")) || !bytes.Contains(body, []byte("Опрос закрыт")) {
+		t.Error("want results once the poll is closed")
+	}
+
+	// Snippets without a poll show none.
+	if _, _, body = ts.get(t, "/snippet/1"); bytes.Contains(body, []byte("class='poll'")) {
+		t.Error("want no poll on a plain snippet")
+	}
+}
+
+func TestVotePoll(t *testing.T) {
+	tests := []struct {
+		name      string
+		urlPath   string
+		options   []string
+		closed    bool
+		wantCode  int
+		wantError string
+	}{
+		{"Vote", "/snippet/3/poll", []string{"2"}, false, http.StatusSeeOther, ""},
+		{"No option", "/snippet/3/poll", nil, false, http.StatusOK, "Выберите вариант"},
+		{"Two options", "/snippet/3/poll", []string{"1", "2"}, false, http.StatusOK, "Выберите только один вариант"},
+		{"Foreign option", "/snippet/3/poll", []string{"7"}, false, http.StatusOK, "Недопустимое значение"},
+		{"Closed", "/snippet/3/poll", []string{"1"}, true, http.StatusOK, "<p class='error'>Опрос закрыт</p>"},
+		{"Not a poll", "/snippet/1/poll", []string{"1"}, false, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			polls := &mock.PollModel{Closed: tt.closed}
+			app.polls = polls
+			ts := newTestServer(t, app.routes())
+			defer ts.Close()
+			csrfToken := ts.login(t)
+
+			form := url.Values{"csrf_token": {csrfToken}, "option": tt.options}
+			code, header, body := ts.postForm(t, tt.urlPath, form)
+			if code != tt.wantCode {
+				t.Fatalf("want %d; got %d", tt.wantCode, code)
+			}
+			if tt.wantError != "" {
+				if !bytes.Contains(body, []byte(tt.wantError)) {
+					t.Errorf("want body to contain %q", tt.wantError)
+				}
+				if len(polls.Ballots) != 0 {
+					t.Errorf("want no ballot; got %v", polls.Ballots)
+				}
+				return
+			}
+			if code != http.StatusSeeOther {
+				return
+			}
+			if loc := header.Get("Location"); loc != "/snippet/3#poll" {
+				t.Errorf("want Location /snippet/3#poll; got %q", loc)
+			}
+			if !reflect.DeepEqual(polls.Ballots[1], []int{2}) {
+				t.Errorf("want a ballot for option 2; got %v", polls.Ballots)
+			}
+
+			// After voting the results replace the form, and a second
+			// ballot is refused.
+			_, _, body = ts.get(t, "/snippet/3")
+			if !bytes.Contains(body, []byte("<li class='chosen'>")) || bytes.Contains(body, []byte("action='/snippet/3/poll'")) {
+				t.Error("want results with the chosen option after voting")
+			}
+			code, _, body = ts.postForm(t, tt.urlPath, form)
+			if code != http.StatusOK || !bytes.Contains(body, []byte("Вы уже проголосовали")) {
+				t.Errorf("want an already voted error; got %d", code)
+			}
+		})
+	}
+}
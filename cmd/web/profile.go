@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/media"
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/storage"
+	"golangify.com/snippetbox/pkg/username"
+)
+
+// Сколько последних заметок и комментариев показывать в профиле.
+const profileListSize = 20
+
+// Сторона аватара в пикселях и предельная длина рассказа о себе.
+const (
+	avatarSize   = 128
+	bioMaxLength = 500
+)
+
+// profileFromPath возвращает профиль по имени пользователя :name из
+// адреса. Имя нормализуется, поэтому /u/Alice и /u/@alice тоже находят
+// alice. Если профиля нет, ответ уже отправлен и возвращается nil.
+func (app *application) profileFromPath(w http.ResponseWriter, r *http.Request) *models.Profile {
+	p, err := app.profiles.ByUsername(username.Normalize(r.URL.Query().Get(":name")))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil
+	}
+	return p
+}
+
+// showProfile показывает публичную страницу пользователя: последние
+// заметки и комментарии, карму и возраст аккаунта. Адрес электронной
+// почты в models.Profile не попадает, поэтому показать его нельзя.
+func (app *application) showProfile(w http.ResponseWriter, r *http.Request) {
+	p := app.profileFromPath(w, r)
+	if p == nil {
+		return
+	}
+	// У профиля один адрес: остальные варианты написания имени
+	// перенаправляются на него.
+	if canonical := profileURL(p.Username); r.URL.Path != canonical {
+		http.Redirect(w, r, canonical, http.StatusMovedPermanently)
+		return
+	}
+	snippets, err := app.snippets.ByUser(p.ID, profileListSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	comments, err := app.comments.ByUser(p.ID, profileListSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	td := &templateData{
+		Comments: comments,
+		Profile:  p,
+		Snippets: snippets,
+	}
+	// Кнопка блокировки показывает, заблокирован ли уже этот пользователь.
+	if userID := app.authenticatedUserID(r); userID != 0 && userID != p.ID {
+		blockedBy, err := app.dms.BlockedBy(p.ID, []int{userID})
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Blocked = len(blockedBy) > 0
+	}
+	app.render(w, r, "profile.page.tmpl", td)
+}
+
+// showAvatar отдаёт аватар пользователя. Как и вложения, он проверен и
+// перекодирован при загрузке, а заголовки не дают браузеру исполнить его.
+func (app *application) showAvatar(w http.ResponseWriter, r *http.Request) {
+	p := app.profileFromPath(w, r)
+	if p == nil {
+		return
+	}
+	if p.AvatarKey == "" {
+		app.notFound(w)
+		return
+	}
+	f, err := app.storage.Open(p.AvatarKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(p.AvatarKey)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	io.Copy(w, f)
+}
+
+// readAvatar читает изображение из поля "avatar" multipart-формы, удаляет
+// из него метаданные и уменьшает до avatarSize. Если файла нет или он не
+// прошёл проверку, возвращается nil; ошибки проверки добавляются в форму.
+func (app *application) readAvatar(r *http.Request, form *forms.Form) (*pendingUpload, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["avatar"]) == 0 {
+		return nil, nil
+	}
+	fh := r.MultipartForm.File["avatar"][0]
+	if fh.Size > app.uploads.MaxFileSize {
+		form.AddError("avatar", "%s is too large (maximum is %d MB)", fh.Filename, app.uploads.MaxFileSize>>20)
+		return nil, nil
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, app.uploads.MaxFileSize+1))
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, err := media.Sniff(data)
+	if err != nil || !media.IsImage(contentType) {
+		form.AddError("avatar", "%s: only JPEG and PNG images are allowed", fh.Filename)
+		return nil, nil
+	}
+	_, img, err := media.StripMetadata(data, contentType)
+	if errors.Is(err, media.ErrTooManyPixels) {
+		form.AddError("avatar", "%s: the image is too large (maximum is %d megapixels)", fh.Filename, media.MaxPixels/1_000_000)
+		return nil, nil
+	}
+	if err != nil {
+		form.AddError("avatar", "%s: the image is damaged", fh.Filename)
+		return nil, nil
+	}
+	// Миниатюра кодируется заново и поэтому не содержит метаданных.
+	avatar, err := media.Thumbnail(img, avatarSize, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingUpload{filename: fh.Filename, contentType: contentType, data: avatar}, nil
+}
+
+// saveAvatar сохраняет аватар в хранилище и возвращает его ключ.
+func (app *application) saveAvatar(u *pendingUpload) (string, error) {
+	key, err := newStorageKey("-avatar" + media.Extension(u.contentType))
+	if err != nil {
+		return "", err
+	}
+	if err = app.storage.Save(key, bytes.NewReader(u.data)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestShowProfile(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+		wantBody     []string
+	}{
+		{"Existing user", "/u/alice", http.StatusOK, "", []string{
+			"Alice", "@alice", "Карма: 3", "Haiku enthusiast.", "An old silent pond", "A frog jumps into the pond",
+		}},
+		{"Other case", "/u/Alice", http.StatusMovedPermanently, "/u/alice", nil},
+		{"Mention form", "/u/@alice", http.StatusMovedPermanently, "/u/alice", nil},
+		{"Unknown user", "/u/nobody", http.StatusNotFound, "", nil},
+		{"No avatar", "/u/alice/avatar", http.StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want Location %q; got %q", tt.wantLocation, loc)
+			}
+			for _, want := range tt.wantBody {
+				if !bytes.Contains(body, []byte(want)) {
+					t.Errorf("want body to contain %q", want)
+				}
+			}
+			// The profile is public: the email address must never leak.
+			if bytes.Contains(body, []byte("alice@example.com")) {
+				t.Error("profile page exposes the email address")
+			}
+		})
+	}
+}
+
+func TestCreateComment(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, _, body := ts.get(t, "/snippet/1")
+	if code != http.StatusOK {
+		t.Fatalf("want %d; got %d", http.StatusOK, code)
+	}
+	if !bytes.Contains(body, []byte("A frog jumps into the pond")) || !bytes.Contains(body, []byte("href='/u/alice'")) {
+		t.Error("want the comment with a link to its author's profile")
+	}
+
+	csrfToken := ts.login(t)
+	tests := []struct {
+		name         string
+		content      string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{"Valid", "Lovely haiku", http.StatusSeeOther, "/snippet/1#comment-2", ""},
+		{"Empty", "   ", http.StatusOK, "", "Это поле не может быть пустым"},
+		{"Too long", strings.Repeat("a", commentMaxLength+1), http.StatusOK, "", "максимум символов: 2000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("content", tt.content)
+			form.Add("csrf_token", csrfToken)
+			code, header, body := ts.postForm(t, "/snippet/1/comment", form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want Location %q; got %q", tt.wantLocation, loc)
+			}
+			if !bytes.Contains(body, []byte(tt.wantBody)) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestVoteSnippet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		value    string
+		wantCode int
+	}{
+		{"Upvote", "/snippet/3/vote", "1", http.StatusSeeOther},
+		{"Downvote", "/snippet/3/vote", "-1", http.StatusSeeOther},
+		{"Remove vote", "/snippet/3/vote", "0", http.StatusSeeOther},
+		{"Out of range", "/snippet/3/vote", "5", http.StatusBadRequest},
+		{"Not a number", "/snippet/3/vote", "up", http.StatusBadRequest},
+		{"Own snippet", "/snippet/1/vote", "1", http.StatusForbidden},
+		{"Non-existent snippet", "/snippet/2/vote", "1", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("value", tt.value)
+			form.Add("csrf_token", csrfToken)
+			code, _, _ := ts.postForm(t, tt.urlPath, form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+		})
+	}
+}
+
+func TestUpdateSettingsAvatar(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 400, 300))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		wantCode int
+		wantBody string
+	}{
+		{"PNG image", "me.png", pngData.Bytes(), http.StatusSeeOther, ""},
+		{"Not an image", "me.png", []byte("<script>alert(1)</script>"), http.StatusOK, "разрешены только изображения JPEG и PNG"},
+		{"PDF", "me.pdf", []byte("%PDF-1.4\n"), http.StatusOK, "разрешены только изображения JPEG и PNG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			mw.WriteField("csrf_token", csrfToken)
+			mw.WriteField("username", "alice")
+			mw.WriteField("bio", "Haiku enthusiast.")
+			fw, err := mw.CreateFormFile("avatar", tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write(tt.data)
+			mw.Close()
+
+			rs, err := ts.Client().Post(ts.URL+"/user/settings", mw.FormDataContentType(), &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rs.Body.Close()
+			respBody, err := io.ReadAll(rs.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rs.StatusCode != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, rs.StatusCode)
+			}
+			if !bytes.Contains(respBody, []byte(tt.wantBody)) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSignupUsername(t *testing.T) {
+	app := newTestApplication(t)
+	// More attempts than the signup rate limit allows.
+	app.limiter = nil
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	_, _, body := ts.get(t, "/user/signup")
+	csrfToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name     string
+		username string
+		wantCode int
+		wantBody string
+	}{
+		{"Valid", "bob_1990", http.StatusSeeOther, ""},
+		{"Normalized", "@Bob", http.StatusSeeOther, ""},
+		{"Empty", "", http.StatusOK, "Это поле не может быть пустым"},
+		{"Too short", "bo", http.StatusOK, "минимум символов: 3"},
+		{"Invalid characters", "bob smith", http.StatusOK, "Используйте латинские буквы"},
+		{"Reserved", "Admin", http.StatusOK, "зарезервировано"},
+		{"Taken", "alice", http.StatusOK, "уже занято"},
+		{"Taken with a lookalike", "АLICE", http.StatusOK, "уже занято"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("name", "Bob")
+			form.Add("username", tt.username)
+			form.Add("email", "bob@example.com")
+			form.Add("password", "validPa$$word")
+			form.Add("csrf_token", csrfToken)
+			code, _, body := ts.postForm(t, "/user/signup", form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if !bytes.Contains(body, []byte(tt.wantBody)) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestChangeUsername(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	tests := []struct {
+		name     string
+		username string
+		wantCode int
+		wantBody string
+	}{
+		{"Unchanged", "alice", http.StatusSeeOther, ""},
+		{"New name", "alice_w", http.StatusSeeOther, ""},
+		{"Taken", "bob", http.StatusOK, "уже занято"},
+		{"Migration placeholder", "user7", http.StatusOK, "зарезервировано"},
+		{"Empty", "", http.StatusOK, "Это поле не может быть пустым"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("username", tt.username)
+			form.Add("csrf_token", csrfToken)
+			code, _, body := ts.postForm(t, "/user/settings", form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if !bytes.Contains(body, []byte(tt.wantBody)) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangify.com/snippetbox/pkg/ratelimit"
+)
+
+// Правила ограничения частоты запросов. readPolicy действует на все
+// запросы с одного адреса и рассчитан на обычный просмотр сайта; более
+// строгие правила добавляются к отдельным маршрутам в routes().
+var (
+	readPolicy  = ratelimit.Policy{Rate: 300, Per: time.Minute, Burst: 100}
+	authPolicy  = ratelimit.Policy{Rate: 5, Per: time.Minute, Burst: 5}
+	writePolicy = ratelimit.Policy{Rate: 30, Per: time.Hour, Burst: 10}
+	// Начинать новые переписки можно реже, чем отвечать в начатых: спам
+	// рассылают именно новыми переписками.
+	conversationPolicy = ratelimit.Policy{Rate: 10, Per: time.Hour, Burst: 5}
+	messagePolicy      = ratelimit.Policy{Rate: 120, Per: time.Hour, Burst: 20}
+)
+
+// limitByIP ограничивает все запросы с одного адреса. Он стоит в
+// стандартной цепочке, до загрузки сеанса, поэтому о пользователе ещё
+// ничего не известно.
+func (app *application) limitByIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !app.allow(w, "read:ip:"+app.clientIP(r).String(), readPolicy) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limit возвращает middleware для отдельного маршрута. Аутентифицированные
+// пользователи ограничиваются по идентификатору (смена адреса не поможет
+// обойти предел), анонимные - по адресу. Поэтому limit должен идти в
+// цепочке после authenticate.
+func (app *application) limit(name string, p ratelimit.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if app.limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := name + ":ip:" + app.clientIP(r).String()
+			if id := app.authenticatedUserID(r); id != 0 {
+				key = name + ":user:" + strconv.Itoa(id)
+			}
+			if !app.allow(w, key, p) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow берёт токен и, если его нет, отвечает 429 с заголовком Retry-After.
+func (app *application) allow(w http.ResponseWriter, key string, p ratelimit.Policy) bool {
+	ok, retry := app.limiter.Allow(key, p)
+	if ok {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(retry.Seconds())))
+	app.clientError(w, http.StatusTooManyRequests)
+	return false
+}
+
+// clientIP возвращает адрес клиента. X-Forwarded-For учитывается, только
+// если запрос пришёл от доверенного прокси, иначе клиент мог бы подставить
+// любой адрес и получить новый лимит. Заголовок читается справа налево:
+// правые адреса дописали наши прокси, левее первого недоверенного адреса
+// может быть что угодно.
+func (app *application) clientIP(r *http.Request) netip.Addr {
+	ip := parseIP(r.RemoteAddr)
+	if !app.trustedProxy(ip) {
+		return ip
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := parseIP(strings.TrimSpace(forwarded[i]))
+		if !hop.IsValid() {
+			break
+		}
+		ip = hop
+		if !app.trustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (app *application) trustedProxy(ip netip.Addr) bool {
+	for _, prefix := range app.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP разбирает адрес с портом или без него.
+func parseIP(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap()
+}
+
+// parseTrustedProxies разбирает список адресов и подсетей доверенных прокси.
+func parseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			ip, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("trusted-proxies: %w", err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted-proxies: %w", err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
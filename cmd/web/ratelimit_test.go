@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestLoginRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+	form := url.Values{}
+	form.Add("email", "mallory@example.com")
+	form.Add("password", "wrong")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+
+	for i := 0; i < authPolicy.Burst; i++ {
+		if code, _, _ := ts.postForm(t, "/user/login", form); code != http.StatusOK {
+			t.Fatalf("attempt %d: want %d; got %d", i+1, http.StatusOK, code)
+		}
+	}
+	code, header, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("want %d; got %d", http.StatusTooManyRequests, code)
+	}
+	if header.Get("Retry-After") != "12" {
+		t.Errorf("want Retry-After %q; got %q", "12", header.Get("Retry-After"))
+	}
+
+	// Reading pages is limited separately and still works.
+	if code, _, _ := ts.get(t, "/"); code != http.StatusOK {
+		t.Errorf("want %d for a read; got %d", http.StatusOK, code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	app := newTestApplication(t)
+	var err error
+	app.trustedProxies, err = parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"Direct", "203.0.113.5:1234", "", "203.0.113.5"},
+		{"Untrusted peer is not believed", "203.0.113.5:1234", "198.51.100.7", "203.0.113.5"},
+		{"Trusted proxy", "192.0.2.1:1234", "198.51.100.7", "198.51.100.7"},
+		{"Chain of proxies", "10.0.0.2:1234", "198.51.100.7, 10.0.0.3", "198.51.100.7"},
+		{"Spoofed left entries are ignored", "10.0.0.2:1234", "1.1.1.1, 198.51.100.7", "198.51.100.7"},
+		{"Garbage stops the walk", "10.0.0.2:1234", "198.51.100.7, junk", "10.0.0.2"},
+		{"IPv6", "[2001:db8::1]:1234", "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := app.clientIP(r).String(); got != tt.want {
+				t.Errorf("want %s; got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -11,32 +11,80 @@ import (
 func (app *application) routes() http.Handler {
 	// Create a middleware chain containing our 'standard' middleware
 	// which will be used for every request our application receives.
-	standardMiddleware := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+	standardMiddleware := alice.New(app.requestID, app.metrics.measure, app.logRequest, app.recoverPanic, app.secureHeaders, app.limitByIP, compress)
 
 	// Создаем новую цепочку middleware, содержащую промежуточное программное обеспечение, специфичное для
 	// наших динамических маршрутов приложений. На данный момент эта цепочка будет содержать только
 	// middleware сеанса, но мы добавим к нему больше позже
-	dynamicMiddleware := alice.New(app.session.Enable, noSurf, app.authenticate)
+	dynamicMiddleware := alice.New(app.session.Enable, noSurf, app.authenticate, app.detectLocale)
 
-	mux := pat.New()
+	// Для загрузки вложений размер тела ограничивается до разбора формы.
+	uploadMiddleware := alice.New(app.limitUploadSize).Extend(dynamicMiddleware)
+
+	// routeMux помечает каждый маршрут его шаблоном для метрик.
+	mux := routeMux{pat.New(), app.metrics}
 	// Обновляем эти маршруты, чтобы использовать новую цепочку middleware за которой следует
 	// с помощью соответствующей функции-обработчика.
 	mux.Get("/", dynamicMiddleware.ThenFunc(app.home))
 	mux.Get("/snippet/create", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createSnippetForm))
-	mux.Post("/snippet/create", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createSnippet))
+	mux.Post("/snippet/create", uploadMiddleware.Append(app.requireAuthentication, app.limit("write", writePolicy)).ThenFunc(app.createSnippet))
+	mux.Post("/snippet/preview", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.previewSnippet))
+	// Потоки событий (Server-Sent Events) не проходят через session.Enable:
+	// он задержал бы ответ до конца запроса. Пользователя из сеанса
+	// достаёт streamSession.
+	mux.Get("/snippet/:id/events", http.HandlerFunc(app.snippetEvents))
 	mux.Get("/snippet/:id", dynamicMiddleware.ThenFunc(app.showSnippet))
+	mux.Post("/snippet/:id/expiry", dynamicMiddleware.Append(app.requireAuthentication, app.limit("write", writePolicy)).ThenFunc(app.updateSnippetExpiry))
+	mux.Post("/snippet/:id/comment", dynamicMiddleware.Append(app.requireAuthentication, app.limit("write", writePolicy)).ThenFunc(app.createComment))
+	mux.Post("/snippet/:id/poll", dynamicMiddleware.Append(app.requireAuthentication, app.limit("write", writePolicy)).ThenFunc(app.votePoll))
+	mux.Post("/snippet/:id/save", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.saveItem))
+	mux.Post("/snippet/:id/unsave", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.unsaveItem))
+	mux.Post("/snippet/:id/vote", dynamicMiddleware.Append(app.requireAuthentication, app.limit("write", writePolicy)).ThenFunc(app.voteSnippet))
+	mux.Get("/t/:tag", dynamicMiddleware.ThenFunc(app.showTag))
+	mux.Get("/search", dynamicMiddleware.ThenFunc(app.searchSnippets))
+	mux.Get("/attachment/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showAttachment))
+	mux.Get("/attachment/:id/thumb", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showAttachmentThumbnail))
 
 	// Маршруты для User Authentication.
 	mux.Get("/user/signup", dynamicMiddleware.ThenFunc(app.signupUserForm))
-	mux.Post("/user/signup", dynamicMiddleware.ThenFunc(app.signupUser))
+	mux.Post("/user/signup", dynamicMiddleware.Append(app.limit("auth", authPolicy)).ThenFunc(app.signupUser))
 	mux.Get("/user/login", dynamicMiddleware.ThenFunc(app.loginUserForm))
-	mux.Post("/user/login", dynamicMiddleware.ThenFunc(app.loginUser))
+	mux.Post("/user/login", dynamicMiddleware.Append(app.limit("auth", authPolicy)).ThenFunc(app.loginUser))
 	mux.Post("/user/logout", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.logoutUser))
+	mux.Get("/user/settings", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.settingsForm))
+	mux.Post("/user/settings", uploadMiddleware.Append(app.requireAuthentication).ThenFunc(app.updateSettings))
+	mux.Get("/user/notifications/events", alice.New(app.streamSession, app.requireAuthentication).ThenFunc(app.notificationEvents))
+	mux.Get("/user/notifications", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showNotifications))
+	mux.Post("/user/notifications/read", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.readAllNotifications))
+	mux.Post("/user/notifications/preferences", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.updateNotificationPreferences))
+	mux.Post("/user/notifications/:id/read", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.readNotification))
+	mux.Get("/user/saved", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showSaved))
+	mux.Post("/user/saved/collections", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.createCollection))
+	mux.Post("/user/saved/collections/:id/delete", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.deleteCollection))
+	mux.Get("/messages", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.inbox))
+	mux.Get("/messages/new", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.newMessageForm))
+	mux.Post("/messages/new", dynamicMiddleware.Append(app.requireAuthentication, app.limit("conversation", conversationPolicy)).ThenFunc(app.startConversation))
+	mux.Get("/messages/:id", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.showConversation))
+	mux.Post("/messages/:id", dynamicMiddleware.Append(app.requireAuthentication, app.limit("message", messagePolicy)).ThenFunc(app.sendMessage))
+	mux.Post("/u/:name/block", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.blockUser))
+	mux.Post("/u/:name/unblock", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.unblockUser))
+	mux.Get("/u/:name/avatar", dynamicMiddleware.ThenFunc(app.showAvatar))
+	mux.Get("/u/:name", dynamicMiddleware.ThenFunc(app.showProfile))
+	mux.Post("/locale", dynamicMiddleware.ThenFunc(app.changeLocale))
 
 	mux.Get("/ping", http.HandlerFunc(ping))
+	// Отчёты о нарушениях CSP браузер присылает без cookie и CSRF-токена.
+	mux.Post("/csp-report", http.HandlerFunc(app.reportCSP))
+	mux.Get("/healthz", http.HandlerFunc(app.healthz))
+	mux.Get("/readyz", http.HandlerFunc(app.readyz))
+
+	// Метрики на основном адресе доступны только по паролю; без пароля их
+	// можно получить лишь через отдельный адрес -metrics-addr.
+	if app.metricsPassword != "" {
+		mux.Get("/metrics", basicAuth(app.metricsUser, app.metricsPassword, app.metrics.handler()))
+	}
 
-	fileServer := http.FileServer(http.Dir("./ui/static/"))
-	mux.Get("/static/", http.StripPrefix("/static", fileServer))
+	mux.Get("/static/", app.assets.handler())
 
 	// Return the 'standard' middleware chain followed by the servemux.
 	return standardMiddleware.Then(mux)
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// Сколько сохранённых элементов показывается на одной странице и
+// предельная длина имени коллекции.
+const (
+	savedPerPage            = 20
+	collectionNameMaxLength = 100
+)
+
+// savedPage собирает страницу сохранённого: коллекции пользователя и
+// страницу page элементов коллекции collectionID (0 - всех элементов).
+// Если такой коллекции у пользователя нет, возвращается models.ErrNoRecord.
+func (app *application) savedPage(r *http.Request, collectionID, page int) (*templateData, error) {
+	userID := app.authenticatedUserID(r)
+	collections, err := app.saved.Collections(userID)
+	if err != nil {
+		return nil, err
+	}
+	td := &templateData{Collections: collections}
+	if collectionID != 0 {
+		for _, c := range collections {
+			if c.ID == collectionID {
+				td.Collection = c
+			}
+		}
+		if td.Collection == nil {
+			return nil, models.ErrNoRecord
+		}
+	}
+	// Один лишний элемент показывает, есть ли следующая страница.
+	items, err := app.saved.Items(userID, collectionID, savedPerPage+1, (page-1)*savedPerPage)
+	if err != nil {
+		return nil, err
+	}
+	if page > 1 {
+		td.PrevPage = page - 1
+	}
+	if len(items) > savedPerPage {
+		items = items[:savedPerPage]
+		td.NextPage = page + 1
+	}
+	td.SavedItems = items
+	return td, nil
+}
+
+// showSaved показывает сохранённое, всё или одной коллекции
+// (?collection=id). Пропавшие и истёкшие заметки в список не попадают.
+func (app *application) showSaved(w http.ResponseWriter, r *http.Request) {
+	collectionID, err := strconv.Atoi(r.URL.Query().Get("collection"))
+	if err != nil || collectionID < 0 {
+		collectionID = 0
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	td, err := app.savedPage(r, collectionID, page)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	td.Form = forms.New(nil)
+	app.render(w, r, "saved.page.tmpl", td)
+}
+
+// savedTarget читает из формы, что сохраняется: заметку s или, если поле
+// "comment" не пустое, комментарий к ней. Если комментария у заметки нет,
+// ok равен false.
+func (app *application) savedTarget(r *http.Request, s *models.Snippet) (commentID int, ok bool, err error) {
+	value := r.PostForm.Get("comment")
+	if value == "" || value == "0" {
+		return 0, true, nil
+	}
+	commentID, err = strconv.Atoi(value)
+	if err != nil {
+		return 0, false, nil
+	}
+	comments, err := app.comments.ForSnippet(s.ID)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, c := range comments {
+		if c.ID == commentID {
+			return commentID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// savedRedirect возвращает пользователя туда, откуда он сохранял: на
+// адрес из поля "next" или к заметке и комментарию.
+func savedRedirect(w http.ResponseWriter, r *http.Request, snippetID, commentID int) {
+	path := fmt.Sprintf("/snippet/%d", snippetID)
+	if commentID != 0 {
+		path += fmt.Sprintf("#comment-%d", commentID)
+	}
+	if next := r.PostForm.Get("next"); next != "" {
+		path = localRedirect(next)
+	}
+	http.Redirect(w, r, path, http.StatusSeeOther)
+}
+
+// saveItem сохраняет заметку или комментарий в коллекцию из поля
+// "collection" (пустое - без коллекции). Уже сохранённый элемент
+// перекладывается в эту коллекцию.
+func (app *application) saveItem(w http.ResponseWriter, r *http.Request) {
+	s := app.snippetFromPath(w, r)
+	if s == nil {
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	commentID, ok, err := app.savedTarget(r, s)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	userID := app.authenticatedUserID(r)
+	collectionID := 0
+	if value := r.PostForm.Get("collection"); value != "" && value != "0" {
+		collectionID, _ = strconv.Atoi(value)
+		collections, err := app.saved.Collections(userID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		ok = false
+		for _, c := range collections {
+			ok = ok || c.ID == collectionID
+		}
+		if !ok {
+			app.clientError(w, http.StatusBadRequest)
+			return
+		}
+	}
+	if err = app.saved.Save(userID, s.ID, commentID, collectionID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "Saved!")
+	savedRedirect(w, r, s.ID, commentID)
+}
+
+// unsaveItem убирает заметку или комментарий из сохранённого.
+func (app *application) unsaveItem(w http.ResponseWriter, r *http.Request) {
+	s := app.snippetFromPath(w, r)
+	if s == nil {
+		return
+	}
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	// Удаляемый комментарий проверять незачем: Unsave просто ничего не
+	// найдёт.
+	commentID, err := strconv.Atoi(r.PostForm.Get("comment"))
+	if err != nil {
+		commentID = 0
+	}
+	if err = app.saved.Unsave(app.authenticatedUserID(r), s.ID, commentID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "Removed from saved.")
+	savedRedirect(w, r, s.ID, commentID)
+}
+
+// createCollection создаёт коллекцию и открывает её.
+func (app *application) createCollection(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("name")
+	form.MaxLength("name", collectionNameMaxLength)
+	var id int
+	if form.Valid() {
+		id, err = app.saved.CreateCollection(app.authenticatedUserID(r), form.Get("name"))
+		if errors.Is(err, models.ErrDuplicateCollection) {
+			form.AddError("name", "You already have a collection with this name")
+		} else if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if !form.Valid() {
+		td, err := app.savedPage(r, 0, 1)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		td.Form = form
+		app.render(w, r, "saved.page.tmpl", td)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/user/saved?collection=%d", id), http.StatusSeeOther)
+}
+
+// deleteCollection удаляет коллекцию; её элементы остаются сохранёнными.
+func (app *application) deleteCollection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	err = app.saved.DeleteCollection(app.authenticatedUserID(r), id)
+	if errors.Is(err, models.ErrNoRecord) {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "Collection deleted.")
+	http.Redirect(w, r, "/user/saved", http.StatusSeeOther)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/models/mock"
+)
+
+func TestSaveItem(t *testing.T) {
+	app := newTestApplication(t)
+	saved := &mock.SavedModel{}
+	app.saved = saved
+	exams, _ := saved.CreateCollection(1, "Exams")
+	foreign, _ := saved.CreateCollection(2, "Bob's")
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		comment      string
+		collection   string
+		next         string
+		wantCode     int
+		wantLocation string
+	}{
+		{"Snippet", "/snippet/1/save", "", "", "", http.StatusSeeOther, "/snippet/1"},
+		{"Comment", "/snippet/1/save", "1", strconv.Itoa(exams), "", http.StatusSeeOther, "/snippet/1#comment-1"},
+		{"Back to saved", "/snippet/3/save", "", "", "/user/saved?page=2", http.StatusSeeOther, "/user/saved?page=2"},
+		{"Foreign redirect", "/snippet/3/save", "", "", "//evil.example", http.StatusSeeOther, "/"},
+		{"Comment of another snippet", "/snippet/3/save", "1", "", "", http.StatusBadRequest, ""},
+		{"Foreign collection", "/snippet/1/save", "", strconv.Itoa(foreign), "", http.StatusBadRequest, ""},
+		{"Missing snippet", "/snippet/99/save", "", "", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{"csrf_token": {csrfToken}, "comment": {tt.comment}, "collection": {tt.collection}, "next": {tt.next}}
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want Location %q; got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+
+	got, _ := saved.Saved(1, 1)
+	if !got[0] || !got[1] || len(got) != 2 {
+		t.Errorf("want snippet 1 and comment 1 saved; got %v", got)
+	}
+	_, _, body := ts.get(t, "/snippet/1")
+	for _, want := range []string{"action='/snippet/1/unsave' method='POST' class='save'", "<input type='hidden' name='comment' value='1'>"} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("want body to contain %q", want)
+		}
+	}
+
+	form := url.Values{"csrf_token": {csrfToken}, "comment": {"1"}}
+	code, header, _ := ts.postForm(t, "/snippet/1/unsave", form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/snippet/1#comment-1" {
+		t.Errorf("want redirect to the comment; got %d %q", code, header.Get("Location"))
+	}
+	if got, _ := saved.Saved(1, 1); got[1] || !got[0] {
+		t.Errorf("want only the comment unsaved; got %v", got)
+	}
+}
+
+func TestShowSaved(t *testing.T) {
+	app := newTestApplication(t)
+	saved := &mock.SavedModel{}
+	app.saved = saved
+	exams, _ := saved.CreateCollection(1, "Exams")
+	saved.Save(1, 3, 0, 0)
+	saved.Save(1, 1, 1, exams)
+	// The snippet has expired since it was saved.
+	saved.Save(1, 99, 0, exams)
+	saved.Save(2, 1, 0, 0)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	code, header, _ := ts.get(t, "/user/saved")
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/login" {
+		t.Fatalf("want redirect to /user/login; got %d %q", code, header.Get("Location"))
+	}
+	ts.login(t)
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		want     []string
+		dontWant []string
+	}{
+		{"All", "/user/saved", http.StatusOK,
+			[]string{"Autumn moonlight", "A frog jumps into the pond", "Exams (1)", "href='/snippet/1#comment-1'", "<option value='1' selected>Exams</option>"},
+			[]string{"/snippet/99", "An old silent pond..."}},
+		{"Collection", "/user/saved?collection=1", http.StatusOK,
+			[]string{"<h2>Exams</h2>", "A frog jumps into the pond", "action='/user/saved/collections/1/delete'"},
+			[]string{"Autumn moonlight"}},
+		{"Past the end", "/user/saved?page=2", http.StatusOK, []string{"Вы пока ничего не сохранили."}, nil},
+		{"Unknown collection", "/user/saved?collection=7", http.StatusNotFound, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Fatalf("want %d; got %d", tt.wantCode, code)
+			}
+			for _, want := range tt.want {
+				if !bytes.Contains(body, []byte(want)) {
+					t.Errorf("want body to contain %q", want)
+				}
+			}
+			for _, dontWant := range tt.dontWant {
+				if bytes.Contains(body, []byte(dontWant)) {
+					t.Errorf("want body not to contain %q", dontWant)
+				}
+			}
+		})
+	}
+}
+
+func TestCollections(t *testing.T) {
+	app := newTestApplication(t)
+	saved := &mock.SavedModel{}
+	app.saved = saved
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	form := url.Values{"csrf_token": {csrfToken}, "name": {"Exams"}}
+	code, header, _ := ts.postForm(t, "/user/saved/collections", form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/saved?collection=1" {
+		t.Fatalf("want redirect to the new collection; got %d %q", code, header.Get("Location"))
+	}
+	saved.Save(1, 3, 0, 1)
+
+	for name, tt := range map[string]struct{ name, want string }{
+		"Duplicate": {"Exams", "У вас уже есть коллекция с таким названием"},
+		"Blank":     {" ", "Это поле не может быть пустым"},
+	} {
+		form := url.Values{"csrf_token": {csrfToken}, "name": {tt.name}}
+		code, _, body := ts.postForm(t, "/user/saved/collections", form)
+		if code != http.StatusOK || !bytes.Contains(body, []byte(tt.want)) {
+			t.Errorf("%s: want %d with %q; got %d", name, http.StatusOK, tt.want, code)
+		}
+	}
+
+	form = url.Values{"csrf_token": {csrfToken}}
+	if code, _, _ := ts.postForm(t, "/user/saved/collections/7/delete", form); code != http.StatusNotFound {
+		t.Errorf("want %d; got %d", http.StatusNotFound, code)
+	}
+	code, header, _ = ts.postForm(t, "/user/saved/collections/1/delete", form)
+	if code != http.StatusSeeOther || header.Get("Location") != "/user/saved" {
+		t.Fatalf("want redirect to /user/saved; got %d %q", code, header.Get("Location"))
+	}
+	// Items of a deleted collection stay saved.
+	items, _ := saved.Items(1, 0, 10, 0)
+	if len(items) != 1 || items[0].CollectionID != 0 {
+		t.Errorf("want the item kept without a collection; got %+v", items)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// listenFDEnv сообщает дочернему процессу, что слушающий сокет уже открыт
+// родителем и передан ему как файловый дескриптор 3.
+const listenFDEnv = "QOGAM_LISTEN_FD"
+
+// parentPIDEnv хранит PID процесса, передавшего сокет. По нему дочерний
+// процесс убеждается, что SIGTERM уйдёт именно тому, кто его запустил.
+const parentPIDEnv = "QOGAM_PARENT_PID"
+
+// listen открывает слушающий сокет или, если процесс запущен родителем при
+// перезапуске без простоя, берёт унаследованный. Второе значение равно true
+// для унаследованного сокета.
+func listen(addr string) (net.Listener, bool, error) {
+	fd := os.Getenv(listenFDEnv)
+	if fd == "" {
+		ln, err := net.Listen("tcp", addr)
+		return ln, false, err
+	}
+	os.Unsetenv(listenFDEnv)
+	n, err := strconv.Atoi(fd)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", listenFDEnv, err)
+	}
+	f := os.NewFile(uintptr(n), "listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	return ln, true, err
+}
+
+// serve обслуживает запросы на ln, пока не придёт сигнал остановки.
+//
+//   - SIGINT и SIGTERM: сервер перестаёт принимать соединения и ждёт
+//     завершения начатых запросов, но не дольше cfg.ShutdownTimeout.
+//   - SIGHUP без cfg.GracefulRestart: перечитывается TLS-сертификат.
+//   - SIGHUP (если включён cfg.GracefulRestart): запускается новая копия
+//     программы, которой передаётся слушающий сокет. Когда она начинает
+//     принимать соединения, она присылает этому процессу SIGTERM, и он
+//     завершается как обычно. Так бинарник можно обновить без простоя.
+//
+// Сигналы передаются каналом, чтобы serve можно было проверить в тестах.
+func (app *application) serve(srv *http.Server, ln net.Listener, cfg *config, signals <-chan os.Signal) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		// Сертификат берётся из srv.TLSConfig.GetCertificate.
+		serveErr <- srv.ServeTLS(ln, "", "")
+	}()
+
+	for {
+		select {
+		case err := <-serveErr:
+			// ServeTLS вернулся сам, без Shutdown - это ошибка.
+			return err
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				// Без перезапуска SIGHUP просто перечитывает сертификат
+				// (при перезапуске его и так загрузит новый процесс).
+				if !cfg.GracefulRestart {
+					if app.certs == nil {
+						app.logger.Info("SIGHUP ignored: graceful restart is disabled and certificates are managed by ACME")
+					} else if err := app.certs.reload(); err != nil {
+						app.logger.Error("certificate reload failed, keeping the previous one", "error", err)
+					} else {
+						app.logger.Info("certificate reloaded", "file", cfg.TLSCert)
+					}
+					continue
+				}
+				if err := app.handoff(ln); err != nil {
+					app.logger.Error("handoff failed", "error", err)
+					continue
+				}
+				app.logger.Info("new process started, waiting for it to take over")
+				continue
+			}
+
+			app.logger.Info("shutting down", "signal", sig.String())
+			// Сначала объявляем себя неготовыми и даём балансировщику время
+			// это заметить: пока идёт задержка, запросы ещё обслуживаются.
+			app.shuttingDown.Store(true)
+			time.Sleep(cfg.ShutdownDelay)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(ctx); err != nil {
+				return err
+			}
+			if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// handoff запускает новый экземпляр программы с теми же аргументами
+// и передаёт ему копию слушающего сокета.
+func (app *application) handoff(ln net.Listener) error {
+	tl, ok := ln.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("listener %T can't be handed off", ln)
+	}
+	f, err := tl.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles[0] становится в дочернем процессе дескриптором 3.
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Env = append(os.Environ(), listenFDEnv+"=3", parentPIDEnv+"="+strconv.Itoa(os.Getpid()))
+	return cmd.Start()
+}
+
+// notifyParent просит родительский процесс, передавший сокет, завершиться.
+// Сигнал отправляется, только если родитель - тот самый процесс, который
+// выполнил handoff. Если он уже завершился, родителем становится init
+// (PID 1), а если переменную выставили вручную, PID не совпадёт, и чужой
+// процесс не получит SIGTERM.
+func notifyParent() error {
+	want := os.Getenv(parentPIDEnv)
+	os.Unsetenv(parentPIDEnv)
+	ppid := os.Getppid()
+	if ppid == 1 || want != strconv.Itoa(ppid) {
+		return fmt.Errorf("parent process %d did not hand off the listener, not signalling it", ppid)
+	}
+	parent, err := os.FindProcess(ppid)
+	if err != nil {
+		return err
+	}
+	return parent.Signal(syscall.SIGTERM)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServeGracefulShutdown(t *testing.T) {
+	app := newTestApplication(t)
+	cfg := &config{
+		TLSCert:         "./../../tls/cert.pem",
+		TLSKey:          "./../../tls/key.pem",
+		ShutdownTimeout: 5 * time.Second,
+	}
+
+	// The handler blocks until the test releases it, so the request is
+	// guaranteed to be in flight when the shutdown signal arrives.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	certs, err := newCertReloader(cfg.TLSCert, cfg.TLSKey, app.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		TLSConfig: newTLSConfig(certs.GetCertificate),
+		ErrorLog:  slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		}),
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- app.serve(srv, ln, cfg, signals)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		rs, err := client.Get("https://" + ln.Addr().String() + "/")
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer rs.Body.Close()
+		body, err := io.ReadAll(rs.Body)
+		responses <- response{string(body), err}
+	}()
+
+	<-started
+	signals <- syscall.SIGTERM
+
+	// serve must wait for the in-flight request instead of returning at once.
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned before the request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	rs := <-responses
+	if rs.err != nil || rs.body != "done" {
+		t.Errorf("want in-flight request to complete; got %q, %v", rs.body, rs.err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("want clean shutdown; got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("serve did not return after shutdown")
+	}
+
+	// The listener is closed, so new connections are refused.
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Error("want new connections to be refused after shutdown")
+	}
+}
+
+func TestNotifyParentWithoutHandoff(t *testing.T) {
+	// Neither a missing nor a foreign PID may lead to the test runner's
+	// parent being sent SIGTERM.
+	for _, pid := range []string{"", strconv.Itoa(os.Getppid() + 1)} {
+		t.Setenv(parentPIDEnv, pid)
+		if err := notifyParent(); err == nil {
+			t.Errorf("pid %q: want error; got nil", pid)
+		}
+	}
+}
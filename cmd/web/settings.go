@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// suggestedZones подсказываются в поле выбора часового пояса: пояса
+// Казахстана и соседних стран. Ввести можно любой пояс из базы IANA.
+var suggestedZones = []string{
+	"Asia/Almaty",
+	"Asia/Qostanay",
+	"Asia/Qyzylorda",
+	"Asia/Aqtobe",
+	"Asia/Aqtau",
+	"Asia/Atyrau",
+	"Asia/Oral",
+	"Asia/Tashkent",
+	"Asia/Bishkek",
+	"Asia/Novosibirsk",
+	"Europe/Moscow",
+	"Europe/Istanbul",
+	"Europe/Berlin",
+	"Europe/London",
+	"America/New_York",
+	"UTC",
+}
+
+// zones хранит уже загруженные часовые пояса: time.LoadLocation при
+// каждом вызове заново читает базу tzdata.
+var zones sync.Map
+
+var errUnknownZone = errors.New("unknown time zone")
+
+// loadZone загружает часовой пояс по имени из базы IANA. Пустое имя и
+// "Local" (пояс сервера) не принимаются: time.LoadLocation понимает их
+// по-своему, а пользователю они ничего не говорят.
+func loadZone(name string) (*time.Location, error) {
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, errUnknownZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errUnknownZone
+	}
+	zones.Store(name, loc)
+	return loc, nil
+}
+
+// zoneOf возвращает часовой пояс, в котором запросу показываются даты:
+// выбранный пользователем или, если он не выбран, часовой пояс сайта.
+func (app *application) zoneOf(r *http.Request) *time.Location {
+	if user := app.authenticatedUser(r); user != nil && user.TimeZone != "" {
+		if loc, err := loadZone(user.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return app.timeZone
+}
+
+// viewOf возвращает вид страницы для запроса.
+func (app *application) viewOf(r *http.Request) view {
+	return view{locale: app.localeOf(r), zone: app.zoneOf(r)}
+}
+
+func (app *application) settingsForm(w http.ResponseWriter, r *http.Request) {
+	profile, err := app.profiles.Get(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	user := app.authenticatedUser(r)
+	data := url.Values{}
+	data.Set("username", user.Username)
+	data.Set("timezone", user.TimeZone)
+	data.Set("bio", profile.Bio)
+	app.render(w, r, "settings.page.tmpl", &templateData{
+		Form:      forms.New(data),
+		Profile:   profile,
+		TimeZones: suggestedZones,
+	})
+}
+
+// updateSettings сохраняет имя пользователя, часовой пояс и профиль.
+// Пустой часовой пояс возвращает часовой пояс сайта. Аватар меняется,
+// только если загружен новый файл или отмечено "remove_avatar".
+func (app *application) updateSettings(w http.ResponseWriter, r *http.Request) {
+	// Без нового аватара браузер может прислать обычную urlencoded-форму.
+	err := r.ParseMultipartForm(multipartMemory)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	userID := app.authenticatedUserID(r)
+	profile, err := app.profiles.Get(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	form := forms.New(r.PostForm).Localize(app.printer(r))
+	form.Required("username")
+	form.Username("username")
+	zone := strings.TrimSpace(form.Get("timezone"))
+	if zone != "" {
+		if _, err := loadZone(zone); err != nil {
+			form.AddError("timezone", "Unknown time zone")
+		}
+	}
+	form.MaxLength("bio", bioMaxLength)
+	avatar, err := app.readAvatar(r, form)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !form.Valid() {
+		app.render(w, r, "settings.page.tmpl", &templateData{Form: form, Profile: profile, TimeZones: suggestedZones})
+		return
+	}
+
+	// Имя меняем первым: если оно занято, остальное не сохраняется, и
+	// форма возвращается с ошибкой.
+	if name := form.Get("username"); name != profile.Username {
+		err = app.users.SetUsername(userID, name)
+		if errors.Is(err, models.ErrDuplicateUsername) {
+			form.AddError("username", "Username is already taken")
+			app.render(w, r, "settings.page.tmpl", &templateData{Form: form, Profile: profile, TimeZones: suggestedZones})
+			return
+		}
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	avatarKey := profile.AvatarKey
+	if form.Get("remove_avatar") != "" {
+		avatarKey = ""
+	}
+	if avatar != nil {
+		if avatarKey, err = app.saveAvatar(avatar); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	err = app.profiles.Update(userID, strings.TrimSpace(form.Get("bio")), avatarKey)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// Старый аватар больше не нужен. Профиль уже сохранён, поэтому ошибку
+	// удаления достаточно записать в лог.
+	if profile.AvatarKey != "" && profile.AvatarKey != avatarKey {
+		if err = app.storage.Delete(profile.AvatarKey); err != nil {
+			app.logger.ErrorContext(r.Context(), "delete avatar", "error", err, "key", profile.AvatarKey)
+		}
+	}
+	err = app.users.SetTimeZone(userID, zone)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.session.Put(r, "flash", "Settings saved!")
+	http.Redirect(w, r, "/user/settings", http.StatusSeeOther)
+}
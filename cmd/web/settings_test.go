@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+func TestZonedDates(t *testing.T) {
+	berlin, err := loadZone("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := loadZone("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		zone      *time.Location
+		tm        time.Time
+		wantDate  string
+		wantTitle string
+	}{
+		// Clocks in Berlin jump from 02:00 CET to 03:00 CEST at 01:00 UTC.
+		{"Berlin before DST", berlin, time.Date(2020, 3, 29, 0, 30, 0, 0, time.UTC),
+			"29 Mar 2020 at 01:30", "29 Mar 2020 at 01:30 CET"},
+		{"Berlin after DST", berlin, time.Date(2020, 3, 29, 1, 30, 0, 0, time.UTC),
+			"29 Mar 2020 at 03:30", "29 Mar 2020 at 03:30 CEST"},
+		// In New York 01:00-02:00 happens twice when DST ends at 06:00 UTC;
+		// the zone abbreviation tells the two apart.
+		{"New York first 01:30", newYork, time.Date(2020, 11, 1, 5, 30, 0, 0, time.UTC),
+			"01 Nov 2020 at 01:30", "01 Nov 2020 at 01:30 EDT"},
+		{"New York second 01:30", newYork, time.Date(2020, 11, 1, 6, 30, 0, 0, time.UTC),
+			"01 Nov 2020 at 01:30", "01 Nov 2020 at 01:30 EST"},
+		{"Other input zone", time.UTC, time.Date(2020, 12, 17, 10, 0, 0, 0, time.FixedZone("UTC+6", 6*60*60)),
+			"17 Dec 2020 at 04:00", "17 Dec 2020 at 04:00 UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs := viewFunctions(view{i18n.English, tt.zone})
+			if got := funcs["humanDate"].(func(time.Time) string)(tt.tm); got != tt.wantDate {
+				t.Errorf("humanDate: want %q; got %q", tt.wantDate, got)
+			}
+			rel := string(funcs["relativeTime"].(func(time.Time) template.HTML)(tt.tm))
+			if !strings.Contains(rel, "title='"+tt.wantTitle+"'") {
+				t.Errorf("relativeTime: want title %q; got %s", tt.wantTitle, rel)
+			}
+			if !strings.Contains(rel, "datetime='"+tt.tm.UTC().Format(time.RFC3339)+"'") {
+				t.Errorf("relativeTime: want the UTC time in datetime; got %s", rel)
+			}
+		})
+	}
+}
+
+func TestZoneOf(t *testing.T) {
+	app := newTestApplication(t)
+	app.timeZone, _ = loadZone("Asia/Almaty")
+
+	tests := []struct {
+		name string
+		user *models.User
+		want string
+	}{
+		{"Anonymous", nil, "Asia/Almaty"},
+		{"No preference", &models.User{}, "Asia/Almaty"},
+		{"Preference", &models.User{TimeZone: "Europe/Berlin"}, "Europe/Berlin"},
+		{"Unknown zone in the database", &models.User{TimeZone: "Mars/Olympus"}, "Asia/Almaty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), contextKeyUser, tt.user))
+			}
+			if got := app.zoneOf(r).String(); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	tests := []struct {
+		name     string
+		timezone string
+		wantCode int
+		wantBody string
+	}{
+		{"Valid zone", "Europe/Berlin", http.StatusSeeOther, ""},
+		{"Site default", "", http.StatusSeeOther, ""},
+		{"Unknown zone", "Mars/Olympus", http.StatusOK, "Неизвестный часовой пояс"},
+		{"Server zone", "Local", http.StatusOK, "Неизвестный часовой пояс"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("username", "alice")
+			form.Add("timezone", tt.timezone)
+			form.Add("csrf_token", csrfToken)
+			code, _, body := ts.postForm(t, "/user/settings", form)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("want body to contain %q", tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golangify.com/snippetbox/pkg/markdown"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// Сколько заметок показывается на одной странице тега.
+const tagPerPage = 20
+
+// Скольких пользователей можно уведомить упоминаниями в одной заметке.
+// Остальные упоминания остаются ссылками, но уведомлений не создают,
+// чтобы заметка не превращалась в рассылку.
+const maxMentionNotifications = 10
+
+// notifyMentions уведомляет упомянутых пользователей. n - шаблон
+// уведомления, в котором не заполнен только получатель. Несуществующие
+// имена пропускаются.
+func (app *application) notifyMentions(r *http.Request, mentions []string, n models.Notification) {
+	if len(mentions) > maxMentionNotifications {
+		mentions = mentions[:maxMentionNotifications]
+	}
+	for _, name := range mentions {
+		p, err := app.profiles.ByUsername(name)
+		if errors.Is(err, models.ErrNoRecord) {
+			continue
+		}
+		if err != nil {
+			app.logger.ErrorContext(r.Context(), "mentioned user", "error", err, "username", name)
+			continue
+		}
+		mention := n
+		mention.UserID = p.ID
+		app.notify(r, &mention)
+	}
+}
+
+// showTag показывает неистёкшие заметки с тегом :tag, начиная с новых.
+func (app *application) showTag(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get(":tag")
+	tag := markdown.NormalizeTag(raw)
+	if tag == "" {
+		app.notFound(w)
+		return
+	}
+	// Как и у профиля, у тега один адрес: /t/Go ведёт на /t/go.
+	if tag != raw {
+		http.Redirect(w, r, markdown.TagURL(tag), http.StatusMovedPermanently)
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	// Одна лишняя заметка показывает, есть ли следующая страница.
+	snippets, err := app.tags.Snippets(tag, tagPerPage+1, (page-1)*tagPerPage)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	td := &templateData{Tag: tag}
+	if page > 1 {
+		td.PrevPage = page - 1
+	}
+	if len(snippets) > tagPerPage {
+		snippets = snippets[:tagPerPage]
+		td.NextPage = page + 1
+	}
+	td.Snippets = snippets
+	app.render(w, r, "tag.page.tmpl", td)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/models/mock"
+)
+
+func TestShowTag(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantLocation string
+		wantBody     []string
+	}{
+		{"Tag", "/t/haiku", http.StatusOK, "", []string{"#haiku", "An old silent pond", "Autumn moonlight"}},
+		{"Other case", "/t/Haiku", http.StatusMovedPermanently, "/t/haiku", nil},
+		{"Unused tag", "/t/go", http.StatusOK, "", []string{"Здесь ничего нет"}},
+		{"Second page", "/t/haiku?page=2", http.StatusOK, "", []string{"Здесь ничего нет"}},
+		{"Empty tag", "/t/%23", http.StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+			if code != tt.wantCode {
+				t.Errorf("want %d; got %d", tt.wantCode, code)
+			}
+			if loc := header.Get("Location"); loc != tt.wantLocation {
+				t.Errorf("want Location %q; got %q", tt.wantLocation, loc)
+			}
+			for _, want := range tt.wantBody {
+				if !bytes.Contains(body, []byte(want)) {
+					t.Errorf("want body to contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSnippetTokens(t *testing.T) {
+	app := newTestApplication(t)
+	tags := &mock.TagModel{}
+	app.tags = tags
+	notifications := &mock.NotificationModel{}
+	app.notifications = notifications
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+	csrfToken := ts.login(t)
+
+	form := url.Values{}
+	form.Add("title", "Study group")
+	// alice is the author, ghost doesn't exist, and tokens in code don't count.
+	form.Add("content", "Join @bob, @alice and @ghost for #Haiku!\n\n```\n@carol #notatag\n```")
+	form.Add("expires", "7")
+	form.Add("csrf_token", csrfToken)
+	code, _, _ := ts.postForm(t, "/snippet/create", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("want %d; got %d", http.StatusSeeOther, code)
+	}
+
+	if got := strings.Join(tags.Added[2], ","); got != "haiku" {
+		t.Errorf("want tags [haiku]; got [%s]", got)
+	}
+	if len(notifications.Inserted) != 1 {
+		t.Fatalf("want 1 notification; got %d", len(notifications.Inserted))
+	}
+	n := notifications.Inserted[0]
+	if n.UserID != 2 || n.Type != models.NotificationMention || n.SnippetID != 2 || n.ActorUsername != "alice" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+}
@@ -1,40 +1,222 @@
 package main
 
 import (
+	"container/list"
+	"fmt"
 	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/i18n"
+	"golangify.com/snippetbox/pkg/markdown"
 	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/search"
 	"html/template" // новый импорт
-	"path/filepath" // новый импорт
+	"io/fs"
+	"net/url"
+	"path"
+	"sync"
 	"time"
 )
 
 // Update the templateData fields, removing the individual FormData and
 // FormErrors fields and replacing them with a single Form field.
 type templateData struct {
-	CSRFToken       string
-	CurrentYear     int
-	Flash           string
-	Form            *forms.Form
-	Snippet         *models.Snippet
-	Snippets        []*models.Snippet
+	Attachments         []*models.Attachment
+	AuthenticatedUserID int
+	// AuthenticatedUsername нужен для ссылки на свой профиль.
+	AuthenticatedUsername string
+	Comments              []*models.Comment
+	CSPNonce              string
+	CSRFToken             string
+	CurrentYear           int
+	ExpiryPolicy          expiryPolicy
+	Flash                 string
+	Form                  *forms.Form
+	// Locale - язык страницы, Locales - языки для переключателя, а
+	// CurrentPath - адрес, на который переключатель вернёт пользователя.
+	Locale      i18n.Locale
+	Locales     []i18n.Locale
+	CurrentPath string
+	// Conversations, Conversation и Messages - личные переписки;
+	// UnreadMessages - счётчик в меню; BlockedUsers - заблокированные
+	// пользователи; Blocked - заблокирован ли владелец профиля.
+	Conversations  []*models.Conversation
+	Conversation   *models.Conversation
+	Messages       []*models.Message
+	UnreadMessages int
+	BlockedUsers   []models.Participant
+	Blocked        bool
+	// Notifications и NotificationPreferences - список и настройки на
+	// странице уведомлений; UnreadNotifications - счётчик в меню;
+	// EmailNotifications - можно ли получать уведомления письмом.
+	Notifications           []*models.Notification
+	NotificationPreferences []models.NotificationPreference
+	UnreadNotifications     int
+	EmailNotifications      bool
+	// TimeZone - часовой пояс, в котором показываются даты; TimeZones -
+	// подсказки для поля выбора часового пояса в настройках.
+	TimeZone  string
+	TimeZones []string
+	// Poll - опрос на странице заметки, PollClosed - закрыт ли он;
+	// PollOptions - поля вариантов на форме создания заметки.
+	Poll        *models.Poll
+	PollClosed  bool
+	PollOptions []string
+	// SavedItems и Collections - сохранённое и коллекции пользователя,
+	// Collection - открытая коллекция; Saved - что из страницы заметки
+	// сохранено (0 - сама заметка, иначе идентификатор комментария).
+	SavedItems  []*models.SavedItem
+	Collections []*models.Collection
+	Collection  *models.Collection
+	Saved       map[int]bool
+	// Profile - профиль на странице /u/:name и в настройках.
+	Profile *models.Profile
+	// Score - сумма голосов за заметку.
+	Score       int
+	Search      *search.Results
+	SearchQuery string
+	Snippet     *models.Snippet
+	Snippets    []*models.Snippet
+	// Tag - тег на странице /t/:tag; PrevPage и NextPage - номера
+	// соседних страниц списка или 0, если такой страницы нет.
+	Tag             string
+	PrevPage        int
+	NextPage        int
 	IsAuthenticated bool
 }
 
 // Initialize a template.FuncMap object and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup between the names of our
 // custom template functions and the functions themselves.
+// Функции t, humanDate и relativeTime здесь английские и показывают время
+// в UTC; при отрисовке страницы они заменяются функциями языка и часового
+// пояса запроса (см. viewFunctions).
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":        humanDate,
+	"markdown":         markdown.Render,
+	"notification":     viewFunctions(view{i18n.English, time.UTC})["notification"],
+	"notificationPath": notificationPath,
+	"profileURL":       profileURL,
+	"relativeTime":     viewFunctions(view{i18n.English, time.UTC})["relativeTime"],
+	"t":                i18n.NewPrinter(i18n.English).Sprintf,
+	"tagURL":           markdown.TagURL,
 }
 
-func newTemplateCache(dir string) (map[string]*template.Template, error) {
+// profileURL возвращает адрес страницы профиля пользователя с именем
+// пользователя username. Имена состоят только из безопасных для адреса
+// символов, но PathEscape защищает от записей, появившихся в обход проверки.
+func profileURL(username string) string {
+	return "/u/" + url.PathEscape(username)
+}
+
+// view - то, от чего, кроме данных, зависит вид страницы: язык и часовой
+// пояс читателя.
+type view struct {
+	locale i18n.Locale
+	zone   *time.Location
+}
+
+// key отличает виды друг от друга. Указатели на *time.Location сравнивать
+// нельзя: LoadLocation каждый раз возвращает новый.
+func (v view) key() string {
+	return string(v.locale) + "|" + v.zone.String()
+}
+
+// viewFunctions возвращает функции шаблонов, зависящие от вида страницы:
+// {{t "Home"}} переводит строку (с аргументами - как fmt.Sprintf),
+// humanDate показывает дату в часовом поясе читателя с названиями месяцев
+// его языка, notification описывает уведомление, а relativeTime - сколько времени прошло ("3 часа назад") с
+// точной датой во всплывающей подсказке.
+func viewFunctions(v view) template.FuncMap {
+	p := i18n.NewPrinter(v.locale)
+	absolute := func(t time.Time) string {
+		local := t.In(v.zone)
+		return p.Date(local) + " " + local.Format("MST")
+	}
+	return template.FuncMap{
+		"humanDate": func(t time.Time) string { return p.Date(t.In(v.zone)) },
+		"notification": func(n *models.Notification) string {
+			return notificationText(p, n)
+		},
+		"relativeTime": func(t time.Time) template.HTML {
+			if t.IsZero() {
+				return ""
+			}
+			return template.HTML(fmt.Sprintf("<time datetime='%s' title='%s'>%s</time>",
+				t.UTC().Format(time.RFC3339),
+				template.HTMLEscapeString(absolute(t)),
+				template.HTMLEscapeString(p.Ago(time.Since(t)))))
+		},
+		"t": p.Sprintf,
+	}
+}
+
+// maxViews - сколько копий одной страницы хранится одновременно. Часовой
+// пояс выбирает пользователь, поэтому видов может быть сколько угодно, а
+// на практике большинство читателей приходится на несколько поясов.
+const maxViews = 32
+
+// pageTemplate - набор шаблонов одной страницы. Сам разобранный набор
+// никогда не выполняется: html/template не даёт клонировать набор после
+// первого выполнения. Для каждого вида страницы (языка и часового пояса)
+// при первой отрисовке делается копия со своими функциями, и дальше
+// используется она. Копий хранится не больше maxViews: давно не нужные
+// вытесняются.
+type pageTemplate struct {
+	parsed *template.Template
+
+	mu    sync.Mutex
+	views map[string]*list.Element
+	// recent упорядочивает виды от недавно использованных к давним.
+	recent *list.List
+}
+
+type pageView struct {
+	key string
+	ts  *template.Template
+}
+
+func newPageTemplate(ts *template.Template) *pageTemplate {
+	return &pageTemplate{parsed: ts, views: map[string]*list.Element{}, recent: list.New()}
+}
+
+// forView возвращает набор шаблонов страницы для вида v.
+func (p *pageTemplate) forView(v view) (*template.Template, error) {
+	key := v.key()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.views[key]; ok {
+		p.recent.MoveToFront(e)
+		return e.Value.(*pageView).ts, nil
+	}
+	ts, err := p.parsed.Clone()
+	if err != nil {
+		return nil, err
+	}
+	ts.Funcs(viewFunctions(v))
+	p.views[key] = p.recent.PushFront(&pageView{key: key, ts: ts})
+	if p.recent.Len() > maxViews {
+		oldest := p.recent.Back()
+		p.recent.Remove(oldest)
+		delete(p.views, oldest.Value.(*pageView).key)
+	}
+	return ts, nil
+}
+
+// newTemplateCache разбирает шаблоны из корня fsys (встроенного ui.Files
+// или каталога на диске в режиме -dev). Функция static в шаблонах
+// возвращает адрес статического файла с хешем в имени: {{static "css/main.css"}}.
+func newTemplateCache(fsys fs.FS, static *assets) (map[string]*pageTemplate, error) {
 	// Инициализируем новую карту, которая будет хранить кэш.
-	cache := map[string]*template.Template{}
+	cache := map[string]*pageTemplate{}
+
+	funcs := template.FuncMap{"static": static.url}
+	for name, fn := range functions {
+		funcs[name] = fn
+	}
 
 	// Используем функцию filepath.Glob, чтобы получить срез всех файловых путей с
 	// расширением '.page.tmpl'. По сути, мы получим список всех файлов шаблонов для страниц
 	// нашего веб-приложения.
-	pages, err := filepath.Glob(filepath.Join(dir, "*.page.tmpl"))
+	pages, err := fs.Glob(fsys, "*.page.tmpl")
 	if err != nil {
 		return nil, err
 	}
@@ -43,35 +225,35 @@ func newTemplateCache(dir string) (map[string]*template.Template, error) {
 	for _, page := range pages {
 		// Извлечение конечное названия файла (например, 'home.page.tmpl') из полного пути к файлу
 		// и присваивание его переменной name.
-		name := filepath.Base(page)
+		name := path.Base(page)
 
 		// Обрабатываем итерируемый файл шаблона.
 		// The template.FuncMap must be registered with the template set before you
 		// call the ParseFiles() method. This means we have to use template.New() to
 		// create an empty template set, use the Funcs() method to register the
 		// template.FuncMap, and then parse the file as normal.
-		ts, err := template.New(name).Funcs(functions).ParseFiles(page)
+		ts, err := template.New(name).Funcs(funcs).ParseFS(fsys, page)
 		if err != nil {
 			return nil, err
 		}
 
 		// метод ParseGlob для добавления всех каркасных шаблонов.
 		// В нашем случае это только файл base.layout.tmpl (основная структура шаблона).
-		ts, err = ts.ParseGlob(filepath.Join(dir, "*.layout.tmpl"))
+		ts, err = ts.ParseFS(fsys, "*.layout.tmpl")
 		if err != nil {
 			return nil, err
 		}
 
 		// Используем метод ParseGlob для добавления всех вспомогательных шаблонов.
 		// В нашем случае это footer.partial.tmpl "подвал" нашего шаблона.
-		ts, err = ts.ParseGlob(filepath.Join(dir, "*.partial.tmpl"))
+		ts, err = ts.ParseFS(fsys, "*.partial.tmpl")
 		if err != nil {
 			return nil, err
 		}
 
 		// Добавляем полученный набор шаблонов в кэш, используя название страницы
 		// (например, home.page.tmpl) в качестве ключа для нашей карты.
-		cache[name] = ts
+		cache[name] = newPageTemplate(ts)
 	}
 
 	// Возвращаем полученную карту.
@@ -88,3 +270,44 @@ func humanDate(t time.Time) string {
 	// Convert the time to UTC before formatting it.
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
+
+// templateReloader используется в режиме -dev: перед каждой отрисовкой он
+// проверяет, изменились ли шаблоны на диске, и при необходимости разбирает
+// их заново, так что правки видны без перезапуска сервера.
+type templateReloader struct {
+	fsys   fs.FS
+	static *assets
+
+	mu      sync.Mutex
+	version string
+	cache   map[string]*pageTemplate
+}
+
+func newTemplateReloader(fsys fs.FS, static *assets) (*templateReloader, error) {
+	tr := &templateReloader{fsys: fsys, static: static}
+	if _, err := tr.load(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// load возвращает актуальный набор шаблонов. Если новые шаблоны не
+// разбираются, возвращается ошибка: в режиме разработки её лучше увидеть
+// сразу, чем смотреть на старую версию страницы.
+func (tr *templateReloader) load() (map[string]*pageTemplate, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	version, err := hashFS(tr.fsys, nil)
+	if err != nil {
+		return nil, err
+	}
+	if version == tr.version {
+		return tr.cache, nil
+	}
+	cache, err := newTemplateCache(tr.fsys, tr.static)
+	if err != nil {
+		return nil, err
+	}
+	tr.version, tr.cache = version, cache
+	return cache, nil
+}
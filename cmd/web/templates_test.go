@@ -2,9 +2,16 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	"html/template"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
+
+	"golangify.com/snippetbox/pkg/i18n"
 )
 
 func TestHumanDate(t *testing.T) {
@@ -93,3 +100,94 @@ func TestShowSnippet(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateReloader(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "footer.partial.tmpl"), []byte(`{{define "footer"}}{{end}}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		layout := `{{define "base"}}<p>{{template "main" .}}</p>{{end}}`
+		if err := os.WriteFile(filepath.Join(dir, "base.layout.tmpl"), []byte(layout), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		page := `{{template "base" .}}{{define "main"}}` + body + `{{end}}`
+		if err := os.WriteFile(filepath.Join(dir, "home.page.tmpl"), []byte(page), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	render := func(tr *templateReloader) string {
+		t.Helper()
+		cache, err := tr.load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := cache["home.page.tmpl"].forView(view{i18n.English, time.UTC})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err = ts.Execute(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	write("old")
+	static, err := newAssets(fstest.MapFS{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := newTemplateReloader(os.DirFS(dir), static)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(tr); got != "<p>old</p>" {
+		t.Errorf("want %q; got %q", "<p>old</p>", got)
+	}
+
+	// An edit on disk is picked up without a restart.
+	write("new")
+	if got := render(tr); got != "<p>new</p>" {
+		t.Errorf("want %q; got %q", "<p>new</p>", got)
+	}
+
+	// A broken edit is reported rather than silently ignored.
+	write("{{if}}")
+	if _, err := tr.load(); err == nil {
+		t.Error("want an error for a broken template")
+	}
+}
+
+func TestPageTemplateViewLimit(t *testing.T) {
+	parsed, err := template.New("page").Funcs(functions).Parse(`{{humanDate .}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newPageTemplate(parsed)
+	created := time.Date(2020, 12, 17, 23, 0, 0, 0, time.UTC)
+
+	render := func(zone *time.Location) string {
+		t.Helper()
+		ts, err := p.forView(view{i18n.English, zone})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err = ts.Execute(&buf, created); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	for i := 0; i < maxViews*2; i++ {
+		render(time.FixedZone(fmt.Sprintf("Z%d", i), i*60))
+	}
+	if len(p.views) != maxViews || p.recent.Len() != maxViews {
+		t.Errorf("want %d cached views; got %d", maxViews, len(p.views))
+	}
+	// Evicted views are cloned again on demand.
+	if got := render(time.FixedZone("Z0", 0)); got != "17 Dec 2020 at 23:00" {
+		t.Errorf("unexpected date %q", got)
+	}
+}
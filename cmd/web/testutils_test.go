@@ -2,12 +2,24 @@ package main
 
 import (
 	"github.com/golangcollege/sessions"
+	"golangify.com/snippetbox/pkg/i18n"
 	"golangify.com/snippetbox/pkg/models/mock"
+	"golangify.com/snippetbox/pkg/models/mysql"
+	"golangify.com/snippetbox/pkg/pubsub"
+	"golangify.com/snippetbox/pkg/ratelimit"
+	"golangify.com/snippetbox/pkg/search"
+	"golangify.com/snippetbox/pkg/storage"
+	"golangify.com/snippetbox/ui"
+	"html"
 	"io"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -16,7 +28,25 @@ import (
 // application struct containing mocked dependencies.
 func newTestApplication(t *testing.T) *application {
 	// Create an instance of the template cache.
-	templateCache, err := newTemplateCache("./../../ui/html/")
+	// Tests use the same embedded files as the production binary.
+	htmlFiles, err := fs.Sub(ui.Files, "html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticFiles, err := fs.Sub(ui.Files, "static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	static, err := newAssets(staticFiles, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateCache, err := newTemplateCache(htmlFiles, static)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The mock database is always migrated to the latest embedded version.
+	latestMigration, err := mysql.LatestVersion()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -24,15 +54,64 @@ func newTestApplication(t *testing.T) *application {
 	session := sessions.New([]byte("3dSm5MnygFHh7XidAtbskXrjbwfoJcbJ"))
 	session.Lifetime = 12 * time.Hour
 	session.Secure = true
+	// The in-memory search index stands in for MySQL's FULLTEXT index and is
+	// seeded with the mock snippet.
+	index := search.NewMemoryIndex()
+	snippet, err := (&mock.SnippetModel{}).Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Add(search.Document{
+		Kind:    search.KindPost,
+		ID:      snippet.ID,
+		PostID:  snippet.ID,
+		Title:   snippet.Title,
+		Content: snippet.Content,
+		Author:  "alice",
+		Created: snippet.Created,
+	})
 	// Initialize the dependencies, using the mocks for the loggers and
 	// database models.
+	// Attachments are stored in a temporary directory removed after the test.
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.Save("pond.png", strings.NewReader("pond"))
+	files.Save("pond-thumb.png", strings.NewReader("thumb"))
+	limiter := ratelimit.NewMemory(time.Hour)
+	t.Cleanup(limiter.Close)
+	events := pubsub.NewMemory()
+	t.Cleanup(events.Close)
 	return &application{
-		errorLog:      log.New(io.Discard, "", 0),
-		infoLog:       log.New(io.Discard, "", 0),
-		session:       session,
-		snippets:      &mock.SnippetModel{},
-		templateCache: templateCache,
-		users:         &mock.UserModel{},
+		assets:          static,
+		attachments:     &mock.AttachmentModel{},
+		comments:        &mock.CommentModel{},
+		dms:             &mock.DMModel{},
+		events:          events,
+		headers:         securityHeaders{CSP: defaultCSP, HSTSMaxAge: time.Hour},
+		limiter:         limiter,
+		health:          &mock.HealthModel{},
+		latestMigration: latestMigration,
+		locale:          i18n.Russian,
+		timeZone:        time.UTC,
+		expiry:          expiryPolicy{MinDays: 1, MaxDays: 365, AllowPermanent: true},
+		logger:          newLogger(io.Discard, slog.LevelInfo),
+		metrics:         newMetrics(nil),
+		notifications:   &mock.NotificationModel{},
+		polls:           &mock.PollModel{},
+		profiles:        &mock.ProfileModel{},
+		saved:           &mock.SavedModel{},
+		search:          index,
+		session:         session,
+		snippets:        &mock.SnippetModel{},
+		storage:         files,
+		tags:            &mock.TagModel{},
+		templateCache:   templateCache,
+		uiVersion:       static.version,
+		uploads:         uploadPolicy{MaxFileSize: 1 << 20, MaxFiles: 2},
+		users:           &mock.UserModel{},
+		votes:           &mock.VoteModel{},
 	}
 }
 
@@ -79,3 +158,52 @@ func (ts *testServer) get(t *testing.T, urlPath string) (int, http.Header, []byt
 	}
 	return rs.StatusCode, rs.Header, body
 }
+
+// Define a regular expression which captures the CSRF token value from the
+// HTML for our login and signup pages.
+var csrfTokenRX = regexp.MustCompile(`<input type='hidden' name='csrf_token' value='(.+)'>`)
+
+func extractCSRFToken(t *testing.T, body []byte) string {
+	// Use the FindSubmatch method to extract the token from the HTML body.
+	// Note that this returns an array with the entire matched pattern in the
+	// first position, and the values of any captured data in the subsequent
+	// positions.
+	matches := csrfTokenRX.FindSubmatch(body)
+	if len(matches) < 2 {
+		t.Fatal("no csrf token found in body")
+	}
+	return html.UnescapeString(string(matches[1]))
+}
+
+// Create a postForm method for sending POST requests to the test server.
+// The final parameter to this method is a url.Values object which can contain
+// any data that you want to send in the request body.
+func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (int, http.Header, []byte) {
+	rs, err := ts.Client().PostForm(ts.URL+urlPath, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs.StatusCode, rs.Header, body
+}
+
+// login signs in as the mock user alice@example.com. The session cookie is
+// kept in the client's cookie jar, so subsequent requests are authenticated.
+// It returns a CSRF token which is valid for the rest of the session.
+func (ts *testServer) login(t *testing.T) string {
+	_, _, body := ts.get(t, "/user/login")
+	form := url.Values{}
+	form.Add("email", "alice@example.com")
+	form.Add("password", "")
+	form.Add("csrf_token", extractCSRFToken(t, body))
+	code, _, _ := ts.postForm(t, "/user/login", form)
+	if code != http.StatusSeeOther {
+		t.Fatalf("login: want %d; got %d", http.StatusSeeOther, code)
+	}
+	_, _, body = ts.get(t, "/snippet/create")
+	return extractCSRFToken(t, body)
+}
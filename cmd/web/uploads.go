@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"golangify.com/snippetbox/pkg/forms"
+	"golangify.com/snippetbox/pkg/media"
+	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/storage"
+)
+
+// uploadPolicy ограничивает загрузку вложений к заметке.
+type uploadPolicy struct {
+	MaxFileSize int64
+	MaxFiles    int
+}
+
+// Сторона миниатюры в пикселях.
+const thumbnailSize = 320
+
+// Сколько данных multipart-формы держать в памяти; остальное Go сбрасывает
+// во временные файлы.
+const multipartMemory = 8 << 20
+
+// maxRequestSize - предельный размер тела запроса с вложениями: все файлы
+// плюс запас на текстовые поля формы.
+func (p uploadPolicy) maxRequestSize() int64 {
+	return int64(p.MaxFiles)*p.MaxFileSize + 1<<20
+}
+
+// pendingUpload - проверенный и обработанный файл, который ещё не сохранён
+// в хранилище.
+type pendingUpload struct {
+	filename    string
+	contentType string
+	data        []byte
+	thumbnail   []byte
+}
+
+// readUploads читает файлы из поля "attachments" multipart-формы, проверяет
+// их размер и настоящий тип, удаляет метаданные из изображений и строит
+// миниатюры. Ошибки проверки добавляются в форму.
+func (app *application) readUploads(r *http.Request, form *forms.Form) ([]*pendingUpload, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+	files := r.MultipartForm.File["attachments"]
+	if len(files) > app.uploads.MaxFiles {
+		form.AddError("attachments", "You can attach at most %d files", app.uploads.MaxFiles)
+		return nil, nil
+	}
+
+	var uploads []*pendingUpload
+	for _, fh := range files {
+		if fh.Size > app.uploads.MaxFileSize {
+			form.AddError("attachments", "%s is too large (maximum is %d MB)", fh.Filename, app.uploads.MaxFileSize>>20)
+			continue
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(f, app.uploads.MaxFileSize+1))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		contentType, err := media.Sniff(data)
+		if err != nil {
+			form.AddError("attachments", "%s: only JPEG, PNG and PDF files are allowed", fh.Filename)
+			continue
+		}
+		u := &pendingUpload{filename: fh.Filename, contentType: contentType, data: data}
+		if media.IsImage(contentType) {
+			stripped, img, err := media.StripMetadata(data, contentType)
+			if errors.Is(err, media.ErrTooManyPixels) {
+				form.AddError("attachments", "%s: the image is too large (maximum is %d megapixels)", fh.Filename, media.MaxPixels/1_000_000)
+				continue
+			}
+			if err != nil {
+				form.AddError("attachments", "%s: the image is damaged", fh.Filename)
+				continue
+			}
+			u.data = stripped
+			u.thumbnail, err = media.Thumbnail(img, thumbnailSize, contentType)
+			if err != nil {
+				return nil, err
+			}
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, nil
+}
+
+// storeUploads сохраняет файлы в хранилище и записывает их описание в базу.
+// Ключи уже сохранённых файлов возвращаются и при ошибке, чтобы вызывающий
+// мог их удалить.
+func (app *application) storeUploads(snippetID, userID int, uploads []*pendingUpload) ([]string, error) {
+	var keys []string
+	for _, u := range uploads {
+		key, err := newStorageKey(media.Extension(u.contentType))
+		if err != nil {
+			return keys, err
+		}
+		if err = app.storage.Save(key, bytes.NewReader(u.data)); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+		a := &models.Attachment{
+			SnippetID:   snippetID,
+			UserID:      userID,
+			Filename:    u.filename,
+			ContentType: u.contentType,
+			Size:        int64(len(u.data)),
+			StorageKey:  key,
+		}
+		if u.thumbnail != nil {
+			a.ThumbnailKey, err = newStorageKey("-thumb" + media.Extension(u.contentType))
+			if err != nil {
+				return keys, err
+			}
+			if err = app.storage.Save(a.ThumbnailKey, bytes.NewReader(u.thumbnail)); err != nil {
+				return keys, err
+			}
+			keys = append(keys, a.ThumbnailKey)
+		}
+		if _, err = app.attachments.Insert(a); err != nil {
+			return keys, err
+		}
+	}
+	return keys, nil
+}
+
+// discardSnippet отменяет создание заметки id, которую не удалось сохранить
+// целиком: удаляет её из базы, а затем уже записанные файлы keys. Если
+// заметку удалить не вышло, файлы остаются, чтобы её вложения не
+// сломались. Пользователь в любом случае получит ответ 500, поэтому ошибки
+// здесь только записываются в лог.
+func (app *application) discardSnippet(r *http.Request, id int, keys []string) {
+	if err := app.snippets.Delete(id); err != nil {
+		app.logger.ErrorContext(r.Context(), "discard snippet", "error", err, "snippet_id", id)
+		return
+	}
+	for _, key := range keys {
+		if err := app.storage.Delete(key); err != nil {
+			app.logger.ErrorContext(r.Context(), "discard attachment", "error", err, "key", key)
+		}
+	}
+}
+
+// newStorageKey генерирует случайное имя файла, чтобы имена, которые
+// присылает пользователь, никогда не попадали в путь на диске.
+func newStorageKey(suffix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + suffix, nil
+}
+
+// showAttachment отдаёт вложение только аутентифицированным пользователям,
+// поэтому файлы не раздаются через публичный /static/.
+func (app *application) showAttachment(w http.ResponseWriter, r *http.Request) {
+	app.serveAttachment(w, r, false)
+}
+
+func (app *application) showAttachmentThumbnail(w http.ResponseWriter, r *http.Request) {
+	app.serveAttachment(w, r, true)
+}
+
+func (app *application) serveAttachment(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	id, err := strconv.Atoi(r.URL.Query().Get(":id"))
+	if err != nil || id < 1 {
+		app.notFound(w)
+		return
+	}
+	a, err := app.attachments.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	key := a.StorageKey
+	if thumbnail {
+		if !a.IsImage() {
+			app.notFound(w)
+			return
+		}
+		key = a.ThumbnailKey
+	}
+
+	f, err := app.storage.Open(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			app.notFound(w)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	// Тип берём из базы (он определён по содержимому при загрузке) и
+	// запрещаем браузеру угадывать его заново. sandbox не даёт выполнить
+	// скрипты, даже если файл окажется не тем, чем кажется.
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Filename}))
+	io.Copy(w, f)
+}
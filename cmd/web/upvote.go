@@ -1,7 +0,0 @@
-package main
-
-type Upvote struct {
-	ID     int
-	UserID int
-	PostID int
-}
@@ -1,11 +0,0 @@
-package main
-
-import "time"
-
-type User struct {
-	ID               int
-	Username         string
-	Email            string
-	Password         string
-	RegistrationDate time.Time
-}
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
+
+	"golangify.com/snippetbox/pkg/username"
 )
 
 // EmailRX Используйте функцию regexp.MustCompile() для анализа шаблона
@@ -22,17 +26,44 @@ var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](
 type Form struct {
 	url.Values
 	Errors errors
+	// printer переводит сообщения об ошибках; без него они остаются
+	// английскими.
+	printer Printer
+}
+
+// Printer переводит сообщение и подставляет в него аргументы, как
+// fmt.Sprintf. Ему удовлетворяет *i18n.Printer.
+type Printer interface {
+	Sprintf(key string, args ...any) string
 }
 
 // New определяет новую функцию для инициализации структуры пользовательской формы.
 // Обратите внимание, что это принимает данные формы в качестве параметра?
 func New(data url.Values) *Form {
 	return &Form{
-		data,
-		errors(map[string][]string{}),
+		Values: data,
+		Errors: errors(map[string][]string{}),
 	}
 }
 
+// Localize задаёт язык сообщений об ошибках и возвращает ту же форму,
+// чтобы вызов можно было добавить прямо к New.
+func (f *Form) Localize(p Printer) *Form {
+	f.printer = p
+	return f
+}
+
+// AddError переводит сообщение об ошибке и добавляет его к полю field.
+// Сообщение передаётся по-английски и служит ключом для перевода.
+func (f *Form) AddError(field, message string, args ...any) {
+	if f.printer != nil {
+		message = f.printer.Sprintf(message, args...)
+	} else if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	f.Errors.Add(field, message)
+}
+
 // Required метод для проверки наличия определенных полей в форме
 // данные присутствуют, а не пустые.
 // Если какие-либо поля не проходят эту проверку, добавит соответствующее сообщение
@@ -41,7 +72,7 @@ func (f *Form) Required(fields ...string) {
 	for _, field := range fields {
 		value := f.Get(field)
 		if strings.TrimSpace(value) == "" {
-			f.Errors.Add(field, "This field cannot be blank")
+			f.AddError(field, "This field cannot be blank")
 		}
 	}
 }
@@ -56,7 +87,7 @@ func (f *Form) MaxLength(field string, d int) {
 		return
 	}
 	if utf8.RuneCountInString(value) > d {
-		f.Errors.Add(field, fmt.Sprintf("This field is too long (maximum is %d characters)", d))
+		f.AddError(field, "This field is too long (maximum is %d characters)", d)
 	}
 }
 
@@ -69,7 +100,7 @@ func (f *Form) MinLength(field string, d int) {
 		return
 	}
 	if utf8.RuneCountInString(value) < d {
-		f.Errors.Add(field, fmt.Sprintf("This field is too short (minimum is %d characters)", d))
+		f.AddError(field, "This field is too short (minimum is %d characters)", d)
 	}
 }
 
@@ -86,7 +117,21 @@ func (f *Form) PermittedValues(field string, opts ...string) {
 			return
 		}
 	}
-	f.Errors.Add(field, "This field is invalid")
+	f.AddError(field, "This field is invalid")
+}
+
+// IntRange метод для проверки того, что определенное поле в форме содержит
+// целое число в диапазоне от min до max включительно. Если проверка завершится
+// неудачей, добавит соответствующее сообщение в форму ошибок.
+func (f *Form) IntRange(field string, min, max int) {
+	value := f.Get(field)
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < min || n > max {
+		f.AddError(field, "This field must be a whole number between %d and %d", min, max)
+	}
 }
 
 // MatchesPattern метод проверки того,
@@ -98,7 +143,78 @@ func (f *Form) MatchesPattern(field string, pattern *regexp.Regexp) {
 		return
 	}
 	if !pattern.MatchString(value) {
-		f.Errors.Add(field, "This field is invalid")
+		f.AddError(field, "This field is invalid")
+	}
+}
+
+// List возвращает непустые значения поля field, которое встречается в
+// форме несколько раз (например, варианты ответа опроса), без пробелов
+// по краям.
+func (f *Form) List(field string) []string {
+	var list []string
+	for _, value := range f.Values[field] {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// ListLength проверяет, что непустых значений поля field от min до max,
+// каждое не длиннее maxLength символов и все они разные.
+func (f *Form) ListLength(field string, min, max, maxLength int) {
+	list := f.List(field)
+	if len(list) < min || len(list) > max {
+		f.AddError(field, "Fill in from %d to %d of these fields", min, max)
+		return
+	}
+	seen := map[string]bool{}
+	for _, value := range list {
+		if utf8.RuneCountInString(value) > maxLength {
+			f.AddError(field, "This field is too long (maximum is %d characters)", maxLength)
+			return
+		}
+		key := strings.ToLower(value)
+		if seen[key] {
+			f.AddError(field, "These fields must not repeat")
+			return
+		}
+		seen[key] = true
+	}
+}
+
+// DateTime проверяет, что поле field содержит дату и время в формате
+// layout (см. time.Parse).
+func (f *Form) DateTime(field, layout string) {
+	value := f.Get(field)
+	if value == "" {
+		return
+	}
+	if _, err := time.Parse(layout, strings.TrimSpace(value)); err != nil {
+		f.AddError(field, "Enter a valid date and time")
+	}
+}
+
+// Username нормализует имя пользователя в поле field (см. пакет username)
+// и проверяет его. Нормализованное имя записывается обратно в форму:
+// его и нужно сохранять, и его же пользователь увидит, если форма вернётся
+// с ошибками.
+func (f *Form) Username(field string) {
+	value := f.Get(field)
+	if value == "" {
+		return
+	}
+	value = username.Normalize(value)
+	f.Set(field, value)
+	switch username.Validate(value) {
+	case username.ErrTooShort:
+		f.AddError(field, "This field is too short (minimum is %d characters)", username.MinLength)
+	case username.ErrTooLong:
+		f.AddError(field, "This field is too long (maximum is %d characters)", username.MaxLength)
+	case username.ErrInvalid:
+		f.AddError(field, "Use Latin letters, digits and underscores, starting with a letter")
+	case username.ErrReserved:
+		f.AddError(field, "This username is reserved")
 	}
 }
 
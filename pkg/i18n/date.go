@@ -0,0 +1,33 @@
+package i18n
+
+import (
+	"fmt"
+	"time"
+)
+
+// Сокращённые названия месяцев. По-русски месяц в дате стоит в родительном
+// падеже ("17 мая"), поэтому сокращения сделаны от него.
+var months = map[Locale][12]string{
+	Russian: {"янв", "фев", "мар", "апр", "мая", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"},
+	Kazakh:  {"қаң", "ақп", "нау", "сәу", "мам", "мау", "шіл", "там", "қыр", "қаз", "қар", "жел"},
+}
+
+// Date форматирует дату и время в часовом поясе t:
+// "17 Dec 2020 at 10:00", "17 дек 2020 в 10:00", "17 жел 2020, 10:00".
+// Для нулевого времени возвращается пустая строка.
+func (p *Printer) Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	names, ok := months[p.locale]
+	if !ok {
+		return t.Format("02 Jan 2006 at 15:04")
+	}
+	month := names[t.Month()-1]
+	switch p.locale {
+	case Russian:
+		return fmt.Sprintf("%02d %s %d в %s", t.Day(), month, t.Year(), t.Format("15:04"))
+	default:
+		return fmt.Sprintf("%02d %s %d, %s", t.Day(), month, t.Year(), t.Format("15:04"))
+	}
+}
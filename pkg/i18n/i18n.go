@@ -0,0 +1,132 @@
+// Package i18n переводит интерфейс на русский, казахский и английский:
+// каталоги сообщений, выбор языка по заголовку Accept-Language и
+// форматирование дат.
+//
+// Ключ сообщения - его английский текст (как в gettext), поэтому для
+// английского языка каталог не нужен, а непереведённая строка показывается
+// по-английски, а не как служебный идентификатор.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale - код языка по ISO 639-1.
+type Locale string
+
+const (
+	Russian Locale = "ru"
+	Kazakh  Locale = "kk"
+	English Locale = "en"
+)
+
+// Supported - поддерживаемые языки в том порядке, в каком они показываются
+// в переключателе.
+var Supported = []Locale{Russian, Kazakh, English}
+
+var catalogs = map[Locale]map[string]string{
+	Russian: ru,
+	Kazakh:  kk,
+}
+
+// Parse проверяет код языка. Регион отбрасывается: "ru-RU" означает "ru".
+func Parse(s string) (Locale, bool) {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "-")
+	for _, l := range Supported {
+		if Locale(base) == l {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Name возвращает название языка на нём самом - так его проще найти в
+// переключателе тому, кто не понимает текущий язык страницы.
+func (l Locale) Name() string {
+	switch l {
+	case Russian:
+		return "Русский"
+	case Kazakh:
+		return "Қазақша"
+	case English:
+		return "English"
+	}
+	return string(l)
+}
+
+// Match выбирает язык по заголовку Accept-Language с учётом весов q.
+// При равных весах побеждает язык, указанный раньше. Если ни один из
+// языков клиента не поддерживается, второй результат равен false.
+func Match(acceptLanguage string) (Locale, bool) {
+	type candidate struct {
+		locale Locale
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		l, ok := Parse(tag)
+		if !ok || q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{l, q})
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].locale, true
+}
+
+// Printer переводит сообщения на один язык.
+type Printer struct {
+	locale  Locale
+	catalog map[string]string
+}
+
+// NewPrinter возвращает Printer для языка l. Для неизвестного языка
+// сообщения остаются английскими.
+func NewPrinter(l Locale) *Printer {
+	return &Printer{locale: l, catalog: catalogs[l]}
+}
+
+// Locale возвращает язык, на который переводит p.
+func (p *Printer) Locale() Locale {
+	return p.locale
+}
+
+// Has сообщает, есть ли в каталоге перевод сообщения key. Английский
+// каталог пуст: ключи и так написаны по-английски.
+func (p *Printer) Has(key string) bool {
+	if p.locale == English {
+		return true
+	}
+	_, ok := p.catalog[key]
+	return ok
+}
+
+// Sprintf переводит сообщение key и подставляет в перевод аргументы, как
+// fmt.Sprintf. Если перевода нет, используется сам key.
+func (p *Printer) Sprintf(key string, args ...any) string {
+	msg, ok := p.catalog[key]
+	if !ok {
+		msg = key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
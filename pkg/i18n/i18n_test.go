@@ -0,0 +1,150 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Locale
+		ok     bool
+	}{
+		{"ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7", Russian, true},
+		{"kk", Kazakh, true},
+		{"de-DE,de;q=0.9,en;q=0.5,kk;q=0.8", Kazakh, true},
+		{"en;q=0.5, ru;q=0.5", English, true},
+		{"EN-gb", English, true},
+		{"ru;q=0", "", false},
+		{"de, fr;q=0.9, *;q=0.1", "", false},
+		{"ru;q=abc, kk;q=0.3", Kazakh, true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := Match(tt.header)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("want %q, %v; got %q, %v", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	tests := []struct {
+		locale Locale
+		key    string
+		args   []any
+		want   string
+	}{
+		{Russian, "Home", nil, "На главную"},
+		{Kazakh, "Created by %s in %d", []any{"Gentlemen", 2024}, "2024 жылы Gentlemen командасы жасаған"},
+		{English, "This field must be a whole number between %d and %d", []any{1, 365}, "This field must be a whole number between 1 and 365"},
+		// Сообщение без перевода показывается как есть.
+		{Russian, "Untranslated %d", []any{5}, "Untranslated 5"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.locale)+"/"+tt.key, func(t *testing.T) {
+			if got := NewPrinter(tt.locale).Sprintf(tt.key, tt.args...); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDate(t *testing.T) {
+	tm := time.Date(2020, 5, 7, 9, 5, 0, 0, time.UTC)
+	tests := []struct {
+		locale Locale
+		want   string
+	}{
+		{English, "07 May 2020 at 09:05"},
+		{Russian, "07 мая 2020 в 09:05"},
+		{Kazakh, "07 мам 2020, 09:05"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.locale), func(t *testing.T) {
+			if got := NewPrinter(tt.locale).Date(tm); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+	if got := NewPrinter(Russian).Date(time.Time{}); got != "" {
+		t.Errorf("want empty string for zero time; got %q", got)
+	}
+}
+
+var verbRX = regexp.MustCompile(`%(\[\d+\])?[a-z]`)
+
+// verbs возвращает форматные глаголы сообщения без учёта их порядка:
+// перевод может переставить аргументы, но не добавить или потерять их.
+func verbs(msg string) []string {
+	found := verbRX.FindAllString(msg, -1)
+	for i, v := range found {
+		found[i] = regexp.MustCompile(`\[\d+\]`).ReplaceAllString(v, "")
+	}
+	sort.Strings(found)
+	return found
+}
+
+func TestCatalogs(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for other, otherCatalog := range catalogs {
+			for key := range otherCatalog {
+				if _, ok := catalog[key]; !ok {
+					t.Errorf("%s: %q is translated to %s but not to %s", locale, key, other, locale)
+				}
+			}
+		}
+		for key, msg := range catalog {
+			if want, got := verbs(key), verbs(msg); !equal(want, got) {
+				t.Errorf("%s: %q has verbs %v; want %v", locale, msg, got, want)
+			}
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAgo(t *testing.T) {
+	tests := []struct {
+		locale Locale
+		d      time.Duration
+		want   string
+	}{
+		{Russian, -time.Minute, "только что"},
+		{Russian, 30 * time.Second, "только что"},
+		{Russian, time.Minute, "1 минуту назад"},
+		{Russian, 3 * time.Hour, "3 часа назад"},
+		{Russian, 11 * time.Hour, "11 часов назад"},
+		{Russian, 21 * time.Hour, "21 час назад"},
+		{Russian, 22 * 24 * time.Hour, "22 дня назад"},
+		{Russian, 5 * 30 * 24 * time.Hour, "5 месяцев назад"},
+		{Russian, 2 * 365 * 24 * time.Hour, "2 года назад"},
+		{English, time.Hour, "1 hour ago"},
+		{English, 90 * time.Minute, "1 hour ago"},
+		{English, 45 * time.Minute, "45 minutes ago"},
+		{Kazakh, 3 * time.Hour, "3 сағат бұрын"},
+		{Kazakh, 10 * time.Second, "жаңа ғана"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.locale)+"/"+tt.d.String(), func(t *testing.T) {
+			if got := NewPrinter(tt.locale).Ago(tt.d); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
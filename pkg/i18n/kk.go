@@ -0,0 +1,184 @@
+package i18n
+
+// kk - казахский каталог. Порядок слов в казахском другой, поэтому в
+// переводах используются номера аргументов: %[2]d, %[1]s.
+var kk = map[string]string{
+	// Навигация и общие элементы страниц.
+	"Home":                 "Басты бет",
+	"Search":               "Іздеу",
+	"Publish":              "Жариялау",
+	"Log out":              "Шығу",
+	"Sign up":              "Тіркелу",
+	"Log in":               "Кіру",
+	"Created by %s in %d":  "%[2]d жылы %[1]s командасы жасаған",
+	"Home page":            "Басты бет",
+	"Latest snippets":      "Соңғы жазбалар",
+	"Title":                "Тақырып",
+	"Created":              "Құрылған",
+	"Nothing here... yet!": "Әзірге мұнда ештеңе жоқ!",
+	"just now":             "жаңа ғана",
+	"Profile":              "Профиль",
+
+	// Заметки.
+	"Create a New Snippet": "Жаңа жазба",
+	"Title:":               "Тақырып:",
+	"Content:":             "Мазмұны:",
+	"Preview:":             "Алдын ала қарау:",
+	"Attachments:":         "Тіркемелер:",
+	"Delete in (days):":    "Жою мерзімі (күн):",
+	"Never":                "Мерзімсіз",
+	"Publish snippet":      "Жазбаны жариялау",
+	"Snippet #%d":          "Жазба #%d",
+	"Created: %s":          "Құрылған: %s",
+	"Expires: never":       "Мерзімі: шексіз",
+	"Expires: %s":          "Мерзімі: %s",
+	"Change expiry":        "Мерзімін өзгерту",
+	"Score:":               "Рейтинг:",
+	"Upvote":               "Қолдау",
+	"Downvote":             "Қарсы",
+	"Remove vote":          "Дауысты қайтару",
+	"Comments":             "Пікірлер",
+	"No comments yet.":     "Әзірге пікір жоқ.",
+	"Comment:":             "Пікір:",
+	"Add comment":          "Пікір қалдыру",
+
+	// Вход и регистрация.
+	"Name:":     "Аты:",
+	"Email:":    "Email:",
+	"Password:": "Құпиясөз:",
+	"Username:": "Пайдаланушы аты:",
+	"Latin letters, digits and underscores. Your profile address and @mentions use the username.": "Латын әріптері, сандар және астын сызу. Пайдаланушы аты профиль мекенжайында және @атауларда қолданылады.",
+
+	// Профиль.
+	"Karma: %d":     "Карма: %d",
+	"Member since:": "Тіркелген уақыты:",
+	"Snippets":      "Жазбалар",
+	"Snippet":       "Жазба",
+	"Comment":       "Пікір",
+
+	// Настройки.
+	"Settings":                               "Баптаулар",
+	"Time zone:":                             "Уақыт белдеуі:",
+	"Leave empty to use the site time zone.": "Сайттың уақыт белдеуін қолдану үшін бос қалдырыңыз.",
+	"Save":                                   "Сақтау",
+	"About me:":                              "Өзім туралы:",
+	"Avatar:":                                "Аватар:",
+	"Remove avatar":                          "Аватарды жою",
+	"Settings saved!":                        "Баптаулар сақталды!",
+
+	// Уведомления.
+	"Notifications":                    "Хабарландырулар",
+	"Mark all as read":                 "Барлығын оқылды деп белгілеу",
+	"Mark as read":                     "Оқылды",
+	"No notifications yet.":            "Әзірге хабарландырулар жоқ.",
+	"Notification settings":            "Хабарландыру баптаулары",
+	"Event":                            "Оқиға",
+	"On the site":                      "Сайтта",
+	"By email":                         "Поштамен",
+	"Comments on my snippets":          "Жазбаларыма пікірлер",
+	"Mentions":                         "Аталымдар",
+	"Upvotes":                          "Қолдау дауыстары",
+	"Someone":                          "Біреу",
+	"(deleted)":                        "(жойылған)",
+	"%s commented on your snippet: %s": "%[1]s сіздің жазбаңызға пікір қалдырды: %[2]s",
+	"%s mentioned you: %s":             "%[1]s сізді атап өтті: %[2]s",
+	"%s upvoted your snippet: %s":      "%[1]s сіздің жазбаңызды қолдады: %[2]s",
+	"You can choose which notifications to receive by email at %s": "Поштамен қандай хабарландырулар алатыныңызды мына жерде таңдауға болады: %s",
+	"Notification settings saved!":                                 "Хабарландыру баптаулары сақталды!",
+
+	// Теги.
+	"Snippets tagged #%s": "#%s тегі бар жазбалар",
+
+	// Личные сообщения.
+	"Messages":                             "Хабарламалар",
+	"New message":                          "Жаңа хабарлама",
+	"No messages yet.":                     "Әзірге хабарламалар жоқ.",
+	"Blocked users":                        "Бұғатталған пайдаланушылар",
+	"Block":                                "Бұғаттау",
+	"Unblock":                              "Бұғаттан шығару",
+	"Send a message":                       "Хабарлама жазу",
+	"To (usernames, separated by commas):": "Кімге (пайдаланушы аттары үтір арқылы):",
+	"Message:":                             "Хабарлама:",
+	"Send":                                 "Жіберу",
+	"Conversation":                         "Хат алмасу",
+	"Reply:":                               "Жауап:",
+	"User blocked.":                        "Пайдаланушы бұғатталды.",
+	"User unblocked.":                      "Пайдаланушы бұғаттан шығарылды.",
+	"Unknown user: %s":                     "Мұндай пайдаланушы жоқ: %s",
+	"Add at least one other user":          "Кем дегенде бір басқа пайдаланушыны қосыңыз",
+	"You can message at most %d users at once":                      "Бір мезгілде %d пайдаланушыдан артық жазуға болмайды",
+	"%s doesn't accept messages from you":                           "%s сізден хабарлама қабылдамайды",
+	"Someone in this conversation doesn't accept messages from you": "Хат алмасудың бір қатысушысы сізден хабарлама қабылдамайды",
+
+	// Опросы.
+	"Add a poll":                            "Сауалнама қосу",
+	"Options:":                              "Жауап нұсқалары:",
+	"Allow several answers":                 "Бірнеше нұсқаны таңдауға болады",
+	"Closes (optional):":                    "Жабылу уақыты (міндетті емес):",
+	"Vote":                                  "Дауыс беру",
+	"Voters: %d":                            "Дауыс бергендер: %d",
+	"Log in to vote.":                       "Дауыс беру үшін кіріңіз.",
+	"Poll closed: %s":                       "Сауалнама жабылды: %s",
+	"Poll closes: %s":                       "Сауалнама жабылады: %s",
+	"Fill in from %d to %d of these fields": "%d-ден %d-ге дейін өрісті толтырыңыз",
+	"These fields must not repeat":          "Мәндер қайталанбауы керек",
+	"Enter a valid date and time":           "Дұрыс күн мен уақытты енгізіңіз",
+	"The poll must close in the future":     "Сауалнама болашақта жабылуы керек",
+	"This poll is closed":                   "Сауалнама жабық",
+	"You have already voted":                "Сіз дауыс беріп қойдыңыз",
+	"Choose an option":                      "Нұсқаны таңдаңыз",
+	"Choose only one option":                "Тек бір нұсқаны таңдаңыз",
+	"Your vote has been counted!":           "Дауысыңыз есептелді!",
+
+	// Сохранённое.
+	"Saved":               "Сақталғандар",
+	"All":                 "Барлығы",
+	"Unsave":              "Сақталғандардан алып тастау",
+	"No collection":       "Жинақсыз",
+	"Move":                "Ауыстыру",
+	"comment by":          "пікір авторы",
+	"Nothing saved yet.":  "Сіз әзірге ештеңе сақтамадыңыз.",
+	"Delete collection":   "Жинақты жою",
+	"New collection":      "Жаңа жинақ",
+	"Collection name:":    "Жинақ атауы:",
+	"Create":              "Құру",
+	"Saved!":              "Сақталды!",
+	"Removed from saved.": "Сақталғандардан алынды.",
+	"Collection deleted.": "Жинақ жойылды.",
+	"You already have a collection with this name": "Сізде мұндай атаулы жинақ бар",
+
+	// Поиск.
+	`"exact phrase" -exclude author:name`: `"нақты тіркес" -алып_тастау author:аты`,
+	"Find":                                "Табу",
+	"Found: %d":                           "Табылды: %d",
+	"← Back":                              "← Артқа",
+	"Next →":                              "Келесі →",
+	"Nothing found.":                      "Ештеңе табылмады.",
+
+	// Сообщения после действий.
+	"Snippet successfully created!":              "Жазба жарияланды!",
+	"Snippet expiry updated!":                    "Жазбаның мерзімі өзгертілді!",
+	"Your signup was successful. Please log in.": "Тіркелу сәтті өтті. Енді кіріңіз.",
+	"You've been logged out successfully!":       "Сіз аккаунттан шықтыңыз.",
+	"Comment added!":                             "Пікір қосылды!",
+
+	// Ошибки в формах.
+	"This field cannot be blank":                                        "Бұл өріс бос болмауы керек",
+	"This field is too long (maximum is %d characters)":                 "Мән тым ұзын (ең көбі %d таңба)",
+	"This field is too short (minimum is %d characters)":                "Мән тым қысқа (кемінде %d таңба)",
+	"This field is invalid":                                             "Жарамсыз мән",
+	"This field must be a whole number between %d and %d":               "Бүтін сан енгізіңіз (%d – %d)",
+	"Permanent posts are not allowed":                                   "Мерзімсіз жазбаларға рұқсат жоқ",
+	"Address is already in use":                                         "Бұл мекенжай бұрыннан тіркелген",
+	"Username is already taken":                                         "Бұл пайдаланушы аты бос емес",
+	"This username is reserved":                                         "Бұл пайдаланушы аты сақталған",
+	"Use Latin letters, digits and underscores, starting with a letter": "Латын әріптерін, сандарды және астын сызуды қолданыңыз; бірінші таңба әріп болуы керек",
+	"Email or Password is incorrect":                                    "Email немесе құпиясөз қате",
+	"Unknown time zone":                                                 "Белгісіз уақыт белдеуі",
+	"You can attach at most %d files":                                   "Ең көбі %d файл тіркеуге болады",
+	"%s is too large (maximum is %d MB)":                                "%s: файл тым үлкен (ең көбі %d МБ)",
+	"%s: only JPEG, PNG and PDF files are allowed":                      "%s: тек JPEG, PNG және PDF файлдарына рұқсат етілген",
+	"%s: the image is too large (maximum is %d megapixels)":             "%s: сурет тым үлкен (ең көбі %d мегапиксель)",
+	"%s: the image is damaged":                                          "%s: сурет бүлінген",
+	"%s: only JPEG and PNG images are allowed":                          "%s: тек JPEG және PNG суреттеріне рұқсат етілген",
+}
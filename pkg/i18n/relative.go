@@ -0,0 +1,91 @@
+package i18n
+
+import (
+	"fmt"
+	"time"
+)
+
+// Формы множественного числа. В русском их три: "1 минуту", "2 минуты",
+// "5 минут"; в английском две; в казахском существительное после числа
+// не меняется.
+const (
+	one = iota
+	few
+	many
+)
+
+// units - фразы "N единиц назад" для каждой формы множественного числа.
+var units = map[Locale]map[string][3]string{
+	English: {
+		"minute": {"%d minute ago", "%d minutes ago", "%d minutes ago"},
+		"hour":   {"%d hour ago", "%d hours ago", "%d hours ago"},
+		"day":    {"%d day ago", "%d days ago", "%d days ago"},
+		"month":  {"%d month ago", "%d months ago", "%d months ago"},
+		"year":   {"%d year ago", "%d years ago", "%d years ago"},
+	},
+	Russian: {
+		"minute": {"%d минуту назад", "%d минуты назад", "%d минут назад"},
+		"hour":   {"%d час назад", "%d часа назад", "%d часов назад"},
+		"day":    {"%d день назад", "%d дня назад", "%d дней назад"},
+		"month":  {"%d месяц назад", "%d месяца назад", "%d месяцев назад"},
+		"year":   {"%d год назад", "%d года назад", "%d лет назад"},
+	},
+	Kazakh: {
+		"minute": {"%d минут бұрын", "%d минут бұрын", "%d минут бұрын"},
+		"hour":   {"%d сағат бұрын", "%d сағат бұрын", "%d сағат бұрын"},
+		"day":    {"%d күн бұрын", "%d күн бұрын", "%d күн бұрын"},
+		"month":  {"%d ай бұрын", "%d ай бұрын", "%d ай бұрын"},
+		"year":   {"%d жыл бұрын", "%d жыл бұрын", "%d жыл бұрын"},
+	},
+}
+
+// pluralForm выбирает форму множественного числа для n по правилам CLDR.
+func pluralForm(l Locale, n int) int {
+	switch l {
+	case Russian:
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return one
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return few
+		}
+		return many
+	default:
+		if n == 1 {
+			return one
+		}
+		return many
+	}
+}
+
+// Ago описывает, сколько времени прошло: "только что", "3 часа назад",
+// "2 года назад". Отрицательный промежуток (часы сервера и базы немного
+// расходятся) считается нулевым.
+func (p *Printer) Ago(d time.Duration) string {
+	const (
+		day   = 24 * time.Hour
+		month = 30 * day
+		year  = 365 * day
+	)
+	var unit string
+	var n int
+	switch {
+	case d < time.Minute:
+		return p.Sprintf("just now")
+	case d < time.Hour:
+		unit, n = "minute", int(d/time.Minute)
+	case d < day:
+		unit, n = "hour", int(d/time.Hour)
+	case d < month:
+		unit, n = "day", int(d/day)
+	case d < year:
+		unit, n = "month", int(d/month)
+	default:
+		unit, n = "year", int(d/year)
+	}
+	forms, ok := units[p.locale]
+	if !ok {
+		forms = units[English]
+	}
+	return fmt.Sprintf(forms[unit][pluralForm(p.locale, n)], n)
+}
@@ -0,0 +1,184 @@
+package i18n
+
+// ru - русский каталог. Числа по возможности выносятся в конец фразы
+// ("символов: %d"), чтобы не согласовывать с ними существительные.
+var ru = map[string]string{
+	// Навигация и общие элементы страниц.
+	"Home":                 "На главную",
+	"Search":               "Поиск",
+	"Publish":              "Опубликовать",
+	"Log out":              "Выйти",
+	"Sign up":              "Регистрация",
+	"Log in":               "Войти",
+	"Created by %s in %d":  "Создано командой %s в %d году",
+	"Home page":            "Домашняя страница",
+	"Latest snippets":      "Последние заметки",
+	"Title":                "Заголовок",
+	"Created":              "Создан",
+	"Nothing here... yet!": "Здесь ничего нет... пока что!",
+	"just now":             "только что",
+	"Profile":              "Профиль",
+
+	// Заметки.
+	"Create a New Snippet": "Новая заметка",
+	"Title:":               "Заголовок:",
+	"Content:":             "Содержание:",
+	"Preview:":             "Предпросмотр:",
+	"Attachments:":         "Вложения:",
+	"Delete in (days):":    "Удалить через (дней):",
+	"Never":                "Бессрочно",
+	"Publish snippet":      "Опубликовать заметку",
+	"Snippet #%d":          "Заметка #%d",
+	"Created: %s":          "Создан: %s",
+	"Expires: never":       "Срок: бессрочно",
+	"Expires: %s":          "Срок: %s",
+	"Change expiry":        "Изменить срок",
+	"Score:":               "Рейтинг:",
+	"Upvote":               "Плюс",
+	"Downvote":             "Минус",
+	"Remove vote":          "Отменить голос",
+	"Comments":             "Комментарии",
+	"No comments yet.":     "Комментариев пока нет.",
+	"Comment:":             "Комментарий:",
+	"Add comment":          "Отправить комментарий",
+
+	// Вход и регистрация.
+	"Name:":     "Имя:",
+	"Email:":    "Email:",
+	"Password:": "Пароль:",
+	"Username:": "Имя пользователя:",
+	"Latin letters, digits and underscores. Your profile address and @mentions use the username.": "Латинские буквы, цифры и подчёркивание. Имя пользователя используется в адресе профиля и в @упоминаниях.",
+
+	// Профиль.
+	"Karma: %d":     "Карма: %d",
+	"Member since:": "С нами с:",
+	"Snippets":      "Заметки",
+	"Snippet":       "Заметка",
+	"Comment":       "Комментарий",
+
+	// Настройки.
+	"Settings":                               "Настройки",
+	"Time zone:":                             "Часовой пояс:",
+	"Leave empty to use the site time zone.": "Оставьте пустым, чтобы использовать часовой пояс сайта.",
+	"Save":                                   "Сохранить",
+	"About me:":                              "О себе:",
+	"Avatar:":                                "Аватар:",
+	"Remove avatar":                          "Удалить аватар",
+	"Settings saved!":                        "Настройки сохранены!",
+
+	// Уведомления.
+	"Notifications":                    "Уведомления",
+	"Mark all as read":                 "Отметить все прочитанными",
+	"Mark as read":                     "Прочитано",
+	"No notifications yet.":            "Уведомлений пока нет.",
+	"Notification settings":            "Настройки уведомлений",
+	"Event":                            "Событие",
+	"On the site":                      "На сайте",
+	"By email":                         "По почте",
+	"Comments on my snippets":          "Комментарии к моим заметкам",
+	"Mentions":                         "Упоминания",
+	"Upvotes":                          "Голоса «за»",
+	"Someone":                          "Кто-то",
+	"(deleted)":                        "(удалено)",
+	"%s commented on your snippet: %s": "%s прокомментировал(а) вашу заметку: %s",
+	"%s mentioned you: %s":             "%s упомянул(а) вас: %s",
+	"%s upvoted your snippet: %s":      "%s проголосовал(а) за вашу заметку: %s",
+	"You can choose which notifications to receive by email at %s": "Выбрать, какие уведомления получать по почте, можно здесь: %s",
+	"Notification settings saved!":                                 "Настройки уведомлений сохранены!",
+
+	// Теги.
+	"Snippets tagged #%s": "Заметки с тегом #%s",
+
+	// Личные сообщения.
+	"Messages":                             "Сообщения",
+	"New message":                          "Новое сообщение",
+	"No messages yet.":                     "Сообщений пока нет.",
+	"Blocked users":                        "Заблокированные пользователи",
+	"Block":                                "Заблокировать",
+	"Unblock":                              "Разблокировать",
+	"Send a message":                       "Написать сообщение",
+	"To (usernames, separated by commas):": "Кому (имена пользователей через запятую):",
+	"Message:":                             "Сообщение:",
+	"Send":                                 "Отправить",
+	"Conversation":                         "Переписка",
+	"Reply:":                               "Ответ:",
+	"User blocked.":                        "Пользователь заблокирован.",
+	"User unblocked.":                      "Пользователь разблокирован.",
+	"Unknown user: %s":                     "Нет такого пользователя: %s",
+	"Add at least one other user":          "Добавьте хотя бы одного другого пользователя",
+	"You can message at most %d users at once":                      "Написать можно сразу не более чем %d пользователям",
+	"%s doesn't accept messages from you":                           "%s не принимает от вас сообщения",
+	"Someone in this conversation doesn't accept messages from you": "Кто-то из участников переписки не принимает от вас сообщения",
+
+	// Опросы.
+	"Add a poll":                            "Добавить опрос",
+	"Options:":                              "Варианты ответа:",
+	"Allow several answers":                 "Можно выбрать несколько вариантов",
+	"Closes (optional):":                    "Закрыть (необязательно):",
+	"Vote":                                  "Проголосовать",
+	"Voters: %d":                            "Проголосовало: %d",
+	"Log in to vote.":                       "Войдите, чтобы проголосовать.",
+	"Poll closed: %s":                       "Опрос закрыт: %s",
+	"Poll closes: %s":                       "Опрос закроется: %s",
+	"Fill in from %d to %d of these fields": "Заполните от %d до %d полей",
+	"These fields must not repeat":          "Значения не должны повторяться",
+	"Enter a valid date and time":           "Укажите корректные дату и время",
+	"The poll must close in the future":     "Время закрытия опроса должно быть в будущем",
+	"This poll is closed":                   "Опрос закрыт",
+	"You have already voted":                "Вы уже проголосовали",
+	"Choose an option":                      "Выберите вариант",
+	"Choose only one option":                "Выберите только один вариант",
+	"Your vote has been counted!":           "Ваш голос учтён!",
+
+	// Сохранённое.
+	"Saved":               "Сохранённое",
+	"All":                 "Всё",
+	"Unsave":              "Убрать из сохранённого",
+	"No collection":       "Без коллекции",
+	"Move":                "Переложить",
+	"comment by":          "комментарий от",
+	"Nothing saved yet.":  "Вы пока ничего не сохранили.",
+	"Delete collection":   "Удалить коллекцию",
+	"New collection":      "Новая коллекция",
+	"Collection name:":    "Название коллекции:",
+	"Create":              "Создать",
+	"Saved!":              "Сохранено!",
+	"Removed from saved.": "Убрано из сохранённого.",
+	"Collection deleted.": "Коллекция удалена.",
+	"You already have a collection with this name": "У вас уже есть коллекция с таким названием",
+
+	// Поиск.
+	`"exact phrase" -exclude author:name`: `"точная фраза" -исключить author:имя`,
+	"Find":                                "Найти",
+	"Found: %d":                           "Найдено: %d",
+	"← Back":                              "← Назад",
+	"Next →":                              "Дальше →",
+	"Nothing found.":                      "Ничего не найдено.",
+
+	// Сообщения после действий.
+	"Snippet successfully created!":              "Заметка опубликована!",
+	"Snippet expiry updated!":                    "Срок заметки изменён!",
+	"Your signup was successful. Please log in.": "Регистрация прошла успешно. Теперь войдите.",
+	"You've been logged out successfully!":       "Вы вышли из аккаунта.",
+	"Comment added!":                             "Комментарий добавлен!",
+
+	// Ошибки в формах.
+	"This field cannot be blank":                                        "Это поле не может быть пустым",
+	"This field is too long (maximum is %d characters)":                 "Слишком длинное значение (максимум символов: %d)",
+	"This field is too short (minimum is %d characters)":                "Слишком короткое значение (минимум символов: %d)",
+	"This field is invalid":                                             "Недопустимое значение",
+	"This field must be a whole number between %d and %d":               "Введите целое число от %d до %d",
+	"Permanent posts are not allowed":                                   "Бессрочные заметки запрещены",
+	"Address is already in use":                                         "Этот адрес уже используется",
+	"Username is already taken":                                         "Это имя пользователя уже занято",
+	"This username is reserved":                                         "Это имя пользователя зарезервировано",
+	"Use Latin letters, digits and underscores, starting with a letter": "Используйте латинские буквы, цифры и подчёркивание; первой должна быть буква",
+	"Email or Password is incorrect":                                    "Неверный email или пароль",
+	"Unknown time zone":                                                 "Неизвестный часовой пояс",
+	"You can attach at most %d files":                                   "Можно прикрепить не больше файлов: %d",
+	"%s is too large (maximum is %d MB)":                                "%s: файл слишком большой (максимум %d МБ)",
+	"%s: only JPEG, PNG and PDF files are allowed":                      "%s: разрешены только файлы JPEG, PNG и PDF",
+	"%s: the image is too large (maximum is %d megapixels)":             "%s: изображение слишком большое (максимум %d мегапикселей)",
+	"%s: the image is damaged":                                          "%s: изображение повреждено",
+	"%s: only JPEG and PNG images are allowed":                          "%s: разрешены только изображения JPEG и PNG",
+}
@@ -0,0 +1,84 @@
+// Package mail отправляет письма. Sender - интерфейс отправки, SMTP - его
+// реализация поверх net/smtp.
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message - простое текстовое письмо одному получателю.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письма.
+type Sender interface {
+	Send(Message) error
+}
+
+// SMTP отправляет письма через SMTP-сервер. Если Username пуст, сервер
+// используется без аутентификации (например, локальный relay).
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	// From - адрес отправителя, можно с именем: "Qogam <noreply@example.com>".
+	From string
+}
+
+func (s *SMTP) Send(m Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := strings.Cut(s.Addr, ":")
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	data, err := m.Bytes(s.From, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, auth, envelope(s.From), []string{envelope(m.To)}, data)
+}
+
+// envelope возвращает голый адрес из "Имя <адрес>" для команд SMTP.
+func envelope(addr string) string {
+	if i := strings.LastIndex(addr, "<"); i >= 0 {
+		return strings.TrimSuffix(addr[i+1:], ">")
+	}
+	return addr
+}
+
+// Bytes собирает письмо в формате RFC 5322. Тема кодируется по RFC 2047,
+// текст - quoted-printable, поэтому в письме может быть любой текст в UTF-8.
+// Переводы строк в адресах и теме запрещены: иначе через них можно было бы
+// добавить в письмо свои заголовки.
+func (m Message) Bytes(from string, date time.Time) ([]byte, error) {
+	for _, h := range []string{from, m.To, m.Subject} {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, fmt.Errorf("mail: line break in header %q", h)
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(strings.ReplaceAll(m.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
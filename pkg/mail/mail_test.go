@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+	"time"
+)
+
+func TestMessageBytes(t *testing.T) {
+	m := Message{
+		To:      "alice@example.com",
+		Subject: "Новый комментарий",
+		Body:    "Bob прокомментировал вашу заметку.\nhttps://example.com/snippet/1",
+	}
+	date := time.Date(2020, 12, 17, 10, 0, 0, 0, time.UTC)
+	data, err := m.Bytes("Qogam <noreply@example.com>", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The result must be a well-formed message that decodes back to the input.
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != m.Subject {
+		t.Errorf("want subject %q; got %q", m.Subject, subject)
+	}
+	if got := msg.Header.Get("To"); got != m.To {
+		t.Errorf("want To %q; got %q", m.To, got)
+	}
+	if got, _ := msg.Header.Date(); !got.Equal(date) {
+		t.Errorf("want date %v; got %v", date, got)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Bob прокомментировал вашу заметку.\r\nhttps://example.com/snippet/1"; string(body) != want {
+		t.Errorf("want body %q; got %q", want, body)
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+	}{
+		{"To", Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Hi"}},
+		{"Subject", Message{To: "alice@example.com", Subject: "Hi\nBcc: eve@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.m.Bytes("noreply@example.com", time.Now()); err == nil {
+				t.Error("want an error for a line break in a header")
+			}
+		})
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	tests := map[string]string{
+		"noreply@example.com":         "noreply@example.com",
+		"Qogam <noreply@example.com>": "noreply@example.com",
+	}
+	for in, want := range tests {
+		if got := envelope(in); got != want {
+			t.Errorf("envelope(%q): want %q; got %q", in, want, got)
+		}
+	}
+}
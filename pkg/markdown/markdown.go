@@ -0,0 +1,50 @@
+// Package markdown превращает текст заметок в Markdown в безопасный HTML.
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// Goldmark по умолчанию не пропускает сырой HTML из текста, но ссылки вида
+// javascript: и атрибуты всё равно нужно проверить, поэтому результат
+// дополнительно проходит через санитайзер.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, tokens{}),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// Классы вида language-go на <code> нужны для подсветки синтаксиса
+// на стороне браузера. Остальные классы вырезаются.
+var languageClass = regexp.MustCompile(`^language-[\w+#.-]+$`)
+
+// Ссылки на профили и теги (см. tokens.go) различаются классами.
+var tokenClass = regexp.MustCompile(`^(mention|tag)$`)
+
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(languageClass).OnElements("code")
+	p.AllowAttrs("class").Matching(tokenClass).OnElements("a")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Render преобразует Markdown в HTML и очищает его от всего, что может
+// выполнить скрипт: тегов <script>, обработчиков событий, javascript:-ссылок.
+// При ошибке разбора возвращается экранированный исходный текст.
+func Render(src string) template.HTML {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(src), &buf); err != nil {
+		return template.HTML(template.HTMLEscapeString(src))
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes()))
+}
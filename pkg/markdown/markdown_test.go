@@ -0,0 +1,84 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		want     []string
+		dontWant []string
+	}{
+		{"Heading", "# Title", []string{"<h1"}, nil},
+		{"List", "- one\n- two", []string{"<ul>", "<li>one</li>"}, nil},
+		{"Link", "[docs](https://go.dev)", []string{`href="https://go.dev"`, `rel="nofollow noopener"`}, nil},
+		{"Code block", "```go\nfmt.Println(1)\n```", []string{`<code class="language-go">`}, nil},
+		{"Table", "| a | b |\n|---|---|\n| 1 | 2 |", []string{"<table>", "<td>1</td>"}, nil},
+		{"Script tag", "<script>alert(1)</script>", nil, []string{"<script"}},
+		{"Event handler", `<img src="x" onerror="alert(1)">`, nil, []string{"onerror"}},
+		{"Javascript link", "[click](javascript:alert(1))", nil, []string{"javascript:"}},
+		{"Foreign class", "<code class=\"evil\">x</code>", nil, []string{"evil"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Render(tt.src))
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("want output to contain %q; got %q", w, got)
+				}
+			}
+			for _, w := range tt.dontWant {
+				if strings.Contains(got, w) {
+					t.Errorf("want output not to contain %q; got %q", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          string
+		wantMentions []string
+		wantTags     []string
+		wantHTML     []string
+	}{
+		{"Mention and tags", "hi @Bob, #Go and #Қазақ!", []string{"bob"}, []string{"go", "қазақ"}, []string{
+			`<a href="/u/bob" class="mention" rel="nofollow">@Bob</a>`,
+			`<a href="/t/go" class="tag" rel="nofollow">#Go</a>`,
+			`href="/t/%D2%9B%D0%B0%D0%B7%D0%B0%D2%9B"`,
+		}},
+		{"Repeated tokens", "#go #GO @bob @Bob", []string{"bob"}, []string{"go"}, nil},
+		{"Emphasis", "**@bob** _#go_", []string{"bob"}, []string{"go"}, nil},
+		{"Issue number", "fixes #12", nil, nil, []string{"fixes #12"}},
+		{"Too short name", "@al", nil, nil, nil},
+		{"Email", "write to bob@example.com", nil, nil, nil},
+		{"URLs", "https://go.dev/#intro www.example.com/@bob", nil, nil, []string{`href="https://go.dev/#intro"`}},
+		{"Inside a word", "a#b c@def", nil, nil, nil},
+		{"Code span", "`@bob #go`", nil, nil, []string{"<code>@bob #go</code>"}},
+		{"Code block", "```\n@bob #go\n```", nil, nil, []string{"@bob #go\n</code>"}},
+		{"Link text", "[#go](https://go.dev)", nil, nil, []string{`target="_blank">#go</a>`}},
+		{"Heading", "# Title", nil, nil, []string{"<h1>Title</h1>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mentions, tags := Extract(tt.src)
+			if strings.Join(mentions, ",") != strings.Join(tt.wantMentions, ",") {
+				t.Errorf("want mentions %v; got %v", tt.wantMentions, mentions)
+			}
+			if strings.Join(tags, ",") != strings.Join(tt.wantTags, ",") {
+				t.Errorf("want tags %v; got %v", tt.wantTags, tags)
+			}
+			got := string(Render(tt.src))
+			for _, w := range tt.wantHTML {
+				if !strings.Contains(got, w) {
+					t.Errorf("want output to contain %q; got %q", w, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package markdown
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"golang.org/x/text/unicode/norm"
+	"golangify.com/snippetbox/pkg/username"
+)
+
+// MaxTagLength - предельная длина тега в символах, без знака #.
+const MaxTagLength = 50
+
+// TokenKind - вид токена: упоминание пользователя или тег.
+type TokenKind int
+
+const (
+	// Mention - @username, ссылка на профиль пользователя.
+	Mention TokenKind = iota
+	// Tag - #тег, ссылка на страницу тега.
+	Tag
+)
+
+// KindToken - тип узла Token в дереве goldmark.
+var KindToken = ast.NewNodeKind("Token")
+
+// Token - @упоминание или #тег в тексте. Value - нормализованное значение
+// (имя пользователя или тег в нижнем регистре), Raw - текст так, как его
+// написал автор, вместе с @ или #.
+type Token struct {
+	ast.BaseInline
+	TokenKind TokenKind
+	Value     string
+	Raw       string
+}
+
+// Kind реализует ast.Node.
+func (t *Token) Kind() ast.NodeKind {
+	return KindToken
+}
+
+// Dump реализует ast.Node.
+func (t *Token) Dump(source []byte, level int) {
+	ast.DumpHelper(t, source, level, map[string]string{"Value": t.Value}, nil)
+}
+
+// tokenParser находит @упоминания и #теги. Он работает как обычный
+// inline-парсер goldmark, поэтому не видит содержимого блоков кода,
+// `кода в строке`, HTML и адресов, которые расширение Linkify уже
+// превратило в ссылки.
+type tokenParser struct{}
+
+func (tokenParser) Trigger() []byte {
+	return []byte{'@', '#'}
+}
+
+func (tokenParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	// Знак внутри слова или адреса (bob@example.com, page#anchor,
+	// example.com/@bob) не начинает токен.
+	prev := block.PrecendingCharacter()
+	if !tokenBoundary(prev) {
+		return nil
+	}
+	line, _ := block.PeekLine()
+	// В _#go_ закрывающее подчёркивание - разметка, а не часть тега.
+	if prev == '_' {
+		line = trimEmphasis(line)
+	}
+	var t *Token
+	switch line[0] {
+	case '@':
+		t = parseMention(line)
+	case '#':
+		t = parseTag(line)
+	}
+	if t == nil {
+		return nil
+	}
+	block.Advance(len(t.Raw))
+	return t
+}
+
+// tokenBoundary сообщает, может ли токен начинаться после символа r.
+// Подчёркивание здесь - разметка курсива (_#go_), а не часть слова.
+func tokenBoundary(r rune) bool {
+	if r != '_' && isWordRune(r) {
+		return false
+	}
+	return !strings.ContainsRune("@#/&.-+=\\", r)
+}
+
+// trimEmphasis обрезает line по концу первого слова без подчёркиваний
+// в его конце.
+func trimEmphasis(line []byte) []byte {
+	n := 1
+	for n < len(line) {
+		r, size := utf8.DecodeRune(line[n:])
+		if !isWordRune(r) {
+			break
+		}
+		n += size
+	}
+	for n > 1 && line[n-1] == '_' {
+		n--
+	}
+	return line[:n]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// parseMention разбирает @username в начале line. Проверяется только
+// форма имени: зарезервированные имена и заглушки user<id> у старых
+// аккаунтов username.Validate отклоняет при регистрации, но упомянуть
+// существующего пользователя с таким именем можно.
+func parseMention(line []byte) *Token {
+	n := 1
+	for n < len(line) && line[n] < utf8.RuneSelf && isWordRune(rune(line[n])) {
+		n++
+	}
+	// Имя, продолжающееся не-ASCII буквой, не упоминание: @bobжан.
+	if n < len(line) {
+		if r, _ := utf8.DecodeRune(line[n:]); isWordRune(r) {
+			return nil
+		}
+	}
+	name := strings.ToLower(string(line[1:n]))
+	if len(name) < username.MinLength || len(name) > username.MaxLength || !unicode.IsLetter(rune(name[0])) {
+		return nil
+	}
+	return &Token{TokenKind: Mention, Value: name, Raw: string(line[:n])}
+}
+
+// parseTag разбирает #тег в начале line. Тег состоит из букв, цифр и
+// подчёркиваний и содержит хотя бы одну букву, чтобы номера задач (#12)
+// не становились тегами.
+func parseTag(line []byte) *Token {
+	n, runes, letters := 1, 0, 0
+	for n < len(line) {
+		r, size := utf8.DecodeRune(line[n:])
+		if !isWordRune(r) {
+			break
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+		n += size
+		runes++
+	}
+	if letters == 0 || runes > MaxTagLength {
+		return nil
+	}
+	return &Token{TokenKind: Tag, Value: NormalizeTag(string(line[1:n])), Raw: string(line[:n])}
+}
+
+// NormalizeTag приводит тег к виду, в котором он хранится и сравнивается:
+// без ведущего #, в форме NFKC и в нижнем регистре.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(norm.NFKC.String(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+}
+
+// MentionURL и TagURL - адреса, на которые ведут токены.
+func MentionURL(name string) string { return "/u/" + url.PathEscape(name) }
+func TagURL(tag string) string      { return "/t/" + url.PathEscape(tag) }
+
+// tokenRenderer выводит токены ссылками. Внутри другой ссылки
+// ([#go](https://go.dev)) вложенная ссылка недопустима, и токен остаётся
+// текстом.
+type tokenRenderer struct{}
+
+func (tokenRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindToken, renderToken)
+}
+
+func renderToken(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	t := n.(*Token)
+	raw := util.EscapeHTML([]byte(t.Raw))
+	if insideLink(t) {
+		w.Write(raw)
+		return ast.WalkContinue, nil
+	}
+	href, class := MentionURL(t.Value), "mention"
+	if t.TokenKind == Tag {
+		href, class = TagURL(t.Value), "tag"
+	}
+	w.WriteString(`<a href="`)
+	w.Write(util.EscapeHTML([]byte(href)))
+	w.WriteString(`" class="` + class + `">`)
+	w.Write(raw)
+	w.WriteString("</a>")
+	return ast.WalkContinue, nil
+}
+
+func insideLink(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindLink || p.Kind() == ast.KindAutoLink {
+			return true
+		}
+	}
+	return false
+}
+
+// tokens - расширение goldmark с парсером и отрисовкой токенов.
+type tokens struct{}
+
+func (tokens) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(tokenParser{}, 500)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(tokenRenderer{}, 500)))
+}
+
+// Extract возвращает упоминания и теги из текста заметки без повторов, в
+// порядке появления. Учитываются те же токены, что становятся ссылками
+// при отрисовке.
+func Extract(src string) (mentions, tags []string) {
+	doc := md.Parser().Parse(text.NewReader([]byte(src)))
+	seen := map[TokenKind]map[string]bool{Mention: {}, Tag: {}}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		t, ok := n.(*Token)
+		if !entering || !ok || insideLink(t) || seen[t.TokenKind][t.Value] {
+			return ast.WalkContinue, nil
+		}
+		seen[t.TokenKind][t.Value] = true
+		if t.TokenKind == Mention {
+			mentions = append(mentions, t.Value)
+		} else {
+			tags = append(tags, t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return mentions, tags
+}
@@ -0,0 +1,122 @@
+// Package media проверяет и обрабатывает загруженные файлы: определяет их
+// настоящий тип по содержимому, удаляет метаданные из изображений и строит
+// миниатюры.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+// ErrUnsupportedType возвращается для файлов, тип которых не входит
+// в список разрешённых.
+var ErrUnsupportedType = errors.New("media: unsupported file type")
+
+// ErrTooManyPixels возвращается для изображений больше MaxPixels. Размер
+// файла тут ничего не гарантирует: PNG в несколько килобайт может заявить
+// 50000×50000 пикселей, и при декодировании под них выделятся гигабайты.
+var ErrTooManyPixels = errors.New("media: image has too many pixels")
+
+// MaxPixels - наибольшее число пикселей (ширина × высота) в загружаемом
+// изображении. 40 мегапикселей хватает фотографиям с любого телефона.
+const MaxPixels = 40_000_000
+
+// Разрешённые типы файлов.
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypePDF  = "application/pdf"
+)
+
+var extensions = map[string]string{
+	TypeJPEG: ".jpg",
+	TypePNG:  ".png",
+	TypePDF:  ".pdf",
+}
+
+// Sniff определяет тип файла по первым байтам содержимого, а не по имени
+// или заголовку Content-Type, которые присылает браузер.
+func Sniff(data []byte) (string, error) {
+	ct := http.DetectContentType(data)
+	if _, ok := extensions[ct]; !ok {
+		return "", ErrUnsupportedType
+	}
+	return ct, nil
+}
+
+// Extension возвращает расширение файла для разрешённого типа.
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// IsImage возвращает true для типов, для которых строятся миниатюры.
+func IsImage(contentType string) bool {
+	return contentType == TypeJPEG || contentType == TypePNG
+}
+
+// StripMetadata перекодирует изображение, отбрасывая EXIF и прочие
+// метаданные (в том числе координаты съёмки). Поворот из EXIF перед этим
+// применяется к пикселям, чтобы фото с телефона не легло на бок.
+// Размеры читаются из заголовка до декодирования, и изображения больше
+// MaxPixels отклоняются с ошибкой ErrTooManyPixels.
+func StripMetadata(data []byte, contentType string) ([]byte, image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if contentType == TypeJPEG {
+		img = orient(img, exifOrientation(data))
+	}
+	out, err := encode(img, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, img, nil
+}
+
+// Thumbnail уменьшает изображение так, чтобы оно поместилось в квадрат
+// size×size, сохраняя пропорции. Маленькие изображения не увеличиваются.
+func Thumbnail(img image.Image, size int, contentType string) ([]byte, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return encode(dst, contentType)
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case TypeJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case TypePNG:
+		err = png.Encode(&buf, img)
+	default:
+		return nil, ErrUnsupportedType
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
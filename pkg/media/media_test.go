@@ -0,0 +1,137 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	return img
+}
+
+// jpegWithOrientation encodes a JPEG and inserts an EXIF APP1 segment with
+// the given Orientation tag right after the SOI marker.
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestSniff(t *testing.T) {
+	var pngBuf bytes.Buffer
+	png.Encode(&pngBuf, testImage(2, 2))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{"PNG", pngBuf.Bytes(), TypePNG, nil},
+		{"PDF", []byte("%PDF-1.7\n..."), TypePDF, nil},
+		{"HTML disguised as image", []byte("<html><script>alert(1)</script>"), "", ErrUnsupportedType},
+		{"Plain text", []byte("hello"), "", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff(tt.data)
+			if got != tt.want || err != tt.wantErr {
+				t.Errorf("want %q, %v; got %q, %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	data := jpegWithOrientation(t, testImage(40, 20), 6)
+	if exifOrientation(data) != 6 {
+		t.Fatalf("test image has no orientation tag")
+	}
+
+	out, img, err := StripMetadata(data, TypeJPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Error("want EXIF to be removed")
+	}
+	// Orientation 6 means the camera was rotated, so width and height swap.
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Errorf("want 20x40 image; got %dx%d", b.Dx(), b.Dy())
+	}
+	if exifOrientation(out) != 1 {
+		t.Error("want no orientation in the output")
+	}
+}
+
+func TestStripMetadataTooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	// Claim 50000x50000 pixels in the IHDR chunk and fix up its checksum.
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 50000)
+	binary.BigEndian.PutUint32(data[20:], 50000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, _, err := StripMetadata(data, TypePNG); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("want ErrTooManyPixels; got %v", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h         int
+		wantW, wantH int
+	}{
+		{"Landscape", 800, 400, 100, 50},
+		{"Portrait", 300, 600, 50, 100},
+		{"Small", 40, 30, 40, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Thumbnail(testImage(tt.w, tt.h), 100, TypePNG)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("want %dx%d; got %dx%d", tt.wantW, tt.wantH, cfg.Width, cfg.Height)
+			}
+		})
+	}
+}
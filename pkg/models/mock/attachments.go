@@ -0,0 +1,40 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+var mockAttachment = &models.Attachment{
+	ID:           1,
+	SnippetID:    1,
+	UserID:       1,
+	Filename:     "pond.png",
+	ContentType:  "image/png",
+	Size:         4,
+	StorageKey:   "pond.png",
+	ThumbnailKey: "pond-thumb.png",
+	Created:      time.Now(),
+}
+
+type AttachmentModel struct{}
+
+func (m *AttachmentModel) Insert(a *models.Attachment) (int, error) {
+	return 2, nil
+}
+func (m *AttachmentModel) Get(id int) (*models.Attachment, error) {
+	switch id {
+	case 1:
+		return mockAttachment, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+func (m *AttachmentModel) ForSnippet(snippetID int) ([]*models.Attachment, error) {
+	switch snippetID {
+	case 1:
+		return []*models.Attachment{mockAttachment}, nil
+	default:
+		return nil, nil
+	}
+}
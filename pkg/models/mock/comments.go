@@ -0,0 +1,39 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+var mockComment = &models.Comment{
+	ID:             1,
+	SnippetID:      1,
+	UserID:         1,
+	Author:         "Alice",
+	AuthorUsername: "alice",
+	SnippetTitle:   "An old silent pond",
+	Content:        "A frog jumps into the pond, splash! Silence again.",
+	Created:        time.Now(),
+}
+
+type CommentModel struct{}
+
+func (m *CommentModel) Insert(snippetID, userID int, content string) (int, error) {
+	return 2, nil
+}
+func (m *CommentModel) ForSnippet(snippetID int) ([]*models.Comment, error) {
+	switch snippetID {
+	case 1:
+		return []*models.Comment{mockComment}, nil
+	default:
+		return nil, nil
+	}
+}
+func (m *CommentModel) ByUser(userID, limit int) ([]*models.Comment, error) {
+	switch userID {
+	case 1:
+		return []*models.Comment{mockComment}, nil
+	default:
+		return nil, nil
+	}
+}
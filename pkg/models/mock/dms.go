@@ -0,0 +1,128 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+var mockConversation = &models.Conversation{
+	ID:          1,
+	LastMessage: "Nice haiku!",
+	Updated:     time.Now(),
+	Unread:      1,
+}
+
+var mockMessages = []*models.Message{
+	{ID: 1, ConversationID: 1, SenderID: 1, Sender: "Alice", SenderUsername: "alice", Content: "Hi Bob", Created: time.Now()},
+	{ID: 2, ConversationID: 1, SenderID: 2, Sender: "Bob", SenderUsername: "bob", Content: "Nice haiku!", Created: time.Now()},
+}
+
+// DMModel знает одну переписку Алисы (1) и Боба (2). Начатые переписки и
+// отправленные сообщения запоминаются в Started и Sent, блокировки - в
+// Blocked: ключ - кто заблокировал, значение - кого.
+type DMModel struct {
+	Started []*models.Message
+	Sent    []*models.Message
+	Read    []int
+	Blocked map[int][]int
+}
+
+func participant(p *models.Profile) models.Participant {
+	return models.Participant{ID: p.ID, Name: p.Name, Username: p.Username}
+}
+
+// conversation возвращает переписку глазами userID.
+func (m *DMModel) conversation(userID int) *models.Conversation {
+	c := *mockConversation
+	switch userID {
+	case 1:
+		c.Participants = []models.Participant{participant(mockBobProfile)}
+	case 2:
+		c.Participants = []models.Participant{participant(mockProfile)}
+		c.Unread = 0
+	}
+	return &c
+}
+
+func (m *DMModel) Find(userIDs []int) (int, error) {
+	if len(userIDs) == 2 && userIDs[0]+userIDs[1] == 3 && userIDs[0]*userIDs[1] == 2 {
+		return 1, nil
+	}
+	return 0, models.ErrNoRecord
+}
+func (m *DMModel) Start(senderID int, recipientIDs []int, content string) (int, error) {
+	m.Started = append(m.Started, &models.Message{ConversationID: 2, SenderID: senderID, Content: content})
+	return 2, nil
+}
+func (m *DMModel) Send(conversationID, senderID int, content string) (int, error) {
+	m.Sent = append(m.Sent, &models.Message{ConversationID: conversationID, SenderID: senderID, Content: content})
+	return 3, nil
+}
+func (m *DMModel) Get(conversationID, userID int) (*models.Conversation, error) {
+	if conversationID != 1 || (userID != 1 && userID != 2) {
+		return nil, models.ErrNoRecord
+	}
+	return m.conversation(userID), nil
+}
+func (m *DMModel) Conversations(userID, limit int) ([]*models.Conversation, error) {
+	if userID != 1 && userID != 2 {
+		return nil, nil
+	}
+	return []*models.Conversation{m.conversation(userID)}, nil
+}
+func (m *DMModel) Messages(conversationID, limit int) ([]*models.Message, error) {
+	if conversationID != 1 {
+		return nil, nil
+	}
+	return mockMessages, nil
+}
+func (m *DMModel) MarkRead(conversationID, userID int) error {
+	m.Read = append(m.Read, conversationID)
+	return nil
+}
+func (m *DMModel) Unread(userID int) (int, error) {
+	if userID == 1 {
+		return 1, nil
+	}
+	return 0, nil
+}
+func (m *DMModel) Block(userID, blockedID int) error {
+	if m.Blocked == nil {
+		m.Blocked = map[int][]int{}
+	}
+	m.Blocked[userID] = append(m.Blocked[userID], blockedID)
+	return nil
+}
+func (m *DMModel) Unblock(userID, blockedID int) error {
+	var kept []int
+	for _, id := range m.Blocked[userID] {
+		if id != blockedID {
+			kept = append(kept, id)
+		}
+	}
+	m.Blocked[userID] = kept
+	return nil
+}
+func (m *DMModel) Blocks(userID int) ([]models.Participant, error) {
+	var blocked []models.Participant
+	for _, id := range m.Blocked[userID] {
+		switch id {
+		case 1:
+			blocked = append(blocked, participant(mockProfile))
+		case 2:
+			blocked = append(blocked, participant(mockBobProfile))
+		}
+	}
+	return blocked, nil
+}
+func (m *DMModel) BlockedBy(senderID int, userIDs []int) ([]int, error) {
+	var ids []int
+	for _, userID := range userIDs {
+		for _, id := range m.Blocked[userID] {
+			if id == senderID {
+				ids = append(ids, userID)
+			}
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"context"
+	"errors"
+
+	"golangify.com/snippetbox/pkg/models/mysql"
+)
+
+// HealthModel изображает базу с полностью обновлённой схемой: её версия
+// всегда совпадает с последней встроенной миграцией. Down делает базу
+// недоступной.
+type HealthModel struct {
+	Down bool
+}
+
+func (m *HealthModel) Ping(ctx context.Context) error {
+	if m.Down {
+		return errors.New("dial tcp 127.0.0.1:3306: connect: connection refused")
+	}
+	return nil
+}
+
+func (m *HealthModel) SchemaVersion(ctx context.Context) (int, error) {
+	if m.Down {
+		return 0, errors.New("dial tcp 127.0.0.1:3306: connect: connection refused")
+	}
+	return mysql.LatestVersion()
+}
@@ -0,0 +1,80 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+var mockNotification = &models.Notification{
+	ID:            1,
+	UserID:        1,
+	Type:          models.NotificationReply,
+	ActorID:       2,
+	Actor:         "Bob",
+	ActorUsername: "bob",
+	SnippetID:     1,
+	SnippetTitle:  "An old silent pond",
+	CommentID:     1,
+	Created:       time.Now(),
+}
+
+// NotificationModel запоминает добавленные уведомления в Inserted, чтобы
+// тесты могли проверить, кого и о чём уведомили.
+type NotificationModel struct {
+	Inserted []*models.Notification
+	once     map[models.Notification]bool
+}
+
+func (m *NotificationModel) Insert(n *models.Notification) (int, error) {
+	m.Inserted = append(m.Inserted, n)
+	return 2, nil
+}
+func (m *NotificationModel) Once(userID int, notificationType string, actorID, snippetID int) (bool, error) {
+	key := models.Notification{UserID: userID, Type: notificationType, ActorID: actorID, SnippetID: snippetID}
+	if m.once[key] {
+		return false, nil
+	}
+	if m.once == nil {
+		m.once = map[models.Notification]bool{}
+	}
+	m.once[key] = true
+	return true, nil
+}
+func (m *NotificationModel) Latest(userID, limit int) ([]*models.Notification, error) {
+	switch userID {
+	case 1:
+		return []*models.Notification{mockNotification}, nil
+	default:
+		return nil, nil
+	}
+}
+func (m *NotificationModel) Unread(userID int) (int, error) {
+	switch userID {
+	case 1:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+func (m *NotificationModel) MarkRead(userID, id int) error {
+	return nil
+}
+func (m *NotificationModel) MarkAllRead(userID int) error {
+	return nil
+}
+
+// Preferences возвращает настройки по умолчанию, а пользователю 2 ещё и
+// включает письма об ответах.
+func (m *NotificationModel) Preferences(userID int) (map[string]models.NotificationPreference, error) {
+	prefs := map[string]models.NotificationPreference{}
+	for _, t := range models.NotificationTypes {
+		prefs[t] = models.DefaultNotificationPreference(t)
+	}
+	if userID == 2 {
+		prefs[models.NotificationReply] = models.NotificationPreference{Type: models.NotificationReply, InApp: true, Email: true}
+	}
+	return prefs, nil
+}
+func (m *NotificationModel) SetPreferences(userID int, prefs []models.NotificationPreference) error {
+	return nil
+}
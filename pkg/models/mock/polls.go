@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+// PollModel знает опрос у заметки 3, в котором Bob (2) уже выбрал первый
+// вариант. Closed закрывает этот опрос. Созданные опросы запоминаются в
+// Inserted, бюллетени - в Ballots. Если InsertErr задана, Insert
+// возвращает её.
+type PollModel struct {
+	Closed    bool
+	Inserted  []*models.Poll
+	Ballots   map[int][]int
+	InsertErr error
+}
+
+func (m *PollModel) Insert(snippetID int, multiple bool, closes time.Time, options []string) error {
+	if m.InsertErr != nil {
+		return m.InsertErr
+	}
+	p := &models.Poll{SnippetID: snippetID, Multiple: multiple, Closes: closes}
+	for _, text := range options {
+		p.Options = append(p.Options, &models.PollOption{Text: text})
+	}
+	m.Inserted = append(m.Inserted, p)
+	return nil
+}
+func (m *PollModel) Get(snippetID, userID int) (*models.Poll, error) {
+	if snippetID != 3 {
+		return nil, models.ErrNoRecord
+	}
+	p := &models.Poll{
+		SnippetID: 3,
+		Options: []*models.PollOption{
+			{ID: 1, Text: "Monday", Votes: 1},
+			{ID: 2, Text: "Friday"},
+		},
+		Voters: 1,
+	}
+	if m.Closed {
+		p.Closes = time.Now().Add(-time.Hour)
+	}
+	if userID == 2 {
+		p.Choices = []int{1}
+	}
+	for _, id := range m.Ballots[userID] {
+		p.Options[id-1].Votes++
+		p.Choices = append(p.Choices, id)
+	}
+	if len(m.Ballots[userID]) > 0 {
+		p.Voters++
+	}
+	return p, nil
+}
+func (m *PollModel) Vote(snippetID, userID int, optionIDs []int) error {
+	if userID == 2 || len(m.Ballots[userID]) > 0 {
+		return models.ErrAlreadyVoted
+	}
+	if m.Ballots == nil {
+		m.Ballots = map[int][]int{}
+	}
+	m.Ballots[userID] = optionIDs
+	return nil
+}
@@ -0,0 +1,53 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+var mockProfile = &models.Profile{
+	ID:       1,
+	Name:     "Alice",
+	Username: "alice",
+	Bio:      "Haiku enthusiast.",
+	Created:  time.Now(),
+	Karma:    3,
+}
+
+var mockBobProfile = &models.Profile{
+	ID:       2,
+	Name:     "Bob",
+	Username: "bob",
+	Created:  time.Now(),
+}
+
+type ProfileModel struct{}
+
+func (m *ProfileModel) Get(id int) (*models.Profile, error) {
+	switch id {
+	case 1:
+		return mockProfile, nil
+	case 2:
+		return mockBobProfile, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+func (m *ProfileModel) ByUsername(username string) (*models.Profile, error) {
+	switch username {
+	case "alice":
+		return mockProfile, nil
+	case "bob":
+		return mockBobProfile, nil
+	default:
+		return nil, models.ErrNoRecord
+	}
+}
+func (m *ProfileModel) Update(id int, bio, avatarKey string) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
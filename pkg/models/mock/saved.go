@@ -0,0 +1,162 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+	"time"
+)
+
+type savedEntry struct {
+	userID, snippetID, commentID, collectionID int
+	saved                                      time.Time
+}
+
+type collectionEntry struct {
+	userID int
+	models.Collection
+}
+
+// SavedModel хранит сохранённое и коллекции в памяти; тесты наполняют его
+// через Save и CreateCollection. Как и в MySQL, элементы, чья заметка или
+// комментарий пропали, не возвращаются, но и не удаляются.
+type SavedModel struct {
+	entries     []*savedEntry
+	collections []*collectionEntry
+}
+
+func (m *SavedModel) find(userID, snippetID, commentID int) int {
+	for i, e := range m.entries {
+		if e.userID == userID && e.snippetID == snippetID && e.commentID == commentID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *SavedModel) Save(userID, snippetID, commentID, collectionID int) error {
+	if i := m.find(userID, snippetID, commentID); i >= 0 {
+		m.entries[i].collectionID = collectionID
+		return nil
+	}
+	m.entries = append(m.entries, &savedEntry{userID, snippetID, commentID, collectionID, time.Now()})
+	return nil
+}
+func (m *SavedModel) Unsave(userID, snippetID, commentID int) error {
+	if i := m.find(userID, snippetID, commentID); i >= 0 {
+		m.entries = append(m.entries[:i], m.entries[i+1:]...)
+	}
+	return nil
+}
+func (m *SavedModel) Saved(userID, snippetID int) (map[int]bool, error) {
+	saved := map[int]bool{}
+	for _, e := range m.entries {
+		if e.userID == userID && e.snippetID == snippetID {
+			saved[e.commentID] = true
+		}
+	}
+	return saved, nil
+}
+
+// item собирает элемент из текущих данных заметки или комментария и
+// возвращает nil, если их уже нет.
+func (m *SavedModel) item(id int, e *savedEntry) *models.SavedItem {
+	s, err := (&SnippetModel{}).Get(e.snippetID)
+	if err != nil {
+		return nil
+	}
+	author, _ := (&ProfileModel{}).Get(s.UserID)
+	item := &models.SavedItem{
+		ID:           id,
+		SnippetID:    s.ID,
+		SnippetTitle: s.Title,
+		CommentID:    e.commentID,
+		Content:      s.Content,
+		CollectionID: e.collectionID,
+		Saved:        e.saved,
+	}
+	if author != nil {
+		item.Author, item.AuthorUsername = author.Name, author.Username
+	}
+	if e.commentID != 0 {
+		comments, _ := (&CommentModel{}).ForSnippet(e.snippetID)
+		item.Content = ""
+		for _, c := range comments {
+			if c.ID == e.commentID {
+				item.Content, item.Author, item.AuthorUsername = c.Content, c.Author, c.AuthorUsername
+			}
+		}
+		if item.Content == "" {
+			return nil
+		}
+	}
+	for _, c := range m.collections {
+		if c.ID == e.collectionID {
+			item.Collection = c.Name
+		}
+	}
+	return item
+}
+
+func (m *SavedModel) Items(userID, collectionID, limit, offset int) ([]*models.SavedItem, error) {
+	var items []*models.SavedItem
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		e := m.entries[i]
+		if e.userID != userID || (collectionID != 0 && e.collectionID != collectionID) {
+			continue
+		}
+		if item := m.item(i+1, e); item != nil {
+			items = append(items, item)
+		}
+	}
+	if offset >= len(items) {
+		return nil, nil
+	}
+	items = items[offset:]
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+func (m *SavedModel) Collections(userID int) ([]*models.Collection, error) {
+	var collections []*models.Collection
+	for _, c := range m.collections {
+		if c.userID != userID {
+			continue
+		}
+		collection := c.Collection
+		for i, e := range m.entries {
+			if e.userID == userID && e.collectionID == c.ID && m.item(i+1, e) != nil {
+				collection.Items++
+			}
+		}
+		collections = append(collections, &collection)
+	}
+	return collections, nil
+}
+func (m *SavedModel) CreateCollection(userID int, name string) (int, error) {
+	for _, c := range m.collections {
+		if c.userID == userID && c.Name == name {
+			return 0, models.ErrDuplicateCollection
+		}
+	}
+	id := 1
+	if n := len(m.collections); n > 0 {
+		id = m.collections[n-1].ID + 1
+	}
+	m.collections = append(m.collections, &collectionEntry{userID, models.Collection{ID: id, Name: name}})
+	return id, nil
+}
+func (m *SavedModel) DeleteCollection(userID, id int) error {
+	for i, c := range m.collections {
+		if c.ID != id || c.userID != userID {
+			continue
+		}
+		m.collections = append(m.collections[:i], m.collections[i+1:]...)
+		for _, e := range m.entries {
+			if e.userID == userID && e.collectionID == id {
+				e.collectionID = 0
+			}
+		}
+		return nil
+	}
+	return models.ErrNoRecord
+}
@@ -7,21 +7,44 @@ import (
 
 var mockSnippet = &models.Snippet{
 	ID:      1,
+	UserID:  1,
 	Title:   "An old silent pond",
 	Content: "An old silent pond...",
 	Created: time.Now(),
 	Expires: time.Now(),
 }
 
-type SnippetModel struct{}
+// mockForeignSnippet написана другим пользователем: за неё Alice может
+// голосовать.
+var mockForeignSnippet = &models.Snippet{
+	ID:      3,
+	UserID:  2,
+	Title:   "Autumn moonlight",
+	Content: "Autumn moonlight - a worm digs silently into the chestnut.",
+	Created: time.Now(),
+}
 
-func (m *SnippetModel) Insert(title, content, expires string) (int, error) {
+// SnippetModel запоминает удалённые заметки в Deleted.
+type SnippetModel struct {
+	Deleted []int
+}
+
+func (m *SnippetModel) Insert(userID int, title, content string, expires int) (int, error) {
 	return 2, nil
 }
+func (m *SnippetModel) Delete(id int) error {
+	m.Deleted = append(m.Deleted, id)
+	return nil
+}
+func (m *SnippetModel) UpdateExpiry(id, userID, expires int) error {
+	return nil
+}
 func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	switch id {
 	case 1:
 		return mockSnippet, nil
+	case 3:
+		return mockForeignSnippet, nil
 	default:
 		return nil, models.ErrNoRecord
 	}
@@ -29,3 +52,11 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return []*models.Snippet{mockSnippet}, nil
 }
+func (m *SnippetModel) ByUser(userID, limit int) ([]*models.Snippet, error) {
+	switch userID {
+	case 1:
+		return []*models.Snippet{mockSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,34 @@
+package mock
+
+import (
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// TagModel запоминает теги, добавленные заметкам, в Added.
+type TagModel struct {
+	Added map[int][]string
+}
+
+func (m *TagModel) Set(snippetID int, tags []string) error {
+	if m.Added == nil {
+		m.Added = map[int][]string{}
+	}
+	m.Added[snippetID] = append(m.Added[snippetID], tags...)
+	return nil
+}
+
+// Snippets находит по тегу "haiku" обе заметки.
+func (m *TagModel) Snippets(tag string, limit, offset int) ([]*models.Snippet, error) {
+	if tag != "haiku" {
+		return nil, nil
+	}
+	snippets := []*models.Snippet{mockForeignSnippet, mockSnippet}
+	if offset >= len(snippets) {
+		return nil, nil
+	}
+	snippets = snippets[offset:]
+	if len(snippets) > limit {
+		snippets = snippets[:limit]
+	}
+	return snippets, nil
+}
@@ -6,19 +6,32 @@ import (
 )
 
 var mockUser = &models.User{
-	ID:      1,
-	Name:    "Alice",
-	Email:   "alice@example.com",
-	Created: time.Now(),
-	Active:  true,
+	ID:       1,
+	Name:     "Alice",
+	Username: "alice",
+	Email:    "alice@example.com",
+	Created:  time.Now(),
+	Active:   true,
+}
+
+var mockBob = &models.User{
+	ID:       2,
+	Name:     "Bob",
+	Username: "bob",
+	Email:    "bob@example.com",
+	Created:  time.Now(),
+	Active:   true,
+	Locale:   "en",
 }
 
 type UserModel struct{}
 
-func (m *UserModel) Insert(name, email, password string) error {
-	switch email {
-	case "dupe@example.com":
+func (m *UserModel) Insert(name, username, email, password string) error {
+	switch {
+	case email == "dupe@example.com":
 		return models.ErrDuplicateEmail
+	case username == "alice":
+		return models.ErrDuplicateUsername
 	default:
 		return nil
 	}
@@ -35,7 +48,38 @@ func (m *UserModel) Get(id int) (*models.User, error) {
 	switch id {
 	case 1:
 		return mockUser, nil
+	case 2:
+		return mockBob, nil
 	default:
 		return nil, models.ErrNoRecord
 	}
 }
+
+func (m *UserModel) SetLocale(id int, locale string) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) SetTimeZone(id int, zone string) error {
+	switch id {
+	case 1:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *UserModel) SetUsername(id int, username string) error {
+	switch {
+	case id != 1:
+		return models.ErrNoRecord
+	case username == "bob":
+		return models.ErrDuplicateUsername
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,15 @@
+package mock
+
+type VoteModel struct{}
+
+func (m *VoteModel) Vote(snippetID, authorID, userID, value int) error {
+	return nil
+}
+func (m *VoteModel) Score(snippetID int) (int, error) {
+	switch snippetID {
+	case 1:
+		return 3, nil
+	default:
+		return 0, nil
+	}
+}
@@ -13,21 +13,247 @@ var (
 	// ErrDuplicateEmail ошибка. Мы будем использовать это позже, если пользователь
 	// пытается зарегистрироваться с помощью адреса электронной почты, который уже используется.
 	ErrDuplicateEmail = errors.New("models: duplicate email")
+	// ErrDuplicateUsername - имя пользователя (или похожее на него, см.
+	// username.Skeleton) уже занято.
+	ErrDuplicateUsername = errors.New("models: duplicate username")
+	// ErrAlreadyVoted - пользователь уже опустил бюллетень в этом опросе.
+	ErrAlreadyVoted = errors.New("models: already voted")
+	// ErrDuplicateCollection - у пользователя уже есть коллекция с таким
+	// именем.
+	ErrDuplicateCollection = errors.New("models: duplicate collection")
 )
 
+// Snippet - заметка. Нулевое значение Expires означает, что заметка бессрочная,
+// а нулевой UserID - что она создана до появления авторства.
 type Snippet struct {
 	ID      int
+	UserID  int
 	Title   string
 	Content string
 	Created time.Time
 	Expires time.Time
 }
 
+// Permanent возвращает true, если у заметки нет срока жизни.
+func (s *Snippet) Permanent() bool {
+	return s.Expires.IsZero()
+}
+
+// User - пользователь. Name - отображаемое имя, его можно не делать
+// уникальным; Username - уникальное имя для адреса профиля и упоминаний.
 type User struct {
 	ID             int
 	Name           string
+	Username       string
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
 	Active         bool
+	// Locale - выбранный язык интерфейса ("ru", "kk", "en") или пустая
+	// строка, если пользователь его не выбирал.
+	Locale string
+	// TimeZone - часовой пояс из базы IANA, в котором пользователю
+	// показываются даты, или пустая строка для часового пояса сайта.
+	TimeZone string
+}
+
+// Profile - публичная часть данных пользователя. Адреса электронной почты
+// здесь нет: профиль видят все.
+type Profile struct {
+	ID       int
+	Name     string
+	Username string
+	// Bio - необязательный рассказ о себе; AvatarKey - ключ аватара в
+	// хранилище или пустая строка.
+	Bio       string
+	AvatarKey string
+	Created   time.Time
+	// Karma - сумма голосов за заметки пользователя.
+	Karma int
+}
+
+// Comment - комментарий к заметке. Author, AuthorUsername и SnippetTitle
+// заполняются из связанных таблиц для показа в списках.
+type Comment struct {
+	ID             int
+	SnippetID      int
+	UserID         int
+	Author         string
+	AuthorUsername string
+	SnippetTitle   string
+	Content        string
+	Created        time.Time
+}
+
+// Participant - участник переписки или заблокированный пользователь:
+// только то, что нужно для показа имени со ссылкой на профиль.
+type Participant struct {
+	ID       int
+	Name     string
+	Username string
+}
+
+// Conversation - личная переписка двух или более пользователей, как её
+// видит один из участников: Participants - остальные участники, Unread -
+// сколько сообщений он ещё не прочитал.
+type Conversation struct {
+	ID           int
+	Participants []Participant
+	LastMessage  string
+	Updated      time.Time
+	Unread       int
+}
+
+// Message - сообщение в личной переписке. Sender и SenderUsername
+// заполняются из таблицы пользователей.
+type Message struct {
+	ID             int
+	ConversationID int
+	SenderID       int
+	Sender         string
+	SenderUsername string
+	Content        string
+	Created        time.Time
+}
+
+// Poll - опрос, прикреплённый к заметке SnippetID. Нулевое значение Closes
+// означает, что опрос не закрывается. Voters - число бюллетеней, Choices -
+// варианты, выбранные тем, кто смотрит опрос (пусто, если он не голосовал).
+type Poll struct {
+	SnippetID int
+	Multiple  bool
+	Closes    time.Time
+	Options   []*PollOption
+	Voters    int
+	Choices   []int
+}
+
+// PollOption - вариант ответа и число голосов за него.
+type PollOption struct {
+	ID    int
+	Text  string
+	Votes int
+}
+
+// Closed возвращает true, если к моменту now опрос закрыт.
+func (p *Poll) Closed(now time.Time) bool {
+	return !p.Closes.IsZero() && !now.Before(p.Closes)
+}
+
+// Voted возвращает true, если смотрящий уже проголосовал.
+func (p *Poll) Voted() bool {
+	return len(p.Choices) > 0
+}
+
+// Chosen возвращает true, если смотрящий выбрал вариант optionID.
+func (p *Poll) Chosen(optionID int) bool {
+	for _, id := range p.Choices {
+		if id == optionID {
+			return true
+		}
+	}
+	return false
+}
+
+// Percent возвращает долю проголосовавших за вариант o в процентах. В
+// опросе с несколькими ответами сумма долей может быть больше 100.
+func (p *Poll) Percent(o *PollOption) int {
+	if p.Voters == 0 {
+		return 0
+	}
+	return o.Votes * 100 / p.Voters
+}
+
+// SavedItem - сохранённая пользователем заметка или, если CommentID не
+// равен нулю, комментарий к ней. Content - текущий текст заметки или
+// комментария, Author - его автор. CollectionID равен нулю, если элемент
+// не разложен по коллекциям.
+type SavedItem struct {
+	ID             int
+	SnippetID      int
+	SnippetTitle   string
+	CommentID      int
+	Content        string
+	Author         string
+	AuthorUsername string
+	CollectionID   int
+	Collection     string
+	Saved          time.Time
+}
+
+// Collection - именованная коллекция сохранённого; Items - сколько в ней
+// доступных элементов.
+type Collection struct {
+	ID    int
+	Name  string
+	Items int
+}
+
+// Типы уведомлений.
+const (
+	// NotificationReply - комментарий к заметке пользователя.
+	NotificationReply = "reply"
+	// NotificationMention - упоминание пользователя (@username).
+	NotificationMention = "mention"
+	// NotificationVote - голос "за" заметку пользователя.
+	NotificationVote = "vote"
+)
+
+// NotificationTypes перечисляет типы уведомлений в том порядке, в котором
+// они показываются в настройках.
+var NotificationTypes = []string{
+	NotificationReply,
+	NotificationMention,
+	NotificationVote,
+}
+
+// Notification - уведомление пользователя UserID о действии пользователя
+// ActorID. Actor, ActorUsername и SnippetTitle заполняются из связанных
+// таблиц для показа в списке.
+type Notification struct {
+	ID            int
+	UserID        int
+	Type          string
+	ActorID       int
+	Actor         string
+	ActorUsername string
+	SnippetID     int
+	SnippetTitle  string
+	CommentID     int
+	Text          string
+	Created       time.Time
+	Read          bool
+}
+
+// NotificationPreference - как доставлять пользователю уведомления
+// одного типа: в приложении и/или по электронной почте.
+type NotificationPreference struct {
+	Type  string
+	InApp bool
+	Email bool
+}
+
+// DefaultNotificationPreference - настройки для пользователя, который их
+// не менял: все уведомления в приложении, без писем.
+func DefaultNotificationPreference(notificationType string) NotificationPreference {
+	return NotificationPreference{Type: notificationType, InApp: true}
+}
+
+// Attachment - файл, прикреплённый к заметке. Сам файл лежит в хранилище
+// под ключом StorageKey; для изображений там же хранится миниатюра.
+type Attachment struct {
+	ID           int
+	SnippetID    int
+	UserID       int
+	Filename     string
+	ContentType  string
+	Size         int64
+	StorageKey   string
+	ThumbnailKey string
+	Created      time.Time
+}
+
+// IsImage возвращает true, если у вложения есть миниатюра.
+func (a *Attachment) IsImage() bool {
+	return a.ThumbnailKey != ""
 }
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// AttachmentModel - описание вложений заметок.
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+// Insert сохраняет описание уже загруженного в хранилище файла.
+func (m *AttachmentModel) Insert(a *models.Attachment) (int, error) {
+	stmt := `INSERT INTO attachments (snippet_id, user_id, filename, content_type, size, storage_key, thumbnail_key, created)
+    VALUES(?, ?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+	result, err := m.DB.Exec(stmt, a.SnippetID, a.UserID, a.Filename, a.ContentType, a.Size, a.StorageKey, a.ThumbnailKey)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// Get возвращает вложение, если заметка, к которой оно прикреплено,
+// ещё не истекла.
+func (m *AttachmentModel) Get(id int) (*models.Attachment, error) {
+	stmt := `SELECT a.id, a.snippet_id, a.user_id, a.filename, a.content_type, a.size, a.storage_key, a.thumbnail_key, a.created
+    FROM attachments a JOIN snippets s ON s.id = a.snippet_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND a.id = ?`
+	a := &models.Attachment{}
+	err := m.DB.QueryRow(stmt, id).Scan(&a.ID, &a.SnippetID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.ThumbnailKey, &a.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// ForSnippet возвращает вложения заметки в порядке загрузки.
+func (m *AttachmentModel) ForSnippet(snippetID int) ([]*models.Attachment, error) {
+	stmt := `SELECT id, snippet_id, user_id, filename, content_type, size, storage_key, thumbnail_key, created
+    FROM attachments WHERE snippet_id = ? ORDER BY id`
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*models.Attachment
+	for rows.Next() {
+		a := &models.Attachment{}
+		err = rows.Scan(&a.ID, &a.SnippetID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.ThumbnailKey, &a.Created)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
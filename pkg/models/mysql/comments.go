@@ -0,0 +1,68 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// CommentModel - комментарии к заметкам.
+type CommentModel struct {
+	DB *sql.DB
+}
+
+// Insert добавляет комментарий пользователя к заметке.
+func (m *CommentModel) Insert(snippetID, userID int, content string) (int, error) {
+	stmt := `INSERT INTO comments (snippet_id, user_id, content, created)
+    VALUES(?, ?, ?, UTC_TIMESTAMP())`
+	result, err := m.DB.Exec(stmt, snippetID, userID, content)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// ForSnippet возвращает комментарии к заметке от старых к новым вместе
+// с именами авторов.
+func (m *CommentModel) ForSnippet(snippetID int) ([]*models.Comment, error) {
+	stmt := `SELECT c.id, c.snippet_id, c.user_id, u.name, u.username, '', c.content, c.created
+    FROM comments c JOIN users u ON u.id = c.user_id
+    WHERE c.snippet_id = ? ORDER BY c.id`
+	return m.query(stmt, snippetID)
+}
+
+// ByUser возвращает не более limit последних комментариев пользователя
+// к неистёкшим заметкам вместе с заголовками этих заметок.
+func (m *CommentModel) ByUser(userID, limit int) ([]*models.Comment, error) {
+	stmt := `SELECT c.id, c.snippet_id, c.user_id, u.name, u.username, s.title, c.content, c.created
+    FROM comments c JOIN users u ON u.id = c.user_id JOIN snippets s ON s.id = c.snippet_id
+    WHERE (s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND c.user_id = ?
+    ORDER BY c.id DESC LIMIT ?`
+	return m.query(stmt, userID, limit)
+}
+
+func (m *CommentModel) query(stmt string, args ...any) ([]*models.Comment, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*models.Comment
+	for rows.Next() {
+		c := &models.Comment{}
+		err = rows.Scan(&c.ID, &c.SnippetID, &c.UserID, &c.Author, &c.AuthorUsername, &c.SnippetTitle, &c.Content, &c.Created)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
@@ -0,0 +1,324 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// DMModel - личные переписки, сообщения в них и блокировки.
+type DMModel struct {
+	DB *sql.DB
+}
+
+// placeholders возвращает "?, ?, ?" для n значений и сами значения.
+func placeholders(ids []int) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// Find возвращает переписку, участники которой - ровно userIDs (без
+// повторов), или models.ErrNoRecord, если такой нет. Так второе сообщение
+// тому же человеку попадает в уже начатую переписку.
+func (m *DMModel) Find(userIDs []int) (int, error) {
+	if len(userIDs) == 0 {
+		return 0, models.ErrNoRecord
+	}
+	in, args := placeholders(userIDs)
+	stmt := `SELECT cm.conversation_id FROM conversation_members cm
+    JOIN conversation_members me ON me.conversation_id = cm.conversation_id AND me.user_id = ?
+    GROUP BY cm.conversation_id
+    HAVING COUNT(*) = ? AND SUM(cm.user_id IN (` + in + `)) = ?
+    ORDER BY cm.conversation_id DESC LIMIT 1`
+	args = append([]any{userIDs[0], len(userIDs)}, append(args, len(userIDs))...)
+	var id int
+	err := m.DB.QueryRow(stmt, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, models.ErrNoRecord
+	}
+	return id, err
+}
+
+// Start создаёт переписку отправителя с recipientIDs и первое сообщение в
+// ней. Возвращает идентификатор переписки.
+func (m *DMModel) Start(senderID int, recipientIDs []int, content string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO conversations (created, updated) VALUES(UTC_TIMESTAMP(), UTC_TIMESTAMP())`)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	members := append([]int{senderID}, recipientIDs...)
+	args := make([]any, 0, len(members)*2)
+	for _, userID := range members {
+		args = append(args, id, userID)
+	}
+	stmt := `INSERT INTO conversation_members (conversation_id, user_id) VALUES ` +
+		strings.TrimSuffix(strings.Repeat("(?, ?),", len(members)), ",")
+	if _, err = tx.Exec(stmt, args...); err != nil {
+		return 0, err
+	}
+	if _, err = send(tx, int(id), senderID, content); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// Send добавляет сообщение в переписку. Что отправитель - её участник,
+// проверяет вызывающий (см. Get). Возвращает идентификатор сообщения.
+func (m *DMModel) Send(conversationID, senderID int, content string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := send(tx, conversationID, senderID, content)
+	if err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// send сохраняет сообщение в транзакции tx. Своё сообщение отправитель
+// уже прочитал.
+func send(tx *sql.Tx, conversationID, senderID int, content string) (int, error) {
+	result, err := tx.Exec(`INSERT INTO messages (conversation_id, sender_id, content, created)
+    VALUES(?, ?, ?, UTC_TIMESTAMP())`, conversationID, senderID, content)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(`UPDATE conversations SET updated = UTC_TIMESTAMP() WHERE id = ?`, conversationID); err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE conversation_members SET last_read_id = ? WHERE conversation_id = ? AND user_id = ?`,
+		id, conversationID, senderID)
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// Get возвращает переписку такой, какой её видит участник userID. Если
+// переписки нет или userID в ней не участвует, возвращается
+// models.ErrNoRecord: чужие переписки неотличимы от несуществующих.
+func (m *DMModel) Get(conversationID, userID int) (*models.Conversation, error) {
+	c := &models.Conversation{ID: conversationID}
+	stmt := `SELECT c.updated, (SELECT COUNT(*) FROM messages m
+        WHERE m.conversation_id = c.id AND m.id > me.last_read_id AND m.sender_id <> me.user_id)
+    FROM conversation_members me JOIN conversations c ON c.id = me.conversation_id
+    WHERE me.conversation_id = ? AND me.user_id = ?`
+	err := m.DB.QueryRow(stmt, conversationID, userID).Scan(&c.Updated, &c.Unread)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = m.participants([]*models.Conversation{c}, userID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Conversations возвращает не более limit переписок пользователя,
+// начиная с тех, где недавно писали.
+func (m *DMModel) Conversations(userID, limit int) ([]*models.Conversation, error) {
+	stmt := `SELECT c.id, c.updated,
+        COALESCE((SELECT content FROM messages WHERE conversation_id = c.id ORDER BY id DESC LIMIT 1), ''),
+        (SELECT COUNT(*) FROM messages m
+            WHERE m.conversation_id = c.id AND m.id > me.last_read_id AND m.sender_id <> me.user_id)
+    FROM conversation_members me JOIN conversations c ON c.id = me.conversation_id
+    WHERE me.user_id = ? ORDER BY c.updated DESC, c.id DESC LIMIT ?`
+	rows, err := m.DB.Query(stmt, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []*models.Conversation
+	for rows.Next() {
+		c := &models.Conversation{}
+		if err = rows.Scan(&c.ID, &c.Updated, &c.LastMessage, &c.Unread); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = m.participants(conversations, userID); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// participants заполняет Participants переписок: всех участников, кроме
+// userID.
+func (m *DMModel) participants(conversations []*models.Conversation, userID int) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	byID := map[int]*models.Conversation{}
+	ids := make([]int, 0, len(conversations))
+	for _, c := range conversations {
+		byID[c.ID] = c
+		ids = append(ids, c.ID)
+	}
+	in, args := placeholders(ids)
+	stmt := `SELECT cm.conversation_id, u.id, u.name, u.username
+    FROM conversation_members cm JOIN users u ON u.id = cm.user_id
+    WHERE cm.conversation_id IN (` + in + `) AND cm.user_id <> ? ORDER BY u.name, u.id`
+	rows, err := m.DB.Query(stmt, append(args, userID)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var conversationID int
+		var p models.Participant
+		if err = rows.Scan(&conversationID, &p.ID, &p.Name, &p.Username); err != nil {
+			return err
+		}
+		c := byID[conversationID]
+		c.Participants = append(c.Participants, p)
+	}
+	return rows.Err()
+}
+
+// Messages возвращает не более limit последних сообщений переписки, от
+// старых к новым.
+func (m *DMModel) Messages(conversationID, limit int) ([]*models.Message, error) {
+	stmt := `SELECT * FROM (
+        SELECT m.id, m.conversation_id, m.sender_id, COALESCE(u.name, ''), COALESCE(u.username, ''), m.content, m.created
+        FROM messages m LEFT JOIN users u ON u.id = m.sender_id
+        WHERE m.conversation_id = ? ORDER BY m.id DESC LIMIT ?
+    ) latest ORDER BY id`
+	rows, err := m.DB.Query(stmt, conversationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*models.Message
+	for rows.Next() {
+		msg := &models.Message{}
+		err = rows.Scan(&msg.ID, &msg.ConversationID, &msg.SenderID, &msg.Sender, &msg.SenderUsername, &msg.Content, &msg.Created)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// MarkRead отмечает прочитанными все сообщения переписки для userID.
+func (m *DMModel) MarkRead(conversationID, userID int) error {
+	stmt := `UPDATE conversation_members
+    SET last_read_id = (SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = ?)
+    WHERE conversation_id = ? AND user_id = ?`
+	_, err := m.DB.Exec(stmt, conversationID, conversationID, userID)
+	return err
+}
+
+// Unread возвращает число переписок, в которых у пользователя есть
+// непрочитанные сообщения.
+func (m *DMModel) Unread(userID int) (int, error) {
+	stmt := `SELECT COUNT(*) FROM conversation_members me
+    WHERE me.user_id = ? AND EXISTS (SELECT 1 FROM messages m
+        WHERE m.conversation_id = me.conversation_id AND m.id > me.last_read_id AND m.sender_id <> me.user_id)`
+	var n int
+	err := m.DB.QueryRow(stmt, userID).Scan(&n)
+	return n, err
+}
+
+// Block запрещает blockedID писать пользователю userID. Повторная
+// блокировка ничего не меняет.
+func (m *DMModel) Block(userID, blockedID int) error {
+	_, err := m.DB.Exec(`INSERT IGNORE INTO blocks (user_id, blocked_id, created) VALUES(?, ?, UTC_TIMESTAMP())`,
+		userID, blockedID)
+	return err
+}
+
+// Unblock снимает блокировку.
+func (m *DMModel) Unblock(userID, blockedID int) error {
+	_, err := m.DB.Exec(`DELETE FROM blocks WHERE user_id = ? AND blocked_id = ?`, userID, blockedID)
+	return err
+}
+
+// Blocks возвращает пользователей, которых заблокировал userID.
+func (m *DMModel) Blocks(userID int) ([]models.Participant, error) {
+	stmt := `SELECT u.id, u.name, u.username FROM blocks b JOIN users u ON u.id = b.blocked_id
+    WHERE b.user_id = ? ORDER BY u.name, u.id`
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocked []models.Participant
+	for rows.Next() {
+		var p models.Participant
+		if err = rows.Scan(&p.ID, &p.Name, &p.Username); err != nil {
+			return nil, err
+		}
+		blocked = append(blocked, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return blocked, nil
+}
+
+// BlockedBy возвращает тех из userIDs, кто заблокировал senderID.
+func (m *DMModel) BlockedBy(senderID int, userIDs []int) ([]int, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	in, args := placeholders(userIDs)
+	stmt := `SELECT user_id FROM blocks WHERE blocked_id = ? AND user_id IN (` + in + `)`
+	rows, err := m.DB.Query(stmt, append([]any{senderID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,22 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// HealthModel отвечает на вопросы проверок готовности: доступна ли база
+// и до какой версии обновлена её схема.
+type HealthModel struct {
+	DB *sql.DB
+}
+
+// Ping проверяет соединение с базой. Время ожидания задаётся контекстом.
+func (m *HealthModel) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
+
+// SchemaVersion возвращает номер последней применённой миграции.
+func (m *HealthModel) SchemaVersion(ctx context.Context) (int, error) {
+	return schemaVersion(ctx, m.DB)
+}
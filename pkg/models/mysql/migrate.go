@@ -0,0 +1,183 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Файлы миграций встраиваются в бинарник, поэтому схему можно обновить
+// без копирования SQL-скриптов на сервер. Имя файла начинается с номера
+// версии: 0001_create_snippets.sql, 0002_create_users.sql и т.д.
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+type migration struct {
+	version int
+	name    string
+}
+
+// goSteps - шаги миграций, которые не выразить на SQL. Шаг выполняется
+// после скрипта миграции с тем же номером, но до того, как версия
+// записывается в schema_migrations, поэтому упавший шаг повторится при
+// следующем запуске. Шаги должны быть идемпотентными.
+var goSteps = map[int]func(*sql.DB) error{
+	16: backfillSnippetTags,
+}
+
+// Migrate применяет все миграции, номер которых больше текущей версии схемы,
+// и возвращает количество применённых миграций. Версия хранится в таблице
+// schema_migrations.
+func Migrate(db *sql.DB) (int, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    applied DATETIME NOT NULL
+)`)
+	if err != nil {
+		return 0, err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+
+	migrations, err := listMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// SchemaVersion возвращает номер последней применённой миграции
+// или 0, если миграции ещё не запускались.
+func SchemaVersion(db *sql.DB) (int, error) {
+	return schemaVersion(context.Background(), db)
+}
+
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		// Таблицы ещё нет - значит, схема не версионирована.
+		var mySQLError *mysql.MySQLError
+		if errors.As(err, &mySQLError) && mySQLError.Number == 1146 {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// LatestVersion возвращает номер последней миграции, встроенной в бинарник.
+// Если он больше SchemaVersion, схема базы отстаёт от кода.
+func LatestVersion() (int, error) {
+	migrations, err := listMigrations()
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+func listMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	for _, file := range files {
+		name := path.Base(file)
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("mysql: invalid migration file name %q", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: invalid migration file name %q", name)
+		}
+		migrations = append(migrations, migration{version: version, name: name})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	script, err := migrationFS.ReadFile("migrations/" + m.name)
+	if err != nil {
+		return err
+	}
+	// Драйвер по умолчанию не выполняет несколько запросов за один вызов
+	// Exec(), поэтому разбиваем скрипт на отдельные выражения.
+	for _, stmt := range splitStatements(string(script)) {
+		if _, err := db.Exec(stmt); err != nil && !alreadyApplied(err) {
+			return err
+		}
+	}
+	if step := goSteps[m.version]; step != nil {
+		if err := step(db); err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec("INSERT INTO schema_migrations (version, applied) VALUES(?, UTC_TIMESTAMP())", m.version)
+	return err
+}
+
+// splitStatements делит SQL-скрипт на выражения по точке с запятой в конце
+// строки и отбрасывает строки-комментарии.
+func splitStatements(script string) []string {
+	var stmts []string
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSuffix(strings.TrimSpace(b.String()), ";"))
+			b.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(b.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+// alreadyApplied сообщает, что выражение уже было выполнено вручную до того,
+// как схема стала версионироваться (таблица, индекс или столбец уже есть).
+func alreadyApplied(err error) bool {
+	var mySQLError *mysql.MySQLError
+	if !errors.As(err, &mySQLError) {
+		return false
+	}
+	switch mySQLError.Number {
+	case 1050, 1060, 1061:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+-- Сюда фоновая очистка переносит истёкшие заметки, если она запущена
+-- в режиме архивации (-purge-archive).
+CREATE TABLE IF NOT EXISTS snippets_archive (
+    id INTEGER NOT NULL PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    archived DATETIME NOT NULL
+);
+
+CREATE INDEX idx_snippets_expires ON snippets(expires);
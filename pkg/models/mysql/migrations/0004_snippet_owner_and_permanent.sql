@@ -0,0 +1,7 @@
+-- Заметки получают автора, чтобы он мог менять срок их жизни, а NULL
+-- в expires означает бессрочную заметку.
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NULL;
+
+ALTER TABLE snippets MODIFY expires DATETIME NULL;
+
+CREATE INDEX idx_snippets_user_id ON snippets(user_id);
@@ -0,0 +1,2 @@
+-- Полнотекстовый индекс для страницы /search.
+CREATE FULLTEXT INDEX ft_snippets_title_content ON snippets(title, content);
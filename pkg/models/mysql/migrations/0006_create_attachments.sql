@@ -0,0 +1,15 @@
+-- Вложения к заметкам. Сами файлы лежат в хранилище (storage_key),
+-- в базе только их описание.
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size INTEGER NOT NULL,
+    storage_key VARCHAR(100) NOT NULL,
+    thumbnail_key VARCHAR(100) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL
+);
+
+CREATE INDEX idx_attachments_snippet_id ON attachments(snippet_id);
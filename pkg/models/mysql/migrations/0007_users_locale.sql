@@ -0,0 +1,3 @@
+-- Язык интерфейса, выбранный пользователем. Пустая строка - язык ещё не
+-- выбран и определяется по заголовку Accept-Language.
+ALTER TABLE users ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT '';
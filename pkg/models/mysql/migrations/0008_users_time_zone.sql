@@ -0,0 +1,3 @@
+-- Часовой пояс пользователя из базы IANA (например, Asia/Almaty). Пустая
+-- строка - показывать даты в часовом поясе сайта.
+ALTER TABLE users ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT '';
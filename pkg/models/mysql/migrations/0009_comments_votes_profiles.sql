@@ -0,0 +1,31 @@
+-- Комментарии к заметкам.
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
+
+CREATE INDEX idx_comments_snippet_id ON comments(snippet_id);
+
+CREATE INDEX idx_comments_user_id ON comments(user_id);
+
+-- Голоса за заметки: один голос (+1 или -1) от пользователя за заметку.
+-- author_id - автор заметки на момент голосования: по нему считается
+-- карма, и она не пропадает, когда заметка истекает и удаляется.
+CREATE TABLE IF NOT EXISTS votes (
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    author_id INTEGER NOT NULL,
+    value TINYINT NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (snippet_id, user_id)
+);
+
+CREATE INDEX idx_votes_author_id ON votes(author_id);
+
+-- Необязательные поля публичного профиля.
+ALTER TABLE users ADD COLUMN bio VARCHAR(500) NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN avatar_key VARCHAR(100) NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+-- Уникальные имена пользователей для адресов профилей и упоминаний.
+-- username - нормализованное имя, username_key - его скелет (см. пакет
+-- username), по которому проверяется уникальность.
+ALTER TABLE users ADD COLUMN username VARCHAR(30) NULL;
+
+ALTER TABLE users ADD COLUMN username_key VARCHAR(30) NULL;
+
+-- Пользователи, зарегистрированные раньше, получают имя user<id> и могут
+-- сменить его в настройках. Скелет считается так же, как в
+-- username.Skeleton: в цифрах id 0 заменяется на o, а 1 - на l.
+UPDATE users SET username = CONCAT('user', id),
+    username_key = CONCAT('user', REPLACE(REPLACE(CAST(id AS CHAR), '0', 'o'), '1', 'l'))
+    WHERE username IS NULL;
+
+ALTER TABLE users MODIFY username VARCHAR(30) NOT NULL;
+
+ALTER TABLE users MODIFY username_key VARCHAR(30) NOT NULL;
+
+ALTER TABLE users ADD CONSTRAINT users_uc_username UNIQUE (username_key);
@@ -0,0 +1,26 @@
+-- Уведомления пользователей. actor_id - кто совершил действие (0 для
+-- действий модераторов от имени сайта), snippet_id и comment_id - к чему
+-- оно относится, text - пояснение, например причина действия модератора.
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    actor_id INTEGER NOT NULL DEFAULT 0,
+    snippet_id INTEGER NOT NULL DEFAULT 0,
+    comment_id INTEGER NOT NULL DEFAULT 0,
+    text VARCHAR(500) NOT NULL DEFAULT '',
+    created DATETIME NOT NULL,
+    read_at DATETIME NULL
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, id);
+
+-- Настройки уведомлений по типам. Если строки нет, действуют настройки по
+-- умолчанию (models.DefaultNotificationPreference).
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    in_app BOOLEAN NOT NULL,
+    email BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
@@ -0,0 +1,10 @@
+-- Теги заметок (#тег в тексте). Теги хранятся нормализованными
+-- (markdown.NormalizeTag), поэтому сравниваются побайтно. Теги заметкам,
+-- опубликованным до этой миграции, проставляет миграция 16.
+CREATE TABLE IF NOT EXISTS snippet_tags (
+    tag VARCHAR(200) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    PRIMARY KEY (tag, snippet_id)
+);
+
+CREATE INDEX idx_snippet_tags_snippet_id ON snippet_tags(snippet_id);
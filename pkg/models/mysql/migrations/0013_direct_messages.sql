@@ -0,0 +1,36 @@
+-- Личные переписки. updated - время последнего сообщения, по нему
+-- сортируется список переписок.
+CREATE TABLE IF NOT EXISTS conversations (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    created DATETIME NOT NULL,
+    updated DATETIME NOT NULL
+);
+
+-- Участники переписки. last_read_id - последнее прочитанное участником
+-- сообщение: всё, что новее, считается непрочитанным.
+CREATE TABLE IF NOT EXISTS conversation_members (
+    conversation_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    last_read_id INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX idx_conversation_members_user_id ON conversation_members(user_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    conversation_id INTEGER NOT NULL,
+    sender_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL
+);
+
+CREATE INDEX idx_messages_conversation_id ON messages(conversation_id, id);
+
+-- Блокировки: пользователь user_id не принимает сообщений от blocked_id.
+CREATE TABLE IF NOT EXISTS blocks (
+    user_id INTEGER NOT NULL,
+    blocked_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (user_id, blocked_id)
+);
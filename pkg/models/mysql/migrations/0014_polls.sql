@@ -0,0 +1,36 @@
+-- Опросы. Опрос - это заметка, у которой есть строка в polls. closes -
+-- время закрытия, NULL - опрос не закрывается (но удаляется вместе с
+-- истёкшей заметкой).
+CREATE TABLE IF NOT EXISTS polls (
+    snippet_id INTEGER NOT NULL PRIMARY KEY,
+    multiple BOOLEAN NOT NULL DEFAULT FALSE,
+    closes DATETIME NULL
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    snippet_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    text VARCHAR(200) NOT NULL
+);
+
+CREATE INDEX idx_poll_options_snippet_id ON poll_options(snippet_id, position);
+
+-- Бюллетени: первичный ключ не даёт пользователю проголосовать дважды,
+-- даже если два запроса придут одновременно. Выбранные варианты бюллетеня
+-- хранятся в poll_choices.
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created DATETIME NOT NULL,
+    PRIMARY KEY (snippet_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_choices (
+    snippet_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    PRIMARY KEY (snippet_id, user_id, option_id)
+);
+
+CREATE INDEX idx_poll_choices_option_id ON poll_choices(option_id);
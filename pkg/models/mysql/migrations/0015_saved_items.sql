@@ -0,0 +1,24 @@
+-- Коллекции сохранённого: именованные папки пользователя.
+CREATE TABLE IF NOT EXISTS collections (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created DATETIME NOT NULL,
+    CONSTRAINT collections_uc_user_name UNIQUE (user_id, name)
+);
+
+-- Сохранённые заметки (comment_id = 0) и комментарии. Хранятся только
+-- ссылки, поэтому правки содержимого видны сразу, а удалённое и истёкшее
+-- просто пропадает из списка. collection_id = 0 - без коллекции.
+CREATE TABLE IF NOT EXISTS saved_items (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    comment_id INTEGER NOT NULL DEFAULT 0,
+    collection_id INTEGER NOT NULL DEFAULT 0,
+    created DATETIME NOT NULL,
+    CONSTRAINT saved_items_uc_item UNIQUE (user_id, snippet_id, comment_id)
+);
+
+CREATE INDEX idx_saved_items_user_id ON saved_items(user_id, collection_id, id);
+CREATE INDEX idx_saved_items_snippet_id ON saved_items(snippet_id);
@@ -0,0 +1,3 @@
+-- Теги заметок, опубликованных до миграции 12. Их извлекает из текста
+-- Go-шаг backfillSnippetTags (см. goSteps в migrate.go): SQL сам не
+-- разберёт Markdown.
@@ -0,0 +1,12 @@
+-- Отметки об уже отправленных однократных уведомлениях (голос "за"): по
+-- одной на получателя, тип, автора действия и заметку. Отметка ставится
+-- независимо от настроек доставки и удаляется вместе с заметкой.
+CREATE TABLE IF NOT EXISTS notification_once (
+    user_id INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    actor_id INTEGER NOT NULL,
+    snippet_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, type, actor_id, snippet_id)
+);
+
+CREATE INDEX idx_notification_once_snippet_id ON notification_once(snippet_id);
@@ -0,0 +1,2 @@
+-- Полнотекстовый индекс комментариев: /search находит и их.
+CREATE FULLTEXT INDEX ft_comments_content ON comments(content);
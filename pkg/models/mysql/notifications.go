@@ -0,0 +1,140 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// NotificationModel - уведомления пользователей и их настройки.
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+// Insert сохраняет новое непрочитанное уведомление.
+func (m *NotificationModel) Insert(n *models.Notification) (int, error) {
+	stmt := `INSERT INTO notifications (user_id, type, actor_id, snippet_id, comment_id, text, created)
+    VALUES(?, ?, ?, ?, ?, ?, UTC_TIMESTAMP())`
+	result, err := m.DB.Exec(stmt, n.UserID, n.Type, n.ActorID, n.SnippetID, n.CommentID, n.Text)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// Once отмечает, что пользователь userID уведомлён о действии типа
+// notificationType пользователя actorID с заметкой snippetID. Возвращает
+// true, только если такой отметки ещё не было.
+func (m *NotificationModel) Once(userID int, notificationType string, actorID, snippetID int) (bool, error) {
+	stmt := `INSERT IGNORE INTO notification_once (user_id, type, actor_id, snippet_id) VALUES(?, ?, ?, ?)`
+	result, err := m.DB.Exec(stmt, userID, notificationType, actorID, snippetID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Latest возвращает не более limit последних уведомлений пользователя.
+// Заметка могла уже истечь и быть удалена - тогда SnippetTitle пуст.
+func (m *NotificationModel) Latest(userID, limit int) ([]*models.Notification, error) {
+	stmt := `SELECT n.id, n.user_id, n.type, n.actor_id, COALESCE(u.name, ''), COALESCE(u.username, ''),
+    n.snippet_id, COALESCE(s.title, ''), n.comment_id, n.text, n.created, n.read_at IS NOT NULL
+    FROM notifications n
+    LEFT JOIN users u ON u.id = n.actor_id
+    LEFT JOIN snippets s ON s.id = n.snippet_id AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+    WHERE n.user_id = ? ORDER BY n.id DESC LIMIT ?`
+	rows, err := m.DB.Query(stmt, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		n := &models.Notification{}
+		err = rows.Scan(&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.Actor, &n.ActorUsername,
+			&n.SnippetID, &n.SnippetTitle, &n.CommentID, &n.Text, &n.Created, &n.Read)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// Unread возвращает количество непрочитанных уведомлений пользователя.
+func (m *NotificationModel) Unread(userID int) (int, error) {
+	var n int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// MarkRead отмечает прочитанным уведомление id. Чужие уведомления не
+// меняются.
+func (m *NotificationModel) MarkRead(userID, id int) error {
+	stmt := `UPDATE notifications SET read_at = UTC_TIMESTAMP()
+    WHERE user_id = ? AND id = ? AND read_at IS NULL`
+	_, err := m.DB.Exec(stmt, userID, id)
+	return err
+}
+
+// MarkAllRead отмечает прочитанными все уведомления пользователя.
+func (m *NotificationModel) MarkAllRead(userID int) error {
+	stmt := `UPDATE notifications SET read_at = UTC_TIMESTAMP() WHERE user_id = ? AND read_at IS NULL`
+	_, err := m.DB.Exec(stmt, userID)
+	return err
+}
+
+// Preferences возвращает настройки пользователя для всех типов
+// уведомлений; для типов, которые он не настраивал, - настройки по
+// умолчанию.
+func (m *NotificationModel) Preferences(userID int) (map[string]models.NotificationPreference, error) {
+	prefs := map[string]models.NotificationPreference{}
+	for _, t := range models.NotificationTypes {
+		prefs[t] = models.DefaultNotificationPreference(t)
+	}
+	rows, err := m.DB.Query(`SELECT type, in_app, email FROM notification_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err = rows.Scan(&p.Type, &p.InApp, &p.Email); err != nil {
+			return nil, err
+		}
+		prefs[p.Type] = p
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// SetPreferences сохраняет настройки пользователя для перечисленных типов.
+func (m *NotificationModel) SetPreferences(userID int, prefs []models.NotificationPreference) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt := `INSERT INTO notification_preferences (user_id, type, in_app, email) VALUES(?, ?, ?, ?)
+    ON DUPLICATE KEY UPDATE in_app = VALUES(in_app), email = VALUES(email)`
+	for _, p := range prefs {
+		if _, err = tx.Exec(stmt, userID, p.Type, p.InApp, p.Email); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
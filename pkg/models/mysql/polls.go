@@ -0,0 +1,119 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// PollModel - опросы, их варианты и бюллетени.
+type PollModel struct {
+	DB *sql.DB
+}
+
+// Insert прикрепляет к заметке опрос с вариантами options в заданном
+// порядке. Нулевое closes - опрос без срока закрытия.
+func (m *PollModel) Insert(snippetID int, multiple bool, closes time.Time, options []string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var closesAt sql.NullTime
+	if !closes.IsZero() {
+		closesAt = sql.NullTime{Time: closes.UTC(), Valid: true}
+	}
+	_, err = tx.Exec(`INSERT INTO polls (snippet_id, multiple, closes) VALUES(?, ?, ?)`, snippetID, multiple, closesAt)
+	if err != nil {
+		return err
+	}
+	args := make([]any, 0, len(options)*3)
+	for i, text := range options {
+		args = append(args, snippetID, i, text)
+	}
+	stmt := `INSERT INTO poll_options (snippet_id, position, text) VALUES ` +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(options)), ",")
+	if _, err = tx.Exec(stmt, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get возвращает опрос заметки с числом голосов за каждый вариант и
+// выбором пользователя userID (0 - аноним). Если у заметки нет опроса,
+// возвращается models.ErrNoRecord.
+func (m *PollModel) Get(snippetID, userID int) (*models.Poll, error) {
+	p := &models.Poll{SnippetID: snippetID}
+	var closes sql.NullTime
+	stmt := `SELECT multiple, closes, (SELECT COUNT(*) FROM poll_ballots WHERE snippet_id = polls.snippet_id)
+    FROM polls WHERE snippet_id = ?`
+	err := m.DB.QueryRow(stmt, snippetID).Scan(&p.Multiple, &closes, &p.Voters)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Closes = closes.Time
+
+	stmt = `SELECT o.id, o.text, COUNT(c.option_id), COALESCE(MAX(c.user_id = ?), 0)
+    FROM poll_options o LEFT JOIN poll_choices c ON c.option_id = o.id
+    WHERE o.snippet_id = ? GROUP BY o.id, o.text, o.position ORDER BY o.position`
+	rows, err := m.DB.Query(stmt, userID, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		o := &models.PollOption{}
+		var chosen bool
+		if err = rows.Scan(&o.ID, &o.Text, &o.Votes, &chosen); err != nil {
+			return nil, err
+		}
+		p.Options = append(p.Options, o)
+		if chosen {
+			p.Choices = append(p.Choices, o.ID)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Vote сохраняет бюллетень пользователя с вариантами optionIDs. Что
+// варианты принадлежат опросу и что опрос открыт, проверяет вызывающий.
+// Повторный бюллетень отклоняется первичным ключом poll_ballots с ошибкой
+// models.ErrAlreadyVoted.
+func (m *PollModel) Vote(snippetID, userID int, optionIDs []int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO poll_ballots (snippet_id, user_id, created) VALUES(?, ?, UTC_TIMESTAMP())`,
+		snippetID, userID)
+	if err != nil {
+		var mySQLError *mysql.MySQLError
+		if errors.As(err, &mySQLError) && mySQLError.Number == 1062 {
+			return models.ErrAlreadyVoted
+		}
+		return err
+	}
+	args := make([]any, 0, len(optionIDs)*3)
+	for _, id := range optionIDs {
+		args = append(args, snippetID, userID, id)
+	}
+	stmt := `INSERT INTO poll_choices (snippet_id, user_id, option_id) VALUES ` +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(optionIDs)), ",")
+	if _, err = tx.Exec(stmt, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
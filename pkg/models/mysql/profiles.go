@@ -0,0 +1,50 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// ProfileModel - публичные профили пользователей.
+type ProfileModel struct {
+	DB *sql.DB
+}
+
+// Карма считается по голосам за заметки пользователя, в том числе уже
+// удалённые.
+const profileColumns = `u.id, u.name, u.username, u.bio, u.avatar_key, u.created,
+    (SELECT COALESCE(SUM(v.value), 0) FROM votes v WHERE v.author_id = u.id)`
+
+// Get возвращает профиль активного пользователя по идентификатору.
+func (m *ProfileModel) Get(id int) (*models.Profile, error) {
+	stmt := `SELECT ` + profileColumns + ` FROM users u WHERE u.active = TRUE AND u.id = ?`
+	return m.scan(m.DB.QueryRow(stmt, id))
+}
+
+// ByUsername возвращает профиль активного пользователя по нормализованному
+// имени пользователя.
+func (m *ProfileModel) ByUsername(userName string) (*models.Profile, error) {
+	stmt := `SELECT ` + profileColumns + ` FROM users u WHERE u.active = TRUE AND u.username = ?`
+	return m.scan(m.DB.QueryRow(stmt, userName))
+}
+
+func (m *ProfileModel) scan(row *sql.Row) (*models.Profile, error) {
+	p := &models.Profile{}
+	err := row.Scan(&p.ID, &p.Name, &p.Username, &p.Bio, &p.AvatarKey, &p.Created, &p.Karma)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update сохраняет рассказ о себе и ключ аватара в хранилище.
+func (m *ProfileModel) Update(id int, bio, avatarKey string) error {
+	stmt := `UPDATE users SET bio = ?, avatar_key = ? WHERE id = ?`
+	_, err := m.DB.Exec(stmt, bio, avatarKey, id)
+	return err
+}
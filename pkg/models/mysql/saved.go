@@ -0,0 +1,178 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// SavedModel - сохранённые заметки и комментарии и коллекции, по которым
+// пользователь их раскладывает.
+type SavedModel struct {
+	DB *sql.DB
+}
+
+// savedJoins и savedAvailable оставляют только то сохранённое, что ещё
+// можно открыть: заметка не истекла и не удалена, а сохранённый
+// комментарий существует. Остальное не показывается и не считается, но
+// отдельно не удаляется: ссылки на истёкшие заметки убирает PurgeExpired.
+const (
+	savedJoins = `JOIN snippets s ON s.id = si.snippet_id
+    LEFT JOIN comments c ON si.comment_id <> 0 AND c.id = si.comment_id`
+	savedAvailable = `(s.expires IS NULL OR s.expires > UTC_TIMESTAMP()) AND (si.comment_id = 0 OR c.id IS NOT NULL)`
+)
+
+// Save сохраняет заметку (commentID = 0) или комментарий к ней в
+// коллекцию collectionID (0 - без коллекции). Повторное сохранение
+// перекладывает элемент в другую коллекцию. Что коллекция принадлежит
+// пользователю, проверяет вызывающий.
+func (m *SavedModel) Save(userID, snippetID, commentID, collectionID int) error {
+	stmt := `INSERT INTO saved_items (user_id, snippet_id, comment_id, collection_id, created)
+    VALUES(?, ?, ?, ?, UTC_TIMESTAMP())
+    ON DUPLICATE KEY UPDATE collection_id = VALUES(collection_id)`
+	_, err := m.DB.Exec(stmt, userID, snippetID, commentID, collectionID)
+	return err
+}
+
+// Unsave убирает заметку или комментарий из сохранённого.
+func (m *SavedModel) Unsave(userID, snippetID, commentID int) error {
+	_, err := m.DB.Exec(`DELETE FROM saved_items WHERE user_id = ? AND snippet_id = ? AND comment_id = ?`,
+		userID, snippetID, commentID)
+	return err
+}
+
+// Saved возвращает, что пользователь сохранил на странице заметки:
+// ключ 0 - сама заметка, остальные - идентификаторы комментариев.
+func (m *SavedModel) Saved(userID, snippetID int) (map[int]bool, error) {
+	rows, err := m.DB.Query(`SELECT comment_id FROM saved_items WHERE user_id = ? AND snippet_id = ?`,
+		userID, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		saved[id] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Items возвращает не более limit доступных сохранённых элементов
+// пользователя, пропустив offset, начиная с последних сохранённых. Если
+// collectionID не равен нулю, возвращаются только элементы этой коллекции.
+func (m *SavedModel) Items(userID, collectionID, limit, offset int) ([]*models.SavedItem, error) {
+	stmt := `SELECT si.id, si.snippet_id, s.title, si.comment_id,
+        IF(si.comment_id = 0, s.content, c.content),
+        COALESCE(IF(si.comment_id = 0, su.name, cu.name), ''),
+        COALESCE(IF(si.comment_id = 0, su.username, cu.username), ''),
+        si.collection_id, COALESCE(col.name, ''), si.created
+    FROM saved_items si ` + savedJoins + `
+    LEFT JOIN users su ON su.id = s.user_id
+    LEFT JOIN users cu ON cu.id = c.user_id
+    LEFT JOIN collections col ON col.id = si.collection_id
+    WHERE si.user_id = ? AND (? = 0 OR si.collection_id = ?) AND ` + savedAvailable + `
+    ORDER BY si.id DESC LIMIT ? OFFSET ?`
+	rows, err := m.DB.Query(stmt, userID, collectionID, collectionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.SavedItem
+	for rows.Next() {
+		i := &models.SavedItem{}
+		err = rows.Scan(&i.ID, &i.SnippetID, &i.SnippetTitle, &i.CommentID, &i.Content,
+			&i.Author, &i.AuthorUsername, &i.CollectionID, &i.Collection, &i.Saved)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Collections возвращает коллекции пользователя по алфавиту с числом
+// доступных элементов в каждой.
+func (m *SavedModel) Collections(userID int) ([]*models.Collection, error) {
+	stmt := `SELECT col.id, col.name, (SELECT COUNT(*) FROM saved_items si ` + savedJoins + `
+        WHERE si.user_id = col.user_id AND si.collection_id = col.id AND ` + savedAvailable + `)
+    FROM collections col WHERE col.user_id = ? ORDER BY col.name`
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []*models.Collection
+	for rows.Next() {
+		c := &models.Collection{}
+		if err = rows.Scan(&c.ID, &c.Name, &c.Items); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// CreateCollection создаёт коллекцию. Если у пользователя уже есть
+// коллекция с таким именем, возвращается models.ErrDuplicateCollection.
+func (m *SavedModel) CreateCollection(userID int, name string) (int, error) {
+	result, err := m.DB.Exec(`INSERT INTO collections (user_id, name, created) VALUES(?, ?, UTC_TIMESTAMP())`,
+		userID, name)
+	if err != nil {
+		var mySQLError *mysql.MySQLError
+		if errors.As(err, &mySQLError) && mySQLError.Number == 1062 {
+			return 0, models.ErrDuplicateCollection
+		}
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// DeleteCollection удаляет коллекцию пользователя. Её элементы остаются
+// сохранёнными, но уже без коллекции. Если такой коллекции у пользователя
+// нет, возвращается models.ErrNoRecord.
+func (m *SavedModel) DeleteCollection(userID, id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM collections WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return models.ErrNoRecord
+	}
+	_, err = tx.Exec(`UPDATE saved_items SET collection_id = 0 WHERE user_id = ? AND collection_id = ?`, userID, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,151 @@
+package mysql
+
+import (
+	"database/sql"
+	"strings"
+
+	"golangify.com/snippetbox/pkg/search"
+)
+
+// SearchIndex - реализация search.Index поверх FULLTEXT-индексов MySQL
+// на заметках и комментариях. MySQL поддерживает индексы сам, поэтому Add
+// и Remove ничего не делают.
+type SearchIndex struct {
+	DB *sql.DB
+}
+
+func (m *SearchIndex) Add(doc search.Document) error {
+	return nil
+}
+
+func (m *SearchIndex) Remove(kind string, id int) error {
+	return nil
+}
+
+// searchSource - таблица, в которой ищет SearchIndex. В from заметка
+// всегда называется s, а автор документа - u, поэтому условия поиска для
+// заметок и комментариев одинаковые.
+type searchSource struct {
+	kind string
+	// columns - id, id заметки, заголовок, текст и дата создания.
+	columns string
+	// match - столбцы FULLTEXT-индекса.
+	match string
+	from  string
+}
+
+var searchSources = []searchSource{
+	{
+		kind:    search.KindPost,
+		columns: "s.id AS id, s.id AS post_id, s.title AS title, s.content AS content, s.created AS created",
+		match:   "s.title, s.content",
+		from:    "snippets s LEFT JOIN users u ON u.id = s.user_id",
+	},
+	{
+		// Заголовка у комментария нет; комментарии истёкших заметок
+		// не показываются, как и сами заметки.
+		kind:    search.KindComment,
+		columns: "c.id, s.id, '', c.content, c.created",
+		match:   "c.content",
+		from:    "comments c JOIN snippets s ON s.id = c.snippet_id LEFT JOIN users u ON u.id = c.user_id",
+	},
+}
+
+// query возвращает SELECT по источнику с релевантностью в столбце score
+// и его аргументы.
+func (src searchSource) query(q search.Query) (string, []any) {
+	var where []string
+	var args []any
+	where = append(where, "(s.expires IS NULL OR s.expires > UTC_TIMESTAMP())")
+
+	score := "0"
+	var scoreArgs []any
+	if against := booleanQuery(q.Terms, q.Phrases, "+"); against != "" {
+		score = "MATCH(" + src.match + ") AGAINST(? IN BOOLEAN MODE)"
+		scoreArgs = append(scoreArgs, against)
+		where = append(where, score)
+		args = append(args, against)
+	}
+	if excluded := booleanQuery(nil, q.Exclude, ""); excluded != "" {
+		// В логическом режиме запрос только из исключений ничего не находит,
+		// поэтому исключения проверяем отдельным условием.
+		where = append(where, "NOT MATCH("+src.match+") AGAINST(? IN BOOLEAN MODE)")
+		args = append(args, excluded)
+	}
+	if q.Author != "" {
+		where = append(where, "u.username = ?")
+		args = append(args, q.Author)
+	}
+	if q.Community != "" {
+		// Сообществ у заметок пока нет, поэтому фильтр по сообществу
+		// ничего не находит.
+		where = append(where, "FALSE")
+	}
+
+	stmt := "SELECT ? AS kind, " + src.columns + ", COALESCE(u.username, '') AS author, " + score + " AS score FROM " +
+		src.from + " WHERE " + strings.Join(where, " AND ")
+	return stmt, append(append([]any{src.kind}, scoreArgs...), args...)
+}
+
+// Search выполняет запрос в логическом режиме FULLTEXT-поиска (IN BOOLEAN MODE)
+// по заметкам и комментариям сразу. Слова и фразы запроса становятся
+// обязательными (+), исключения - запрещёнными.
+func (m *SearchIndex) Search(q search.Query, page, perPage int) (*search.Results, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	var selects []string
+	var args []any
+	for _, src := range searchSources {
+		stmt, srcArgs := src.query(q)
+		selects = append(selects, stmt)
+		args = append(args, srcArgs...)
+	}
+	union := strings.Join(selects, " UNION ALL ")
+
+	res := &search.Results{Page: page, PerPage: perPage}
+	err := m.DB.QueryRow("SELECT COUNT(*) FROM ("+union+") AS hits", args...).Scan(&res.Total)
+	if err != nil {
+		return nil, err
+	}
+	if res.Total == 0 {
+		return res, nil
+	}
+
+	// Столбцы UNION называются так же, как в первом SELECT.
+	stmt := union + ` ORDER BY score DESC, created DESC, kind, id DESC LIMIT ? OFFSET ?`
+	rows, err := m.DB.Query(stmt, append(args, perPage, (page-1)*perPage)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		h := &search.Hit{}
+		var relevance float64
+		err = rows.Scan(&h.Kind, &h.ID, &h.PostID, &h.Title, &h.Content, &h.Created, &h.Author, &relevance)
+		if err != nil {
+			return nil, err
+		}
+		h.Snippet = search.Highlight(h.Content, q, search.SnippetWidth)
+		res.Hits = append(res.Hits, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// booleanQuery собирает выражение для AGAINST(... IN BOOLEAN MODE). Слова
+// и фразы уже прошли через search.Tokenize, поэтому операторов MySQL в них нет.
+func booleanQuery(terms, phrases []string, op string) string {
+	var parts []string
+	for _, t := range terms {
+		parts = append(parts, op+t)
+	}
+	for _, p := range phrases {
+		parts = append(parts, op+`"`+p+`"`)
+	}
+	return strings.Join(parts, " ")
+}
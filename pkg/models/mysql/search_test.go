@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golangify.com/snippetbox/pkg/search"
+)
+
+func TestSearchIndex(t *testing.T) {
+	db := newTestDB(t)
+
+	users := &UserModel{DB: db}
+	ids := map[string]int{}
+	for _, name := range []string{"alice", "bob"} {
+		if err := users.Insert(name, name, name+"@example.com", "pa$$word"); err != nil {
+			t.Fatal(err)
+		}
+		var id int
+		if err := db.QueryRow("SELECT id FROM users WHERE username = ?", name).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = id
+	}
+	snippetID, err := (&SnippetModel{DB: db}).Insert(ids["alice"], "Morning tea", "The kettle whistles at dawn", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commentID, err := (&CommentModel{DB: db}).Insert(snippetID, ids["bob"], "A dragonfly lands on the kettle lid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := &SearchIndex{DB: db}
+	post := fmt.Sprintf("%s:%d:%d", search.KindPost, snippetID, snippetID)
+	comment := fmt.Sprintf("%s:%d:%d", search.KindComment, commentID, snippetID)
+	find := func(t *testing.T, query string) []string {
+		res, err := idx.Search(search.Parse(query), 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, h := range res.Hits {
+			got = append(got, fmt.Sprintf("%s:%d:%d", h.Kind, h.ID, h.PostID))
+		}
+		if res.Total != len(got) {
+			t.Errorf("want total %d; got %d", len(got), res.Total)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"Comment only", "dragonfly", []string{comment}},
+		{"Post and comment", "kettle", []string{post, comment}},
+		{"Comment author", "kettle author:bob", []string{comment}},
+		{"Post author", "kettle author:alice", []string{post}},
+		{"Excluded word", "kettle -dragonfly", []string{post}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := find(t, tt.query)
+			// Both hits have the same relevance for "kettle", so compare
+			// them regardless of order.
+			if len(got) == 2 && got[0] == comment {
+				got[0], got[1] = got[1], got[0]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v; got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Expired snippet", func(t *testing.T) {
+		_, err := db.Exec("UPDATE snippets SET expires = UTC_TIMESTAMP() - INTERVAL 1 DAY WHERE id = ?", snippetID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := find(t, "dragonfly"); len(got) != 0 {
+			t.Errorf("want comments of expired snippets hidden; got %v", got)
+		}
+	})
+}
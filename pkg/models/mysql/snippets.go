@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"golangify.com/snippetbox/pkg/models"
+	"strings"
 )
 
 // SnippetModel - Определяем тип который обертывает пул подключения sql.DB
@@ -11,20 +12,21 @@ type SnippetModel struct {
 	DB *sql.DB
 }
 
-// Insert - Метод для создания новой заметки в базе дынных.
-func (m *SnippetModel) Insert(title, content, expires string) (int, error) {
+// Insert - Метод для создания новой заметки в базе дынных. expires - срок
+// жизни заметки в днях, 0 означает бессрочную заметку.
+func (m *SnippetModel) Insert(userID int, title, content string, expires int) (int, error) {
 	// Ниже будет SQL запрос, который мы хотим выполнить. Мы разделили его на две строки
 	// для удобства чтения (поэтому он окружен обратными кавычками
 	// вместо обычных двойных кавычек).
-	stmt := `INSERT INTO snippets (title, content, created, expires)
-    VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (user_id, title, content, created, expires)
+    VALUES(?, ?, ?, UTC_TIMESTAMP(), ` + expiresExpr + `)`
 
 	// Используем метод Exec() из встроенного пула подключений для выполнения
 	// запроса. Первый параметр это сам SQL запрос, за которым следует
 	// заголовок заметки, содержимое и срока жизни заметки. Этот
 	// метод возвращает объект sql.Result, который содержит некоторые основные
 	// данные о том, что произошло после выполнении запроса.
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	result, err := m.DB.Exec(stmt, userID, title, content, expires, expires)
 	if err != nil {
 		return 0, err
 	}
@@ -41,12 +43,24 @@ func (m *SnippetModel) Insert(title, content, expires string) (int, error) {
 	return int(id), nil
 }
 
-// Get - Метод для возвращения данных заметки по её идентификатору ID.
+// expiresExpr вычисляет значение столбца expires по сроку жизни в днях.
+// Параметр подставляется дважды: NULL для 0 и дата истечения для остальных.
+const expiresExpr = `IF(? = 0, NULL, DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+
+// UpdateExpiry - Метод задаёт новый срок жизни заметки, отсчитывая его
+// от текущего момента. Изменить срок может только автор заметки.
+func (m *SnippetModel) UpdateExpiry(id, userID, expires int) error {
+	stmt := `UPDATE snippets SET expires = ` + expiresExpr + `
+    WHERE id = ? AND user_id = ? AND (expires IS NULL OR expires > UTC_TIMESTAMP())`
+	_, err := m.DB.Exec(stmt, expires, expires, id, userID)
+	return err
+}
+
 // Get - Метод для возвращения данных заметки по её идентификатору ID.
 func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	// SQL запрос для получения данных одной записи.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	stmt := `SELECT id, user_id, title, content, created, expires FROM snippets
+    WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND id = ?`
 
 	// Используем метод QueryRow() для выполнения SQL запроса,
 	// передавая ненадежную переменную id в качестве значения для плейсхолдера
@@ -61,7 +75,7 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 	// для row.Scan - это указатели на место, куда требуется скопировать данные
 	// и количество аргументов должно быть точно таким же, как количество
 	// столбцов в таблице базы данных.
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	err := scanSnippet(row, s)
 	if err != nil {
 		// Специально для этого случая, мы проверим при помощи функции errors.Is()
 		// если запрос был выполнен с ошибкой. Если ошибка обнаружена, то
@@ -80,8 +94,8 @@ func (m *SnippetModel) Get(id int) (*models.Snippet, error) {
 // Latest - Метод возвращает последние 10 заметок.
 func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	// Пишем SQL запрос, который мы хотим выполнить.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() ORDER BY created DESC LIMIT 10`
+	stmt := `SELECT id, user_id, title, content, created, expires FROM snippets
+    WHERE expires IS NULL OR expires > UTC_TIMESTAMP() ORDER BY created DESC LIMIT 10`
 
 	// Используем метод Query() для выполнения нашего SQL запроса.
 	// В ответ мы получим sql.Rows, который содержит результат нашего запроса.
@@ -111,7 +125,7 @@ func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 		// должны быть указателями на место, куда требуется скопировать данные и
 		// количество аргументов должно быть точно таким же, как количество
 		// столбцов из таблицы базы данных, возвращаемых вашим SQL запросом.
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		err = scanSnippet(rows, s)
 		if err != nil {
 			return nil, err
 		}
@@ -129,6 +143,168 @@ func (m *SnippetModel) Latest() ([]*models.Snippet, error) {
 	return snippets, nil
 }
 
-func (m *SnippetModel) DELETE(*models.Snippet) {
-	//stmt := `DELETE FROM snippets WHERE id=?1`
+// ByUser - Метод возвращает не более limit последних неистёкших заметок
+// пользователя.
+func (m *SnippetModel) ByUser(userID, limit int) ([]*models.Snippet, error) {
+	stmt := `SELECT id, user_id, title, content, created, expires FROM snippets
+    WHERE (expires IS NULL OR expires > UTC_TIMESTAMP()) AND user_id = ?
+    ORDER BY created DESC, id DESC LIMIT ?`
+	rows, err := m.DB.Query(stmt, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snippets []*models.Snippet
+	for rows.Next() {
+		s := &models.Snippet{}
+		if err = scanSnippet(rows, s); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// scanner - общий интерфейс *sql.Row и *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSnippet копирует столбцы id, user_id, title, content, created, expires
+// в структуру Snippet. user_id и expires могут быть NULL: у старых заметок
+// нет автора, а у бессрочных - даты истечения.
+func scanSnippet(row scanner, s *models.Snippet) error {
+	var userID sql.NullInt64
+	var expires sql.NullTime
+	err := row.Scan(&s.ID, &userID, &s.Title, &s.Content, &s.Created, &expires)
+	if err != nil {
+		return err
+	}
+	s.UserID = int(userID.Int64)
+	s.Expires = expires.Time
+	return nil
+}
+
+// PurgeExpired - Метод удаляет не более limit истёкших заметок и возвращает
+// количество обработанных записей и ключи файлов их вложений в хранилище.
+// Файлы удаляет вызывающий и только после успешного возврата: если
+// транзакция откатится, вложения останутся на месте. Если archive равен
+// true, заметки перед удалением копируются в таблицу snippets_archive.
+func (m *SnippetModel) PurgeExpired(limit int, archive bool) (int, []string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	// Rollback() после успешного Commit() ничего не делает, поэтому его можно
+	// безопасно отложить сразу.
+	defer tx.Rollback()
+
+	// Сначала выбираем идентификаторы пачки, чтобы архивировать и удалять
+	// ровно одни и те же строки.
+	rows, err := tx.Query(`SELECT id FROM snippets WHERE expires <= UTC_TIMESTAMP()
+    ORDER BY id LIMIT ? FOR UPDATE`, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	var ids []any
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	if archive {
+		stmt := `INSERT INTO snippets_archive (id, title, content, created, expires, archived)
+    SELECT id, title, content, created, expires, UTC_TIMESTAMP() FROM snippets
+    WHERE id IN (` + placeholders + `)`
+		if _, err = tx.Exec(stmt, ids...); err != nil {
+			return 0, nil, err
+		}
+	}
+	keys, err := attachmentKeys(tx, placeholders, ids)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, table := range snippetDependents {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE snippet_id IN ("+placeholders+")", ids...); err != nil {
+			return 0, nil, err
+		}
+	}
+	result, err := tx.Exec("DELETE FROM snippets WHERE id IN ("+placeholders+")", ids...)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return int(n), keys, nil
+}
+
+// attachmentKeys возвращает ключи файлов и миниатюр всех вложений заметок ids.
+func attachmentKeys(tx *sql.Tx, placeholders string, ids []any) ([]string, error) {
+	rows, err := tx.Query("SELECT storage_key, thumbnail_key FROM attachments WHERE snippet_id IN ("+placeholders+")", ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key, thumbnail string
+		if err = rows.Scan(&key, &thumbnail); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		if thumbnail != "" {
+			keys = append(keys, thumbnail)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// snippetDependents - таблицы, строки которых удаляются вместе с заметкой:
+// комментарии, теги, опросы, закладки, вложения и отметки об уведомлениях.
+// Голоса остаются: по ним считается карма автора.
+var snippetDependents = []string{"comments", "snippet_tags", "polls", "poll_options", "poll_ballots", "poll_choices", "saved_items", "attachments", "notification_once"}
+
+// Delete - Метод удаляет заметку вместе с зависимыми записями. Файлы
+// вложений в хранилище он не трогает: их удаляет вызывающий.
+func (m *SnippetModel) Delete(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range snippetDependents {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE snippet_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec("DELETE FROM snippets WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
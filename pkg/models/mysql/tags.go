@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"database/sql"
+	"strings"
+
+	"golangify.com/snippetbox/pkg/markdown"
+	"golangify.com/snippetbox/pkg/models"
+)
+
+// TagModel - теги заметок.
+type TagModel struct {
+	DB *sql.DB
+}
+
+// Set добавляет заметке теги. Теги, которые у заметки уже есть,
+// пропускаются.
+func (m *TagModel) Set(snippetID int, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(tags)*2)
+	for _, tag := range tags {
+		args = append(args, tag, snippetID)
+	}
+	stmt := `INSERT IGNORE INTO snippet_tags (tag, snippet_id) VALUES ` +
+		strings.TrimSuffix(strings.Repeat("(?, ?),", len(tags)), ",")
+	_, err := m.DB.Exec(stmt, args...)
+	return err
+}
+
+// Snippets возвращает неистёкшие заметки с тегом tag, начиная с новых:
+// не больше limit заметок, пропустив первые offset.
+func (m *TagModel) Snippets(tag string, limit, offset int) ([]*models.Snippet, error) {
+	stmt := `SELECT s.id, s.user_id, s.title, s.content, s.created, s.expires
+    FROM snippet_tags t JOIN snippets s ON s.id = t.snippet_id
+    WHERE t.tag = ? AND (s.expires IS NULL OR s.expires > UTC_TIMESTAMP())
+    ORDER BY s.created DESC, s.id DESC LIMIT ? OFFSET ?`
+	rows, err := m.DB.Query(stmt, tag, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snippets []*models.Snippet
+	for rows.Next() {
+		s := &models.Snippet{}
+		if err = scanSnippet(rows, s); err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// backfillSnippetTags проставляет теги заметкам, опубликованным до появления
+// таблицы snippet_tags: без этого страница /t/:tag их не показывала, хотя
+// #тег в тексте уже был ссылкой. Заметки читаются пачками по id, а Set
+// пропускает существующие теги, так что повторный запуск безопасен.
+func backfillSnippetTags(db *sql.DB) error {
+	tags := &TagModel{DB: db}
+	type post struct {
+		id      int
+		content string
+	}
+	last := 0
+	for {
+		rows, err := db.Query(`SELECT id, content FROM snippets
+    WHERE id > ? AND (expires IS NULL OR expires > UTC_TIMESTAMP()) ORDER BY id LIMIT 500`, last)
+		if err != nil {
+			return err
+		}
+		var batch []post
+		for rows.Next() {
+			var p post
+			if err = rows.Scan(&p.id, &p.content); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		for _, p := range batch {
+			_, t := markdown.Extract(p.content)
+			if err = tags.Set(p.id, t); err != nil {
+				return err
+			}
+		}
+		last = batch[len(batch)-1].id
+	}
+}
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// newTestDB connects to the database named by QOGAM_TEST_DSN, for example
+// "test_web:pass@/test_snippetbox?parseTime=true", and migrates it to the
+// latest schema. All of its tables are dropped before and after the test,
+// so never point it at a database with data you need. Without the variable
+// the test is skipped.
+func newTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("QOGAM_TEST_DSN")
+	if dsn == "" {
+		t.Skip("QOGAM_TEST_DSN is not set")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropTables(t, db)
+	t.Cleanup(func() {
+		dropTables(t, db)
+		db.Close()
+	})
+	if _, err = Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func dropTables(t *testing.T, db *sql.DB) {
+	rows, err := db.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	for _, name := range tables {
+		if _, err = db.Exec("DROP TABLE `" + name + "`"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 	"golangify.com/snippetbox/pkg/models"
+	"golangify.com/snippetbox/pkg/username"
 	"strings"
 )
 
@@ -14,17 +15,18 @@ type UserModel struct {
 }
 
 // Insert Мы будем использовать этот метод, чтобы добавить новую запись в таблицу users.
-func (m *UserModel) Insert(name, email, password string) error {
+// username должен быть уже нормализован и проверен (см. пакет username).
+func (m *UserModel) Insert(name, userName, email, password string) error {
 	// Создает хэш bcrypt для пароля в виде обычного текста.
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	if err != nil {
 		return err
 	}
-	stmt := `INSERT INTO users (name, email, hashed_password, created)
-VALUES(?, ?, ?, UTC_TIMESTAMP())`
+	stmt := `INSERT INTO users (name, username, username_key, email, hashed_password, created)
+VALUES(?, ?, ?, ?, ?, UTC_TIMESTAMP())`
 	// Метод Exec(), чтобы вставить данные
 	// пользователя и хэшированный пароль в таблицу users.
-	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
+	_, err = m.DB.Exec(stmt, name, userName, username.Skeleton(userName), email, string(hashedPassword))
 	if err != nil {
 		// Если это возвращает ошибку, мы используем errors.As() для проверки того,
 		// имеет ли ошибка тип *mysql.MySQLError.  Если это произойдет, то ошибка
@@ -37,11 +39,21 @@ VALUES(?, ?, ?, UTC_TIMESTAMP())`
 				return models.ErrDuplicateEmail
 			}
 		}
-		return err
+		return duplicateUsername(err)
 	}
 	return nil
 }
 
+// duplicateUsername заменяет ошибку нарушения уникальности users_uc_username
+// на ErrDuplicateUsername и возвращает остальные ошибки как есть.
+func duplicateUsername(err error) error {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) && mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_username") {
+		return models.ErrDuplicateUsername
+	}
+	return err
+}
+
 // Authenticate Мы будем использовать этот метод, чтобы проверить,
 // существует ли пользователь с указанным адресом электронной почты и пароль.
 // Это вернет соответствующий идентификатор пользователя, если они это сделают.
@@ -80,8 +92,8 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 // on their user ID.
 func (m *UserModel) Get(id int) (*models.User, error) {
 	u := &models.User{}
-	stmt := `SELECT id, name, email, created, active FROM users WHERE id = ?`
-	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Email, &u.Created, &u.Active)
+	stmt := `SELECT id, name, username, email, created, active, locale, time_zone FROM users WHERE id = ?`
+	err := m.DB.QueryRow(stmt, id).Scan(&u.ID, &u.Name, &u.Username, &u.Email, &u.Created, &u.Active, &u.Locale, &u.TimeZone)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, models.ErrNoRecord
@@ -91,3 +103,25 @@ func (m *UserModel) Get(id int) (*models.User, error) {
 	}
 	return u, nil
 }
+
+// SetLocale сохраняет выбранный пользователем язык интерфейса.
+func (m *UserModel) SetLocale(id int, locale string) error {
+	stmt := `UPDATE users SET locale = ? WHERE id = ?`
+	_, err := m.DB.Exec(stmt, locale, id)
+	return err
+}
+
+// SetTimeZone сохраняет часовой пояс пользователя.
+func (m *UserModel) SetTimeZone(id int, zone string) error {
+	stmt := `UPDATE users SET time_zone = ? WHERE id = ?`
+	_, err := m.DB.Exec(stmt, zone, id)
+	return err
+}
+
+// SetUsername меняет имя пользователя. userName должен быть уже
+// нормализован и проверен.
+func (m *UserModel) SetUsername(id int, userName string) error {
+	stmt := `UPDATE users SET username = ?, username_key = ? WHERE id = ?`
+	_, err := m.DB.Exec(stmt, userName, username.Skeleton(userName), id)
+	return duplicateUsername(err)
+}
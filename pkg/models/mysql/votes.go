@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"database/sql"
+)
+
+// VoteModel - голоса за заметки.
+type VoteModel struct {
+	DB *sql.DB
+}
+
+// Vote записывает голос пользователя за заметку автора authorID: 1 - за,
+// -1 - против, 0 - отменить голос. Повторный голос заменяет прежний.
+func (m *VoteModel) Vote(snippetID, authorID, userID, value int) error {
+	if value == 0 {
+		_, err := m.DB.Exec(`DELETE FROM votes WHERE snippet_id = ? AND user_id = ?`, snippetID, userID)
+		return err
+	}
+	stmt := `INSERT INTO votes (snippet_id, user_id, author_id, value, created)
+    VALUES(?, ?, ?, ?, UTC_TIMESTAMP())
+    ON DUPLICATE KEY UPDATE value = VALUES(value), created = VALUES(created)`
+	_, err := m.DB.Exec(stmt, snippetID, userID, authorID, value)
+	return err
+}
+
+// Score возвращает сумму голосов за заметку.
+func (m *VoteModel) Score(snippetID int) (int, error) {
+	var score int
+	err := m.DB.QueryRow(`SELECT COALESCE(SUM(value), 0) FROM votes WHERE snippet_id = ?`, snippetID).Scan(&score)
+	return score, err
+}
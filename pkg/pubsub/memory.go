@@ -0,0 +1,91 @@
+package pubsub
+
+import "sync"
+
+// Сколько событий может ждать подписчика, пока он отправляет предыдущие.
+const bufferSize = 16
+
+// Memory рассылает события внутри одного процесса. Если копий приложения
+// несколько, подписчики одной копии не узнают о событиях другой.
+// Безопасен для одновременного использования из нескольких горутин.
+type Memory struct {
+	mu     sync.Mutex
+	topics map[string]map[chan Event]struct{}
+	closed bool
+}
+
+// NewMemory создаёт брокер.
+func NewMemory() *Memory {
+	return &Memory{topics: map[string]map[chan Event]struct{}{}}
+}
+
+// Publish реализует Broker. Publish не ждёт подписчиков: если подписчик
+// не успевает забирать события и его буфер полон, событие для него
+// пропускается.
+func (m *Memory) Publish(topic string, e Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.topics[topic] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe реализует Broker.
+func (m *Memory) Subscribe(topic string) (<-chan Event, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch := make(chan Event, bufferSize)
+	if m.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	subs, ok := m.topics[topic]
+	if !ok {
+		subs = map[chan Event]struct{}{}
+		m.topics[topic] = subs
+	}
+	subs[ch] = struct{}{}
+	return ch, func() { m.unsubscribe(topic, ch) }
+}
+
+func (m *Memory) unsubscribe(topic string, ch chan Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := m.topics[topic]
+	if _, ok := subs[ch]; !ok {
+		// Уже отписан или брокер остановлен.
+		return
+	}
+	delete(subs, ch)
+	if len(subs) == 0 {
+		delete(m.topics, topic)
+	}
+	close(ch)
+}
+
+// Subscribers возвращает число подписчиков темы.
+func (m *Memory) Subscribers(topic string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.topics[topic])
+}
+
+// Close закрывает каналы всех подписчиков, чтобы они завершились, и
+// отклоняет новые подписки. Повторные вызовы безопасны.
+func (m *Memory) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	for _, subs := range m.topics {
+		for ch := range subs {
+			close(ch)
+		}
+	}
+	m.topics = map[string]map[chan Event]struct{}{}
+}
@@ -0,0 +1,21 @@
+// Package pubsub рассылает события подписчикам темы. Через него
+// обработчики, изменившие данные, сообщают об этом открытым страницам.
+package pubsub
+
+// Event - одно событие: Name - его тип ("comment", "score"), Data -
+// содержимое, обычно JSON.
+type Event struct {
+	Name string
+	Data string
+}
+
+// Broker доставляет события, опубликованные в тему, всем её подписчикам.
+// Доставка не гарантируется: событие, опубликованное, когда подписчиков
+// нет, теряется.
+type Broker interface {
+	Publish(topic string, e Event)
+	// Subscribe подписывается на тему. Канал закрывается, когда вызвана
+	// функция отписки или брокер остановлен; функцию нужно вызвать, когда
+	// события больше не нужны.
+	Subscribe(topic string) (<-chan Event, func())
+}
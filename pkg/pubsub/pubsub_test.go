@@ -0,0 +1,83 @@
+package pubsub
+
+import "testing"
+
+func TestPublishSubscribe(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	a, unsubscribeA := m.Subscribe("snippet:1")
+	b, unsubscribeB := m.Subscribe("snippet:1")
+	other, unsubscribeOther := m.Subscribe("snippet:2")
+	defer unsubscribeA()
+	defer unsubscribeB()
+	defer unsubscribeOther()
+
+	m.Publish("snippet:1", Event{Name: "score", Data: `{"score":3}`})
+	for _, ch := range []<-chan Event{a, b} {
+		select {
+		case e := <-ch:
+			if e.Name != "score" || e.Data != `{"score":3}` {
+				t.Errorf("unexpected event %+v", e)
+			}
+		default:
+			t.Error("want every subscriber of the topic to receive the event")
+		}
+	}
+	select {
+	case e := <-other:
+		t.Errorf("want no event on another topic; got %+v", e)
+	default:
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	ch, unsubscribe := m.Subscribe("user:1")
+	if n := m.Subscribers("user:1"); n != 1 {
+		t.Fatalf("want 1 subscriber; got %d", n)
+	}
+	unsubscribe()
+	unsubscribe() // A second call is harmless.
+	if _, ok := <-ch; ok {
+		t.Error("want the channel closed after unsubscribing")
+	}
+	if n := m.Subscribers("user:1"); n != 0 {
+		t.Errorf("want no subscribers; got %d", n)
+	}
+	// Publishing to a topic without subscribers is a no-op.
+	m.Publish("user:1", Event{Name: "unread"})
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	ch, unsubscribe := m.Subscribe("snippet:1")
+	defer unsubscribe()
+	// Publish never blocks: events beyond the buffer are dropped.
+	for i := 0; i < bufferSize*2; i++ {
+		m.Publish("snippet:1", Event{Name: "score"})
+	}
+	if n := len(ch); n != bufferSize {
+		t.Errorf("want %d buffered events; got %d", bufferSize, n)
+	}
+}
+
+func TestClose(t *testing.T) {
+	m := NewMemory()
+	ch, unsubscribe := m.Subscribe("snippet:1")
+	m.Close()
+	m.Close()
+	if _, ok := <-ch; ok {
+		t.Error("want the channel closed when the broker stops")
+	}
+	unsubscribe()
+
+	late, _ := m.Subscribe("snippet:1")
+	if _, ok := <-late; ok {
+		t.Error("want subscriptions after Close to be closed immediately")
+	}
+}
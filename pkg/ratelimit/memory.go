@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory хранит вёдра в памяти процесса. Если копий приложения несколько,
+// каждая считает запросы сама, и фактический предел умножается на число
+// копий. Безопасен для одновременного использования из нескольких горутин.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewMemory создаёт хранилище и запускает горутину, которая раз в
+// evictEvery удаляет полные вёдра: клиент, долго не присылавший запросов,
+// ничем не отличается от нового, и помнить его незачем.
+func NewMemory(evictEvery time.Duration) *Memory {
+	m := &Memory{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go m.run(evictEvery)
+	return m
+}
+
+// Allow реализует Limiter.
+func (m *Memory) Allow(key string, p Policy) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{}
+		m.buckets[key] = b
+	}
+	return b.take(m.now(), p)
+}
+
+// Evict удаляет вёдра, которые к текущему моменту снова полные, и
+// возвращает их количество.
+func (m *Memory) Evict() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	n := 0
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Len возвращает количество отслеживаемых ключей.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+// Close останавливает очистку. Повторные вызовы безопасны.
+func (m *Memory) Close() {
+	m.stopOnce.Do(func() {
+		close(m.quit)
+		<-m.done
+	})
+}
+
+func (m *Memory) run(evictEvery time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(evictEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.quit:
+			return
+		case <-ticker.C:
+			m.Evict()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package ratelimit ограничивает частоту запросов по алгоритму token bucket.
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Policy - правило ограничения: в среднем Rate запросов за Per, но не
+// больше Burst подряд. Пустое ведро наполняется равномерно, по одному
+// токену каждые Per/Rate.
+type Policy struct {
+	Rate  int
+	Per   time.Duration
+	Burst int
+}
+
+// interval - время, за которое в ведро добавляется один токен.
+func (p Policy) interval() time.Duration {
+	return p.Per / time.Duration(p.Rate)
+}
+
+// Limiter решает, можно ли выполнить ещё один запрос с ключом key.
+// Если нельзя, Allow возвращает, через сколько стоит повторить попытку.
+type Limiter interface {
+	Allow(key string, p Policy) (bool, time.Duration)
+}
+
+// bucket хранит не число токенов, а момент, когда ведро снова станет
+// полным (алгоритм GCRA). Так состояние ключа - одно время, а пополнение не
+// нужно пересчитывать по таймеру.
+type bucket struct {
+	full time.Time
+}
+
+// take пытается взять токен в момент now.
+func (b *bucket) take(now time.Time, p Policy) (bool, time.Duration) {
+	interval := p.interval()
+	full := b.full
+	if full.Before(now) {
+		full = now
+	}
+	full = full.Add(interval)
+	// Ведро вмещает Burst токенов, то есть может "задолжать" не больше
+	// Burst интервалов.
+	capacity := time.Duration(p.Burst) * interval
+	if wait := full.Sub(now) - capacity; wait > 0 {
+		return false, roundUp(wait)
+	}
+	b.full = full
+	return true, 0
+}
+
+// roundUp округляет ожидание до целых секунд вверх: заголовок Retry-After
+// принимает только целые секунды, и клиент, повторивший запрос раньше,
+// снова получил бы отказ.
+func roundUp(d time.Duration) time.Duration {
+	return time.Duration(math.Ceil(d.Seconds())) * time.Second
+}
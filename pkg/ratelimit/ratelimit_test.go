@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestMemory(t *testing.T) (*Memory, *clock) {
+	m := NewMemory(time.Hour)
+	t.Cleanup(m.Close)
+	c := &clock{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m.now = c.now
+	return m, c
+}
+
+func TestAllowBurstAndRefill(t *testing.T) {
+	m, c := newTestMemory(t)
+	p := Policy{Rate: 6, Per: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := m.Allow("ip:1", p); !ok {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+	ok, retry := m.Allow("ip:1", p)
+	if ok {
+		t.Fatal("want the request over the burst to be rejected")
+	}
+	if retry != 10*time.Second {
+		t.Errorf("want retry after 10s; got %s", retry)
+	}
+
+	// Other keys are independent.
+	if ok, _ := m.Allow("ip:2", p); !ok {
+		t.Error("want another key to be allowed")
+	}
+
+	// One token comes back every 10 seconds.
+	c.t = c.t.Add(10 * time.Second)
+	if ok, _ := m.Allow("ip:1", p); !ok {
+		t.Error("want a request to be allowed after refill")
+	}
+	if ok, _ := m.Allow("ip:1", p); ok {
+		t.Error("want only one token after 10 seconds")
+	}
+}
+
+func TestRetryAfterRoundsUp(t *testing.T) {
+	m, c := newTestMemory(t)
+	p := Policy{Rate: 1, Per: time.Second, Burst: 1}
+	m.Allow("k", p)
+	c.t = c.t.Add(300 * time.Millisecond)
+	if _, retry := m.Allow("k", p); retry != time.Second {
+		t.Errorf("want retry rounded up to 1s; got %s", retry)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	m, c := newTestMemory(t)
+	p := Policy{Rate: 1, Per: time.Minute, Burst: 5}
+	m.Allow("old", p)
+	c.t = c.t.Add(30 * time.Second)
+	m.Allow("recent", p)
+
+	c.t = c.t.Add(31 * time.Second)
+	if n := m.Evict(); n != 1 {
+		t.Errorf("want 1 evicted bucket; got %d", n)
+	}
+	if m.Len() != 1 {
+		t.Errorf("want 1 remaining bucket; got %d", m.Len())
+	}
+}
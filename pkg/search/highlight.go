@@ -0,0 +1,125 @@
+package search
+
+import (
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type wordSpan struct {
+	start, end int
+	word       string
+}
+
+// words возвращает слова текста вместе с их байтовыми позициями. Границы
+// слов определяются так же, как в Tokenize.
+func words(text string) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, wordSpan{start, i, strings.ToLower(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{start, len(text), strings.ToLower(text[start:])})
+	}
+	return spans
+}
+
+// Highlight возвращает фрагмент text длиной около width символов вокруг
+// первого совпадения с запросом. Текст экранируется для HTML, а найденные
+// слова оборачиваются в <mark>, поэтому результат можно выводить в шаблоне
+// без повторного экранирования.
+func Highlight(text string, q Query, width int) template.HTML {
+	wanted := map[string]bool{}
+	for _, t := range q.Terms {
+		wanted[t] = true
+	}
+	for _, p := range q.Phrases {
+		for _, t := range strings.Fields(p) {
+			wanted[t] = true
+		}
+	}
+
+	spans := words(text)
+	var matches []wordSpan
+	for _, s := range spans {
+		if wanted[s.word] {
+			matches = append(matches, s)
+		}
+	}
+
+	// Выбираем окно так, чтобы первое совпадение оказалось ближе к началу
+	// фрагмента, но с небольшим контекстом перед ним.
+	from := 0
+	if len(matches) > 0 {
+		from = backRunes(text, matches[0].start, width/4)
+	}
+	to := forwardRunes(text, from, width)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m.start < from {
+			continue
+		}
+		if m.end > to {
+			break
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString("</mark>")
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return template.HTML(b.String())
+}
+
+// backRunes сдвигается от байтовой позиции i назад на n символов,
+// стараясь остановиться на границе слова.
+func backRunes(text string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(text[:i])
+		i -= size
+	}
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.IsSpace(r) {
+			break
+		}
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes сдвигается от байтовой позиции i вперёд на n символов,
+// не обрывая последнее слово.
+func forwardRunes(text string, i, n int) int {
+	for ; n > 0 && i < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
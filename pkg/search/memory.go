@@ -0,0 +1,181 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+type docKey struct {
+	kind string
+	id   int
+}
+
+type memoryDoc struct {
+	Document
+	// text - заголовок и содержимое, приведённые к словам через пробел,
+	// чтобы искать фразы простым поиском подстроки.
+	text  string
+	terms map[string]int
+}
+
+// MemoryIndex - инвертированный индекс в памяти процесса. Он не требует
+// базы данных, поэтому используется в тестах и там, где FULLTEXT-индекса нет.
+// Безопасен для одновременного использования из нескольких горутин.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[docKey]*memoryDoc
+	postings map[string]map[docKey]int
+}
+
+// NewMemoryIndex создаёт пустой индекс.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     map[docKey]*memoryDoc{},
+		postings: map[string]map[docKey]int{},
+	}
+}
+
+// Add добавляет документ в индекс или заменяет уже проиндексированный.
+func (idx *MemoryIndex) Add(doc Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := docKey{doc.Kind, doc.ID}
+	idx.remove(key)
+
+	tokens := append(Tokenize(doc.Title), Tokenize(doc.Content)...)
+	md := &memoryDoc{
+		Document: doc,
+		text:     " " + strings.Join(tokens, " ") + " ",
+		terms:    map[string]int{},
+	}
+	for _, t := range tokens {
+		md.terms[t]++
+	}
+	// Слова из заголовка весят больше, чем из текста.
+	for _, t := range Tokenize(doc.Title) {
+		md.terms[t] += 2
+	}
+	for t, n := range md.terms {
+		if idx.postings[t] == nil {
+			idx.postings[t] = map[docKey]int{}
+		}
+		idx.postings[t][key] = n
+	}
+	idx.docs[key] = md
+	return nil
+}
+
+// Remove удаляет документ из индекса. Удаление отсутствующего документа
+// ошибкой не считается.
+func (idx *MemoryIndex) Remove(kind string, id int) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(docKey{kind, id})
+	return nil
+}
+
+func (idx *MemoryIndex) remove(key docKey) {
+	md, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+	for t := range md.terms {
+		delete(idx.postings[t], key)
+		if len(idx.postings[t]) == 0 {
+			delete(idx.postings, t)
+		}
+	}
+	delete(idx.docs, key)
+}
+
+// Search возвращает страницу page (начиная с 1) документов, подходящих под
+// запрос, упорядоченных по релевантности, а при равной релевантности - от
+// новых к старым.
+func (idx *MemoryIndex) Search(q Query, page, perPage int) (*Results, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	type scored struct {
+		md    *memoryDoc
+		score int
+	}
+	var found []scored
+	for key, md := range idx.candidates(q) {
+		if !idx.matches(md, q) {
+			continue
+		}
+		score := 0
+		for _, t := range q.Terms {
+			score += idx.postings[t][key]
+		}
+		for _, p := range q.Phrases {
+			score += 3 * strings.Count(md.text, " "+p+" ")
+		}
+		found = append(found, scored{md, score})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].score != found[j].score {
+			return found[i].score > found[j].score
+		}
+		if !found[i].md.Created.Equal(found[j].md.Created) {
+			return found[i].md.Created.After(found[j].md.Created)
+		}
+		return found[i].md.ID > found[j].md.ID
+	})
+
+	res := &Results{Total: len(found), Page: page, PerPage: perPage}
+	start, end := pageBounds(page, perPage, len(found))
+	for _, f := range found[start:end] {
+		res.Hits = append(res.Hits, &Hit{
+			Document: f.md.Document,
+			Snippet:  Highlight(f.md.Content, q, SnippetWidth),
+		})
+	}
+	return res, nil
+}
+
+// candidates возвращает документы, содержащие первое слово запроса, а если
+// слов нет - все документы. Остальные условия проверяет matches.
+func (idx *MemoryIndex) candidates(q Query) map[docKey]*memoryDoc {
+	first := ""
+	if len(q.Terms) > 0 {
+		first = q.Terms[0]
+	} else if len(q.Phrases) > 0 {
+		first = strings.Fields(q.Phrases[0])[0]
+	}
+	if first == "" {
+		return idx.docs
+	}
+	docs := map[docKey]*memoryDoc{}
+	for key := range idx.postings[first] {
+		docs[key] = idx.docs[key]
+	}
+	return docs
+}
+
+func (idx *MemoryIndex) matches(md *memoryDoc, q Query) bool {
+	if q.Author != "" && !strings.EqualFold(md.Author, q.Author) {
+		return false
+	}
+	if q.Community != "" && !strings.EqualFold(md.Community, q.Community) {
+		return false
+	}
+	for _, t := range q.Terms {
+		if md.terms[t] == 0 {
+			return false
+		}
+	}
+	for _, p := range q.Phrases {
+		if !strings.Contains(md.text, " "+p+" ") {
+			return false
+		}
+	}
+	for _, e := range q.Exclude {
+		if strings.Contains(md.text, " "+e+" ") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"golangify.com/snippetbox/pkg/username"
+)
+
+// Query - разобранный поисковый запрос.
+//
+// Поддерживаемый синтаксис:
+//
+//	go mysql             - слова, все должны встречаться в документе
+//	"old silent pond"    - фраза, должна встречаться целиком
+//	-draft -"work in"    - исключения: документ не должен их содержать
+//	author:alice         - только документы автора с именем пользователя alice
+//	community:physics    - только документы сообщества
+type Query struct {
+	Terms     []string
+	Phrases   []string
+	Exclude   []string
+	Author    string
+	Community string
+}
+
+// Empty возвращает true, если в запросе нет ни слов, ни фраз, ни фильтров.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && q.Author == "" && q.Community == ""
+}
+
+// Parse разбирает строку запроса. Слова и фразы приводятся к нижнему регистру,
+// а знаки препинания внутри слов отбрасываются так же, как при индексации.
+func Parse(s string) Query {
+	var q Query
+	for _, tok := range splitQuery(s) {
+		exclude := false
+		if strings.HasPrefix(tok.text, "-") && len(tok.text) > 1 && !tok.quoted && !tok.excludedPhrase {
+			exclude = true
+			tok.text = tok.text[1:]
+		}
+		if tok.quoted || tok.excludedPhrase {
+			phrase := strings.Join(Tokenize(tok.text), " ")
+			if phrase == "" {
+				continue
+			}
+			if tok.excludedPhrase {
+				q.Exclude = append(q.Exclude, phrase)
+			} else {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			continue
+		}
+		if !exclude {
+			if name, ok := cutFilter(tok.text, "author:"); ok {
+				q.Author = username.Normalize(name)
+				continue
+			}
+			if name, ok := cutFilter(tok.text, "community:"); ok {
+				q.Community = name
+				continue
+			}
+		}
+		for _, term := range Tokenize(tok.text) {
+			if exclude {
+				q.Exclude = append(q.Exclude, term)
+			} else {
+				q.Terms = append(q.Terms, term)
+			}
+		}
+	}
+	return q
+}
+
+func cutFilter(s, prefix string) (string, bool) {
+	if len(s) <= len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.ToLower(s[len(prefix):]), true
+}
+
+type queryToken struct {
+	text           string
+	quoted         bool
+	excludedPhrase bool
+}
+
+// splitQuery делит запрос по пробелам, сохраняя текст в кавычках целиком.
+// Незакрытая кавычка считается закрытой в конце строки.
+func splitQuery(s string) []queryToken {
+	var tokens []queryToken
+	var b strings.Builder
+	inQuotes, excluded := false, false
+
+	flush := func(quoted bool) {
+		if b.Len() > 0 || quoted {
+			tokens = append(tokens, queryToken{
+				text:           b.String(),
+				quoted:         quoted && !excluded,
+				excludedPhrase: quoted && excluded,
+			})
+		}
+		b.Reset()
+		excluded = false
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"' && inQuotes:
+			inQuotes = false
+			flush(true)
+		case r == '"':
+			// Кавычка сразу после минуса открывает исключаемую фразу.
+			excluded = b.String() == "-"
+			if !excluded {
+				flush(false)
+			}
+			b.Reset()
+			inQuotes = true
+		case unicode.IsSpace(r) && !inQuotes:
+			flush(false)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush(inQuotes)
+	return tokens
+}
+
+// Tokenize разбивает текст на слова в нижнем регистре. Словом считается
+// последовательность букв и цифр любого алфавита.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,98 @@
+// Package search реализует полнотекстовый поиск по заметкам и комментариям:
+// разбор запроса, абстракцию поискового индекса и подсветку совпадений.
+package search
+
+import (
+	"html/template"
+	"time"
+)
+
+// Виды индексируемых документов.
+const (
+	KindPost    = "post"
+	KindComment = "comment"
+)
+
+// SnippetWidth - примерная длина (в символах) фрагмента текста, который
+// показывается в результатах поиска.
+const SnippetWidth = 200
+
+// Document - единица индексации. Для комментария PostID указывает на заметку,
+// к которой он оставлен; для заметки PostID совпадает с ID. Author - имя
+// пользователя автора (username), как в /u/alice и @alice: отображаемые
+// имена не уникальны.
+type Document struct {
+	Kind      string
+	ID        int
+	PostID    int
+	Title     string
+	Content   string
+	Author    string
+	Community string
+	Created   time.Time
+}
+
+// Hit - найденный документ вместе с фрагментом текста, в котором совпадения
+// уже подсвечены.
+type Hit struct {
+	Document
+	Snippet template.HTML
+}
+
+// Results - одна страница результатов поиска.
+type Results struct {
+	Hits    []*Hit
+	Total   int
+	Page    int
+	PerPage int
+	// MaxPage - последняя страница, которую разрешено открыть (0 - без
+	// ограничения). Дальше неё ссылка "вперёд" не показывается.
+	MaxPage int
+}
+
+// HasPrev возвращает true, если перед текущей страницей есть ещё результаты.
+func (r *Results) HasPrev() bool {
+	return r.Page > 1
+}
+
+// HasNext возвращает true, если после текущей страницы есть ещё результаты.
+func (r *Results) HasNext() bool {
+	if r.MaxPage > 0 && r.Page >= r.MaxPage {
+		return false
+	}
+	return r.Page*r.PerPage < r.Total
+}
+
+// PrevPage возвращает номер предыдущей страницы.
+func (r *Results) PrevPage() int {
+	return r.Page - 1
+}
+
+// NextPage возвращает номер следующей страницы.
+func (r *Results) NextPage() int {
+	return r.Page + 1
+}
+
+// Index - поисковый индекс. Реализации, которые индексируют данные сами
+// (например, FULLTEXT-индекс MySQL), могут ничего не делать в Add и Remove.
+type Index interface {
+	Add(doc Document) error
+	Remove(kind string, id int) error
+	Search(q Query, page, perPage int) (*Results, error)
+}
+
+// pageBounds возвращает границы среза для страницы page из total элементов.
+func pageBounds(page, perPage, total int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	return start, end
+}
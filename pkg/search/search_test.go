@@ -0,0 +1,136 @@
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{"Words", "Go  MySQL", Query{Terms: []string{"go", "mysql"}}},
+		{"Phrase", `"Old Silent pond" frog`, Query{Terms: []string{"frog"}, Phrases: []string{"old silent pond"}}},
+		{"Exclusions", `pond -frog -"summer rain"`, Query{Terms: []string{"pond"}, Exclude: []string{"frog", "summer rain"}}},
+		{"Filters", "author:Alice community:physics exam", Query{Terms: []string{"exam"}, Author: "alice", Community: "physics"}},
+		{"Unclosed quote", `"old pond`, Query{Phrases: []string{"old pond"}}},
+		{"Punctuation", "c++, go!", Query{Terms: []string{"c", "go"}}},
+		{"Cyrillic", "Экзамен -физика", Query{Terms: []string{"экзамен"}, Exclude: []string{"физика"}}},
+		{"Lone minus", "-", Query{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %+v; got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryIndex(t *testing.T) {
+	idx := NewMemoryIndex()
+	now := time.Now()
+	docs := []Document{
+		{Kind: KindPost, ID: 1, Title: "Exam dates", Content: "Physics exam moved to Friday", Author: "alice", Created: now.Add(-time.Hour)},
+		{Kind: KindPost, ID: 2, Title: "Study group", Content: "Preparing for the physics exam together", Author: "bob", Created: now},
+		{Kind: KindComment, ID: 1, PostID: 2, Content: "Count me in for the exam prep", Author: "carol", Created: now},
+		{Kind: KindPost, ID: 3, Title: "Lost keys", Content: "Found keys near the library", Author: "alice", Community: "campus", Created: now},
+	}
+	for _, d := range docs {
+		if err := idx.Add(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []string
+	}{
+		{"Ranks title matches first", "exam", []string{"post:1", "post:2", "comment:1"}},
+		{"All words required", "physics exam", []string{"post:1", "post:2"}},
+		{"Phrase", `"physics exam moved"`, []string{"post:1"}},
+		{"Exclusion", "exam -friday", []string{"post:2", "comment:1"}},
+		{"Author only", "author:alice", []string{"post:3", "post:1"}},
+		{"Community", "keys community:campus", []string{"post:3"}},
+		{"No match", "chemistry", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := idx.Search(Parse(tt.query), 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, h := range res.Hits {
+				got = append(got, fmt.Sprintf("%s:%d", h.Kind, h.ID))
+			}
+			if !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("want %v; got %v", tt.wantIDs, got)
+			}
+			if res.Total != len(tt.wantIDs) {
+				t.Errorf("want total %d; got %d", len(tt.wantIDs), res.Total)
+			}
+		})
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		res, err := idx.Search(Parse("exam"), 2, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Hits) != 1 || res.Total != 3 || !res.HasPrev() || res.HasNext() {
+			t.Errorf("unexpected page: %d hits of %d, prev %t, next %t", len(res.Hits), res.Total, res.HasPrev(), res.HasNext())
+		}
+	})
+
+	t.Run("Last allowed page", func(t *testing.T) {
+		res, err := idx.Search(Parse("exam"), 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.HasNext() {
+			t.Fatal("want a next page without a limit")
+		}
+		res.MaxPage = 1
+		if res.HasNext() {
+			t.Error("want no next page past MaxPage")
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		idx.Remove(KindPost, 1)
+		res, _ := idx.Search(Parse("friday"), 1, 10)
+		if res.Total != 0 {
+			t.Errorf("want removed document to disappear; got %d hits", res.Total)
+		}
+	})
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{"Marks words", "An old silent pond", "silent", "An old <mark>silent</mark> pond"},
+		{"Case insensitive", "Silent night", "silent", "<mark>Silent</mark> night"},
+		{"Escapes HTML", "<b>pond</b>", "pond", "&lt;b&gt;<mark>pond</mark>&lt;/b&gt;"},
+		{"Trims long text", strings.Repeat("word ", 20) + "target" + strings.Repeat(" word", 20), "target", "…word <mark>target</mark> word word…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Highlight(tt.text, Parse(tt.query), 20))
+			if got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package storage хранит загруженные пользователями файлы.
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNotFound возвращается, если файла с таким ключом нет.
+var ErrNotFound = errors.New("storage: file not found")
+
+// ErrInvalidKey возвращается для ключей, которые могли бы выйти за пределы
+// хранилища (например, "../../etc/passwd").
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage - хранилище файлов, адресуемых строковым ключом. Ключи выдаёт
+// приложение, а не пользователь.
+type Storage interface {
+	Save(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Ключ состоит только из безопасных символов: без разделителей пути и точек
+// в начале, поэтому его можно напрямую использовать как имя файла.
+var keyRX = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*$`)
+
+// Local хранит файлы в каталоге на локальном диске.
+type Local struct {
+	Dir string
+}
+
+// NewLocal создаёт хранилище в каталоге dir, создавая каталог при необходимости.
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{Dir: dir}, nil
+}
+
+func (s *Local) path(key string) (string, error) {
+	if !keyRX.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+// Save записывает файл сначала во временный файл, а затем переименовывает его,
+// чтобы читатели никогда не увидели недописанный файл.
+func (s *Local) Save(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Local) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *Local) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+// Package username проверяет и нормализует имена пользователей - короткие
+// уникальные имена для адресов профилей (/u/alice) и упоминаний (@alice).
+//
+// Имя хранится в нормализованном виде: строчные латинские буквы, цифры и
+// подчёркивание. Уникальность проверяется по "скелету" имени, в котором
+// похожие друг на друга символы сведены к одному, чтобы никто не смог
+// выдать себя за другого пользователя ("a1ice", "аlice" с кириллической
+// "а" и "alice" - одно и то же имя).
+package username
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Допустимая длина имени.
+const (
+	MinLength = 3
+	MaxLength = 30
+)
+
+var (
+	ErrTooShort = errors.New("username: too short")
+	ErrTooLong  = errors.New("username: too long")
+	ErrInvalid  = errors.New("username: invalid characters")
+	ErrReserved = errors.New("username: reserved")
+)
+
+var validRX = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// placeholderRX - имена, которые миграция выдала пользователям,
+// зарегистрированным до появления имён (user42). Новым пользователям они
+// недоступны.
+var placeholderRX = regexp.MustCompile(`^user[0-9]+$`)
+
+// reserved - имена, которые совпадают с адресами сайта или могут ввести в
+// заблуждение. Сравниваются скелеты, поэтому "adm1n" тоже занято.
+var reserved = []string{
+	"admin", "administrator", "root", "system", "moderator", "mod",
+	"support", "help", "staff", "security", "qogam", "official",
+	"user", "users", "u", "me", "settings", "login", "logout", "signup",
+	"snippet", "search", "static", "api", "locale", "metrics",
+	"everyone", "here", "all", "null", "undefined", "anonymous", "deleted",
+}
+
+// confusables переводит в латиницу буквы кириллицы и греческого алфавита,
+// которые выглядят так же, как латинские.
+var confusables = map[rune]rune{
+	// Кириллица.
+	'А': 'a', 'В': 'b', 'Е': 'e', 'К': 'k', 'М': 'm', 'Н': 'h', 'О': 'o',
+	'Р': 'p', 'С': 'c', 'Т': 't', 'Х': 'x', 'У': 'y', 'І': 'i', 'Ј': 'j', 'Ѕ': 's',
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'һ': 'h', 'ү': 'y', 'ԁ': 'd', 'ԛ': 'q',
+	'ԝ': 'w', 'ӏ': 'l',
+	// Греческий алфавит.
+	'Α': 'a', 'Β': 'b', 'Ε': 'e', 'Ζ': 'z', 'Η': 'h', 'Ι': 'i', 'Κ': 'k',
+	'Μ': 'm', 'Ν': 'n', 'Ο': 'o', 'Ρ': 'p', 'Τ': 't', 'Υ': 'y', 'Χ': 'x',
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'ρ': 'p', 'ι': 'i', 'κ': 'k', 'τ': 't',
+	'υ': 'u', 'χ': 'x',
+}
+
+// Normalize приводит введённое имя к виду, в котором оно хранится:
+// убирает пробелы и "@" в начале, заменяет символы совместимости
+// (полноширинные буквы, лигатуры) обычными (NFKC), похожие на латиницу
+// буквы - латинскими и переводит всё в нижний регистр. Normalize не
+// проверяет имя - для этого есть Validate.
+func Normalize(s string) string {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "@")
+	s = norm.NFKC.String(s)
+	return strings.Map(func(r rune) rune {
+		if c, ok := confusables[r]; ok {
+			return c
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// Validate проверяет нормализованное имя: длину, символы (латинские
+// буквы, цифры и подчёркивание, первая - буква) и что имя не занято
+// сайтом.
+func Validate(name string) error {
+	switch {
+	case len(name) < MinLength:
+		return ErrTooShort
+	case len(name) > MaxLength:
+		return ErrTooLong
+	case !validRX.MatchString(name):
+		return ErrInvalid
+	case placeholderRX.MatchString(name):
+		return ErrReserved
+	}
+	key := Skeleton(name)
+	for _, r := range reserved {
+		if key == Skeleton(r) {
+			return ErrReserved
+		}
+	}
+	return nil
+}
+
+// skeletonReplacer сводит похожие символы и их сочетания к одному.
+var skeletonReplacer = strings.NewReplacer(
+	"_", "",
+	"0", "o",
+	"1", "l",
+	"i", "l",
+	"rn", "m",
+	"vv", "w",
+)
+
+// Skeleton возвращает скелет нормализованного имени. Два имени с
+// одинаковым скелетом считаются одним и тем же: по скелету в базе
+// проверяется уникальность.
+func Skeleton(name string) string {
+	return skeletonReplacer.Replace(name)
+}
@@ -0,0 +1,84 @@
+package username
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Lowercase", "alice", "alice"},
+		{"Mixed case", "Alice_42", "alice_42"},
+		{"Mention", "@alice", "alice"},
+		{"Spaces", "  alice ", "alice"},
+		{"Fullwidth", "ａｌｉｃｅ", "alice"},
+		{"Cyrillic a", "аlice", "alice"},
+		{"Uppercase Cyrillic", "АLICЕ", "alice"},
+		{"Greek omicron", "bοb", "bob"},
+		{"Other letters stay", "Дос", "дoc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.want {
+				t.Errorf("want %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"Valid", "alice", nil},
+		{"Digits and underscore", "bob_1990", nil},
+		{"Too short", "al", ErrTooShort},
+		{"Too long", "abcdefghijklmnopqrstuvwxyzabcde", ErrTooLong},
+		{"Leading digit", "1alice", ErrInvalid},
+		{"Leading underscore", "_alice", ErrInvalid},
+		{"Dash", "alice-b", ErrInvalid},
+		{"Dot", "alice.b", ErrInvalid},
+		{"Non-Latin", "айгүл", ErrInvalid},
+		{"Reserved", "admin", ErrReserved},
+		{"Reserved lookalike", "adm1n", ErrReserved},
+		{"Reserved with underscore", "ad_min", ErrReserved},
+		{"Migration placeholder", "user42", ErrReserved},
+		{"Not a placeholder", "user42x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Validate(tt.input); !errors.Is(got, tt.want) {
+				t.Errorf("want %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"alice", "a1ice", true},
+		{"alice", "allce", true},
+		{"bob", "b0b", true},
+		{"modern", "rnodern", true},
+		{"alice", "al_ice", true},
+		{"wave", "vvave", true},
+		{"alice", "alicia", false},
+		{"bob", "rob", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := Skeleton(tt.a) == Skeleton(tt.b); got != tt.same {
+				t.Errorf("Skeleton(%q) = %q, Skeleton(%q) = %q; want same=%v", tt.a, Skeleton(tt.a), tt.b, Skeleton(tt.b), tt.same)
+			}
+		})
+	}
+}
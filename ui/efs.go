@@ -0,0 +1,8 @@
+// Package ui содержит шаблоны и статические файлы сайта. Они встраиваются
+// в бинарник, поэтому для развёртывания достаточно одного файла.
+package ui
+
+import "embed"
+
+//go:embed "html" "static"
+var Files embed.FS
@@ -5,4 +5,97 @@ for (var i = 0; i < navLinks.length; i++) {
 		link.classList.add("live");
 		break;
 	}
-}
\ No newline at end of file
+}
+
+// Живой предпросмотр Markdown на форме создания заметки. Текст отправляется
+// на сервер, который возвращает уже очищенный HTML, чтобы предпросмотр
+// выглядел точно так же, как опубликованная заметка.
+var editor = document.querySelector("textarea[data-preview]");
+if (editor) {
+	var preview = document.getElementById("preview");
+	var csrfToken = editor.form.querySelector("input[name='csrf_token']").value;
+	var timer;
+
+	var renderPreview = function() {
+		var body = new URLSearchParams();
+		body.append("content", editor.value);
+		fetch(editor.getAttribute("data-preview"), {
+			method: "POST",
+			headers: {"X-CSRF-Token": csrfToken},
+			body: body,
+			credentials: "same-origin"
+		}).then(function(response) {
+			if (!response.ok) {
+				throw new Error(response.statusText);
+			}
+			return response.text();
+		}).then(function(html) {
+			preview.innerHTML = html;
+		}).catch(function() {});
+	};
+
+	editor.addEventListener("input", function() {
+		clearTimeout(timer);
+		timer = setTimeout(renderPreview, 300);
+	});
+	renderPreview();
+}
+
+// Живые обновления через Server-Sent Events. Сервер присылает события,
+// когда кто-то комментирует открытую заметку или голосует за неё, а также
+// когда меняется число непрочитанных уведомлений. EventSource сам
+// переподключается после обрыва соединения.
+var comments = document.querySelector(".comments[data-events]");
+if (comments && window.EventSource) {
+	var snippetEvents = new EventSource(comments.getAttribute("data-events"));
+	snippetEvents.addEventListener("comment", function(e) {
+		var c = JSON.parse(e.data);
+		if (document.getElementById("comment-" + c.id)) {
+			return;
+		}
+		var empty = comments.querySelector(".empty");
+		if (empty) {
+			empty.remove();
+		}
+		// Текст вставляется через textContent, чтобы не исполнить разметку
+		// из комментария.
+		var div = document.createElement("div");
+		div.className = "comment";
+		div.id = "comment-" + c.id;
+		var metadata = document.createElement("div");
+		metadata.className = "metadata";
+		var author = document.createElement("a");
+		author.href = c.profileURL;
+		author.textContent = c.author;
+		var time = document.createElement("time");
+		time.setAttribute("datetime", c.created);
+		time.textContent = comments.getAttribute("data-just-now");
+		metadata.append(author, time);
+		var content = document.createElement("p");
+		content.textContent = c.content;
+		div.append(metadata, content);
+		comments.append(div);
+	});
+	snippetEvents.addEventListener("score", function(e) {
+		var score = document.querySelector(".votes .score");
+		if (score) {
+			score.textContent = JSON.parse(e.data).score;
+		}
+	});
+}
+
+// Счётчики в меню: "unread" - непрочитанные уведомления, "messages" -
+// переписки с новыми сообщениями. Событие обновляет значок с тем же
+// data-count.
+var notificationsLink = document.querySelector("nav a[data-events]");
+if (notificationsLink && window.EventSource) {
+	var userEvents = new EventSource(notificationsLink.getAttribute("data-events"));
+	["unread", "messages"].forEach(function(name) {
+		var badge = document.querySelector("nav .badge[data-count='" + name + "']");
+		userEvents.addEventListener(name, function(e) {
+			var count = JSON.parse(e.data).count;
+			badge.textContent = count;
+			badge.hidden = count === 0;
+		});
+	});
+}